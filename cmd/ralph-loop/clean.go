@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+)
+
+// Clean command
+var (
+	cleanPlanPath string
+	cleanAll      bool
+	cleanDryRun   bool
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove scratch directories and other run leftovers",
+	Long: `Remove the scratch directories agents were given for temporary files
+(.ralph-loop/scratch next to the plan).
+
+With --all, transcripts and failure bundles kept in .ralph-loop/logs are removed
+too. The config file and the state of a running loop are never touched.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		base := layout.ForPlan(cleanPlanPath)
+		if err := base.Upgrade(); err != nil {
+			return err
+		}
+		dirs := []string{loop.ScratchRoot(cleanPlanPath)}
+		if cleanAll {
+			dirs = append(dirs, base.Path(layout.Logs))
+		}
+
+		removed := 0
+		for _, dir := range dirs {
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				continue
+			}
+			if cleanDryRun {
+				fmt.Printf("Would remove %s\n", dir)
+				continue
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", dir, err)
+			}
+			fmt.Printf("Removed %s\n", dir)
+			removed++
+		}
+		if removed == 0 && !cleanDryRun {
+			fmt.Println("Nothing to clean")
+		}
+		return nil
+	},
+}
+
+func init() {
+	cleanCmd.Flags().StringVarP(&cleanPlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	cleanCmd.Flags().BoolVar(&cleanAll, "all", false, "Also remove transcripts and failure bundles")
+	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "List what would be removed without removing it")
+
+	rootCmd.AddCommand(cleanCmd)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/config"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+)
+
+// Config command flags
+var (
+	configCheckPlanPath   string
+	configCheckConfigPath string
+	configShowEffective   bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and validate the config file",
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Validate the config file against the schema",
+	Long: `Validate the config file (default .ralph-loop/config.json next to the plan).
+
+Reports JSON syntax errors, unknown keys, values of the wrong type and
+missing required fields, each with its line and column. Exits non-zero
+if any problem is found.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := configPathFor(configCheckConfigPath, configCheckPlanPath)
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			fmt.Printf("No config file at %s (defaults apply)\n", path)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		problems := config.Check(content)
+		for _, problem := range problems {
+			fmt.Printf("%s:%s\n", path, problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d problem(s) in %s", len(problems), path)
+		}
+
+		cfg, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		if cfg.CustomAgent != nil {
+			if _, err := agent.NewCustomAgent(agent.Options{Command: cfg.CustomAgent.Command}); err != nil {
+				return fmt.Errorf("%s: custom_agent.command: %w", path, err)
+			}
+		}
+
+		fmt.Printf("%s is valid\n", path)
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the config file, or the effective run settings",
+	Long: `Print the config file.
+
+With --effective, print the fully merged settings a run would use: the
+run flags given here, then the config file, then defaults and project
+detection (such as the verification command). Accepts the same flags as
+'ralph-loop run'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !configShowEffective {
+			path := configPathFor(runConfigPath, runPlanPath)
+			content, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				fmt.Printf("No config file at %s (defaults apply)\n", path)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(content))
+			return nil
+		}
+
+		settings, err := resolveRunSettings(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(effectiveSettings(settings), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	},
+}
+
+// effectiveConfig is the JSON shape printed by `config show --effective`
+type effectiveConfig struct {
+	WorkDir        string              `json:"workdir"`
+	ConfigFile     string              `json:"config_file"`
+	Plan           string              `json:"plan"`
+	Agent          string              `json:"agent"`
+	Model          string              `json:"model,omitempty"`
+	AgentArgs      []string            `json:"agent_args,omitempty"`
+	Backend        string              `json:"backend"`
+	CustomAgent    *config.CustomAgent `json:"custom_agent,omitempty"`
+	Timeout        string              `json:"timeout"`
+	MaxRetries     int                 `json:"max_retries"`
+	Transient      int                 `json:"transient_retries"`
+	RetryDelay     string              `json:"retry_delay"`
+	BackoffFactor  float64             `json:"backoff_factor"`
+	Order          string              `json:"order"`
+	OnFailure      string              `json:"on_failure"`
+	MaxPlanAdds    int                 `json:"max_plan_adds"`
+	Verify         string              `json:"verify,omitempty"`
+	Upstream       string              `json:"upstream,omitempty"`
+	Rebase         bool                `json:"rebase"`
+	Rollback       bool                `json:"rollback"`
+	Checkpoints    bool                `json:"checkpoints"`
+	Review         bool                `json:"review"`
+	ReviewAgent    string              `json:"review_agent,omitempty"`
+	ReviewModel    string              `json:"review_model,omitempty"`
+	ResumeSessions bool                `json:"resume_sessions"`
+	GitHubStatus   bool                `json:"github_status"`
+	Artifacts      *config.Artifacts   `json:"artifacts,omitempty"`
+	Env            *config.Env         `json:"env,omitempty"`
+	Logs           *config.Logs        `json:"logs,omitempty"`
+	PTY            []string            `json:"pty,omitempty"`
+	Glossary       string              `json:"glossary,omitempty"`
+	Stall          config.Stall        `json:"stall"`
+	Context        []string            `json:"context_providers,omitempty"`
+	Denylist       []string            `json:"denylist"`
+	Hooks          *config.Hooks       `json:"hooks,omitempty"`
+	Budget         *config.Budget      `json:"budget,omitempty"`
+	Notify         *config.Notify      `json:"notify,omitempty"`
+	Export         *config.Export      `json:"export,omitempty"`
+}
+
+func effectiveSettings(s *runSettings) effectiveConfig {
+	backend := "local"
+	if s.Agent.Backend != nil {
+		backend = s.Agent.Backend.Name()
+	}
+	stall := config.Stall{
+		Warn:          stallTier(s.Loop.Stall.Warn),
+		Notify:        stallTier(s.Loop.Stall.Notify),
+		NotifyCommand: s.Loop.Stall.NotifyCommand,
+		Answer:        stallTier(s.Loop.Stall.Answer),
+		AnswerText:    s.Loop.Stall.AnswerText,
+		Kill:          stallTier(s.Loop.Stall.Kill),
+	}
+	wd, _ := os.Getwd()
+	return effectiveConfig{
+		WorkDir:        wd,
+		ConfigFile:     s.ConfigPath,
+		Plan:           runPlanPath,
+		Agent:          string(s.AgentType),
+		Model:          s.Agent.Model,
+		AgentArgs:      s.Agent.ExtraArgs,
+		Backend:        backend,
+		CustomAgent:    s.File.CustomAgent,
+		Timeout:        s.Loop.Timeout.String(),
+		MaxRetries:     s.Loop.MaxRetries,
+		Transient:      s.Loop.TransientRetries,
+		RetryDelay:     s.Loop.RetryDelay.String(),
+		BackoffFactor:  s.Loop.BackoffFactor,
+		Order:          s.Loop.Order,
+		OnFailure:      s.Loop.OnFailure,
+		MaxPlanAdds:    s.Loop.MaxPlanAdds,
+		Verify:         s.Loop.Verify,
+		Upstream:       s.Loop.Upstream,
+		Rebase:         s.Loop.Rebase,
+		Rollback:       s.Loop.Rollback,
+		Checkpoints:    s.Loop.Checkpoints,
+		Review:         s.Loop.Review,
+		ReviewAgent:    s.Loop.ReviewAgent,
+		ReviewModel:    s.Loop.ReviewModel,
+		ResumeSessions: s.Loop.ResumeSessions,
+		GitHubStatus:   s.Loop.GitHubStatus,
+		Artifacts:      s.File.Artifacts,
+		Env:            s.File.Env,
+		Logs:           s.File.Logs,
+		PTY:            ptyAgentNames(s.ptyAgents),
+		Glossary:       s.Loop.Glossary,
+		Stall:          stall,
+		Context:        contextSources(s.Loop.ContextProviders),
+		Denylist:       s.Loop.Denylist,
+		Hooks:          s.File.Hooks,
+		Budget:         runBudgetConfig(s.Loop.Budget),
+		Notify:         notifyConfig(s.Loop.Notify),
+		Export:         s.File.Export,
+	}
+}
+
+// notifyConfig describes the run's notifications as in the config file, or
+// nil when none are on. The SMTP login comes from the environment and is
+// left out, as is the webhook's signing secret.
+func notifyConfig(n loop.Notifications) *config.Notify {
+	if !n.Desktop && n.Email == nil && n.Webhook == nil {
+		return nil
+	}
+	notify := &config.Notify{Desktop: n.Desktop}
+	if e := n.Email; e != nil {
+		notify.Email = &config.EmailNotify{To: e.To, From: e.From, Host: e.Host, Port: e.Port}
+	}
+	if w := n.Webhook; w != nil {
+		notify.Webhook = &config.Webhook{URL: w.URL, Headers: w.Headers, Events: w.Events, Retries: w.Retries}
+	}
+	return notify
+}
+
+// runBudgetConfig describes a run budget as in the config file, or nil
+// when it has no limits
+func runBudgetConfig(b loop.RunBudget) *config.Budget {
+	if b == (loop.RunBudget{}) {
+		return nil
+	}
+	c := &config.Budget{MaxCost: b.MaxCost, MaxIterations: b.MaxIterations}
+	if b.MaxDuration > 0 {
+		c.MaxDuration = b.MaxDuration.String()
+	}
+	return c
+}
+
+// ptyAgentNames lists the agents run in a PTY, sorted
+func ptyAgentNames(agents map[agent.AgentType]bool) []string {
+	var names []string
+	for t := range agents {
+		names = append(names, string(t))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// contextSources describes the context providers in prompt order
+func contextSources(sources []loop.ContextSource) []string {
+	var names []string
+	for _, source := range sources {
+		if source.MaxTokens > 0 {
+			names = append(names, fmt.Sprintf("%s (max %d tokens)", source.Name, source.MaxTokens))
+		} else {
+			names = append(names, source.Name)
+		}
+	}
+	return names
+}
+
+// stallTier formats a stall tier's duration, "off" when disabled
+func stallTier(d time.Duration) string {
+	if d == 0 {
+		return "off"
+	}
+	return d.String()
+}
+
+func init() {
+	configCheckCmd.Flags().StringVarP(&configCheckPlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	configCheckCmd.Flags().StringVar(&configCheckConfigPath, "config", "", "Path to the config file (default .ralph-loop/config.json next to the plan)")
+
+	addRunFlags(configShowCmd.Flags())
+	configShowCmd.Flags().BoolVar(&configShowEffective, "effective", false, "Print the merged settings a run would use")
+
+	configCmd.AddCommand(configCheckCmd)
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/config"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Doctor command
+var (
+	doctorPlanPath   string
+	doctorConfigPath string
+	doctorAgent      string
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that agents, credentials and the plan are ready to run",
+	Long: `Check the environment before a run instead of discovering problems mid-run.
+
+For each supported agent, doctor checks that its CLI is on PATH, that it
+reports a version, and that credentials are present. It also checks that
+the config file and plan parse, and that git is available.
+
+Missing agents are only informational unless --agent names the one you
+intend to use; the command exits non-zero when that agent, the config or
+the plan has a problem.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems := 0
+
+		var only agent.AgentType
+		if doctorAgent != "" {
+			t, err := agent.ParseAgentType(doctorAgent)
+			if err != nil {
+				return err
+			}
+			only = t
+		}
+
+		fmt.Println("Agents:")
+		usable := 0
+		for _, req := range agent.Requirements() {
+			if only != "" && req.Type != only {
+				continue
+			}
+			if checkAgent(req, only != "") {
+				usable++
+			} else if only != "" {
+				problems++
+			}
+		}
+		for _, name := range agent.Plugins() {
+			if only != "" && agent.AgentType(name) != only {
+				continue
+			}
+			doctorOK("%s: plugin %s", name, agent.LookupPlugin(name))
+			usable++
+		}
+
+		fmt.Println("\nConfig:")
+		configPath := configPathFor(doctorConfigPath, doctorPlanPath)
+		cfg, err := config.Load(configPath)
+		switch {
+		case err != nil:
+			doctorFail("%v", err)
+			problems++
+		default:
+			if _, statErr := os.Stat(configPath); statErr != nil {
+				doctorOK("no config file at %s (using defaults)", configPath)
+			} else {
+				doctorOK("%s", configPath)
+			}
+			if cfg.CustomAgent != nil && (only == "" || only == agent.AgentTypeCustom) {
+				if _, err := agent.NewCustomAgent(agent.Options{Command: cfg.CustomAgent.Command}); err != nil {
+					doctorFail("custom agent: %v", err)
+					problems++
+				} else {
+					doctorOK("custom agent: %s", cfg.CustomAgent.Command)
+					usable++
+				}
+			} else if only == agent.AgentTypeCustom {
+				doctorFail("custom agent: no custom_agent in the config file")
+				problems++
+			}
+		}
+
+		fmt.Println("\nPlan:")
+		problems += checkPlan(doctorPlanPath)
+
+		fmt.Println("\nTools:")
+		if path, err := exec.LookPath("git"); err != nil {
+			doctorWarn("git not found on PATH (failure bundles, upstream tracking and context diffs need it)")
+		} else {
+			doctorOK("git (%s)", path)
+		}
+		if cfg != nil && cfg.Notify != nil && cfg.Notify.Desktop {
+			if path, err := exec.LookPath(loop.DesktopNotifyTool()); err != nil {
+				doctorWarn("%s not found on PATH (desktop notifications need it)", loop.DesktopNotifyTool())
+			} else {
+				doctorOK("%s (%s)", loop.DesktopNotifyTool(), path)
+			}
+		}
+
+		fmt.Println()
+		if usable == 0 {
+			fmt.Println("No usable agent found. Install one of the supported CLIs (see above).")
+			problems++
+		}
+		if problems > 0 {
+			return fmt.Errorf("doctor found %d problem(s)", problems)
+		}
+		fmt.Println("Everything looks ready.")
+		return nil
+	},
+}
+
+// checkAgent reports on one agent CLI and returns whether it looks usable.
+// A missing CLI is only a failure when the agent is required.
+func checkAgent(req agent.Requirement, required bool) bool {
+	path, err := exec.LookPath(req.Binary)
+	if err != nil {
+		report := doctorWarn
+		if required {
+			report = doctorFail
+		}
+		report("%s: %s not found on PATH (install: %s)", req.Type, req.Binary, req.Install)
+		return false
+	}
+
+	version := agent.ProbeVersion(path)
+	if version == "" {
+		doctorWarn("%s: %s found but `%s --version` failed; the install may be broken", req.Type, path, req.Binary)
+	}
+	if err := agent.CheckMinVersion(req.Type, version); err != nil {
+		doctorFail("%v", err)
+		return false
+	}
+
+	creds := req.Credentials()
+	switch {
+	case creds != "":
+		doctorOK("%s: %s %s (credentials: %s)", req.Type, path, version, creds)
+	case req.EnvRequired:
+		doctorFail("%s: %s %s, but no credentials (%s)", req.Type, path, version, req.Login)
+		return false
+	default:
+		doctorWarn("%s: %s %s, but no credentials found (%s)", req.Type, path, version, req.Login)
+	}
+
+	_, fallbacks := agent.CapabilitiesFor(req.Type, version)
+	for _, note := range fallbacks {
+		doctorWarn("%s: %s", req.Type, note)
+	}
+	return true
+}
+
+// checkPlan reports on the plan file and returns the number of problems
+func checkPlan(path string) int {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		doctorFail("%s: %v (run 'ralph-loop init' to create one)", path, err)
+		return 1
+	}
+
+	issues, err := plan.Validate(string(content), filepath.Dir(path))
+	if err != nil {
+		doctorFail("%s does not parse: %v", path, err)
+		return 1
+	}
+
+	errors := 0
+	for _, issue := range issues {
+		if issue.Severity == plan.SeverityError {
+			errors++
+		}
+	}
+	if errors > 0 {
+		doctorFail("%s has %d error(s) (run 'ralph-loop validate' for details)", path, errors)
+		return 1
+	}
+
+	p, _ := plan.Parse(string(content))
+	for _, step := range p.Steps {
+		if step.Agent == "" {
+			continue
+		}
+		if _, err := agent.ParseAgentType(step.Agent); err != nil {
+			doctorFail("%s step %d: %v", path, step.Number, err)
+			return 1
+		}
+	}
+
+	doctorOK("%s (%d steps, %d lint warning(s))", path, len(p.Steps), len(issues))
+	return 0
+}
+
+func doctorOK(format string, args ...any) {
+	fmt.Printf("  [ok]   %s\n", fmt.Sprintf(format, args...))
+}
+
+func doctorWarn(format string, args ...any) {
+	fmt.Printf("  [warn] %s\n", fmt.Sprintf(format, args...))
+}
+
+func doctorFail(format string, args ...any) {
+	fmt.Printf("  [fail] %s\n", fmt.Sprintf(format, args...))
+}
+
+func init() {
+	doctorCmd.Flags().StringVarP(&doctorPlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	doctorCmd.Flags().StringVar(&doctorConfigPath, "config", "", "Path to the config file (default .ralph-loop/config.json next to the plan)")
+	doctorCmd.Flags().StringVarP(&doctorAgent, "agent", "a", "", "Only check this agent, and fail if it isn't ready")
+	rootCmd.AddCommand(doctorCmd)
+}
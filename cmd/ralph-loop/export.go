@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/config"
+	"github.com/eraldohasanaj/ralph-loop/internal/export"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Export command
+var (
+	exportPlanPath   string
+	exportConfigPath string
+	exportTo         []string
+	exportEvery      time.Duration
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Publish plan progress to Notion or Confluence",
+	Long: `Publish the plan, step statuses and notes to the destinations configured
+under "export" in the config file.
+
+Credentials are read from the environment:
+  Notion:      NOTION_TOKEN
+  Confluence:  CONFLUENCE_USER and CONFLUENCE_API_TOKEN
+
+With --every, the export repeats on that interval until interrupted, which
+keeps a page current while a run is in progress. Set "on_run_end" in the
+config to export automatically whenever 'ralph-loop run' finishes.`,
+	Example: `  ralph-loop export
+  ralph-loop export --to notion --every 10m`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(configPathFor(exportConfigPath, exportPlanPath))
+		if err != nil {
+			return err
+		}
+		exporters, err := configuredExporters(cfg, exportTo)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		for {
+			err := exportPlan(ctx, exporters, exportPlanPath, nil)
+			if exportEvery == 0 {
+				return err
+			}
+			if err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+			select {
+			case <-time.After(exportEvery):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	},
+}
+
+// configPathFor returns the explicit config path, or the default next to the plan
+func configPathFor(configPath string, planPath string) string {
+	if configPath != "" {
+		return configPath
+	}
+	return config.Path(planPath)
+}
+
+// configuredExporters builds the exporters configured in cfg, limited to
+// the names in only (if any)
+func configuredExporters(cfg *config.Config, only []string) ([]export.Exporter, error) {
+	if cfg.Export == nil || (cfg.Export.Notion == nil && cfg.Export.Confluence == nil) {
+		return nil, fmt.Errorf("no export destinations configured (add \"export\" to the config file)")
+	}
+	wanted := func(name string) bool {
+		return len(only) == 0 || slices.Contains(only, name)
+	}
+
+	var exporters []export.Exporter
+	if n := cfg.Export.Notion; n != nil && wanted("notion") {
+		e, err := export.NewNotionExporter(export.NotionOptions{
+			Token:         os.Getenv("NOTION_TOKEN"),
+			DatabaseID:    n.DatabaseID,
+			TitleProperty: n.TitleProperty,
+		})
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, e)
+	}
+	if c := cfg.Export.Confluence; c != nil && wanted("confluence") {
+		e, err := export.NewConfluenceExporter(export.ConfluenceOptions{
+			BaseURL:  c.BaseURL,
+			PageID:   c.PageID,
+			User:     os.Getenv("CONFLUENCE_USER"),
+			APIToken: os.Getenv("CONFLUENCE_API_TOKEN"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, e)
+	}
+
+	if len(exporters) == 0 {
+		return nil, fmt.Errorf("none of the requested destinations (%v) are configured", only)
+	}
+	return exporters, nil
+}
+
+// exportPlan publishes the plan's current state to every exporter. Each
+// success is reported; failures are returned together.
+func exportPlan(ctx context.Context, exporters []export.Exporter, planPath string, run *export.RunSummary) error {
+	p, err := plan.ParseFile(planPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	report := export.NewReport(p, run)
+	var errs []error
+	for _, e := range exporters {
+		if err := e.Export(ctx, report); err != nil {
+			errs = append(errs, fmt.Errorf("%s export failed: %w", e.Name(), err))
+			continue
+		}
+		fmt.Printf("Exported %s to %s\n", planPath, e.Name())
+	}
+	return errors.Join(errs...)
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportPlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	exportCmd.Flags().StringVar(&exportConfigPath, "config", "", "Path to the config file (default .ralph-loop/config.json next to the plan)")
+	exportCmd.Flags().StringSliceVar(&exportTo, "to", nil, "Only export to these destinations (notion, confluence)")
+	exportCmd.Flags().DurationVar(&exportEvery, "every", 0, "Repeat the export on this interval until interrupted")
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Freeze/unfreeze commands
+var freezePlanPath string
+
+var freezeCmd = &cobra.Command{
+	Use:   "freeze",
+	Short: "Protect the plan from manual edits during runs",
+	Long: `Mark the plan read-only for manual edits.
+
+A banner is added under the project title together with a hash of the
+plan. ralph-loop keeps the hash current as it records results; any other
+change is detected, and a running loop pauses instead of overwriting it.
+
+To reconcile edits made while frozen, revert them or run 'freeze' again to
+accept the plan as it is now.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		content, err := os.ReadFile(freezePlanPath)
+		if err != nil {
+			return fmt.Errorf("failed to read plan file: %w", err)
+		}
+		wasEdited := plan.CheckFrozen(string(content)) != nil
+
+		if err := plan.Freeze(freezePlanPath); err != nil {
+			return err
+		}
+		if wasEdited {
+			fmt.Printf("Accepted manual edits and re-froze %s\n", freezePlanPath)
+		} else {
+			fmt.Printf("Froze %s\n", freezePlanPath)
+		}
+		return nil
+	},
+}
+
+var unfreezeCmd = &cobra.Command{
+	Use:   "unfreeze",
+	Short: "Allow manual edits to the plan again",
+	Long:  `Remove the freeze banner and hash from the plan so it can be edited by hand.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		content, err := os.ReadFile(freezePlanPath)
+		if err != nil {
+			return fmt.Errorf("failed to read plan file: %w", err)
+		}
+		if !plan.IsFrozen(string(content)) {
+			fmt.Printf("%s is not frozen\n", freezePlanPath)
+			return nil
+		}
+
+		if err := plan.Unfreeze(freezePlanPath); err != nil {
+			return err
+		}
+		fmt.Printf("Unfroze %s\n", freezePlanPath)
+		return nil
+	},
+}
+
+func init() {
+	freezeCmd.Flags().StringVarP(&freezePlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	unfreezeCmd.Flags().StringVarP(&freezePlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	rootCmd.AddCommand(freezeCmd)
+	rootCmd.AddCommand(unfreezeCmd)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+	"github.com/eraldohasanaj/ralph-loop/internal/prompt"
+)
+
+// Generate command
+var (
+	generateGoal    string
+	generateTimeout time.Duration
+	generateForce   bool
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate --goal GOAL",
+	Short: "Write a plan for a goal with the agent",
+	Long: `Write a plan for a high-level goal by running the agent once with a
+plan-authoring prompt.
+
+The agent is told how the plan will be executed, may look around the
+repository, and answers with a project name, a context section and a list
+of small, verifiable steps. The answer is checked like 'ralph-loop
+validate' would and written as a well-formed plan, every step pending.
+Review and edit it before running it.
+
+The agent's full answer is kept under .ralph-loop/logs/generate, so a plan
+that can't be read can still be recovered by hand. An existing plan is only
+replaced with --force, and a frozen plan must be unfrozen first.`,
+	Example: `  ralph-loop generate --goal "Build a REST API for todos in Go"
+  ralph-loop generate --goal "Add OAuth login" --agent codex --plan oauth.md`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if generateGoal == "" {
+			return fmt.Errorf("nothing to do; use --goal to describe what to build")
+		}
+		if existing, err := os.ReadFile(runPlanPath); err == nil {
+			if plan.IsFrozen(string(existing)) {
+				return plan.ErrPlanFrozen
+			}
+			if !generateForce {
+				return fmt.Errorf("%s already exists; use --force to replace it", runPlanPath)
+			}
+		}
+
+		settings, err := resolveRunSettings(nil)
+		if err != nil {
+			return err
+		}
+		if err := settings.checkVersion(settings.AgentType); err != nil {
+			return err
+		}
+		a, err := agent.New(settings.AgentType, settings.optionsFor(settings.AgentType))
+		if err != nil {
+			return err
+		}
+
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		var projectInfo string
+		if kind, _ := loop.DetectProject(wd); kind != "" {
+			projectInfo = fmt.Sprintf("A %s project", kind)
+			if settings.Loop.Verify != "" {
+				projectInfo += fmt.Sprintf(", verified with: %s", settings.Loop.Verify)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithTimeout(ctx, generateTimeout)
+		defer cancel()
+
+		fmt.Printf("Writing a plan with %s...\n\n", a.Name())
+		output, runErr := a.Run(ctx, prompt.BuildPlanGeneration(generateGoal, projectInfo), os.Stdout)
+		fmt.Println()
+		if runErr != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("the agent didn't finish within %v (set --timeout)", generateTimeout)
+			}
+			return fmt.Errorf("agent failed: %w", runErr)
+		}
+
+		saved, err := saveGenerateOutput(output)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		p, err := generatedPlan(output)
+		if err != nil {
+			if saved != "" {
+				return fmt.Errorf("%w\nThe agent's answer is in %s", err, saved)
+			}
+			return err
+		}
+
+		if err := plan.WriteFile(runPlanPath, p); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s with %d step(s):\n", runPlanPath, len(p.Steps))
+		for _, step := range p.Steps {
+			fmt.Printf("  Step %d: %s\n", step.Number, step.Description)
+		}
+
+		// Lint warnings are worth a look, but don't throw the plan away
+		if content, err := os.ReadFile(runPlanPath); err == nil {
+			issues, _ := plan.Validate(string(content), filepath.Dir(runPlanPath))
+			if len(issues) > 0 {
+				fmt.Println()
+				for _, issue := range issues {
+					fmt.Println(issue)
+				}
+			}
+		}
+		fmt.Printf("\nReview the plan, then run it with: ralph-loop run --agent %s --plan %s\n", settings.AgentType, runPlanPath)
+		return nil
+	},
+}
+
+// generatedPlan reads the plan out of the agent's answer and resets it to a
+// fresh plan: every step pending, with only what describes the work kept
+func generatedPlan(output string) (*plan.Plan, error) {
+	content, err := prompt.ExtractPlan(output)
+	if err != nil {
+		return nil, err
+	}
+	issues, err := plan.Validate(content, ".")
+	if err != nil {
+		return nil, fmt.Errorf("the agent's plan can't be read: %w", err)
+	}
+	for _, issue := range issues {
+		if issue.Severity == plan.SeverityError && !issue.Fixable {
+			return nil, fmt.Errorf("the agent's plan is invalid: %s", issue)
+		}
+	}
+
+	parsed, err := plan.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("the agent's plan can't be read: %w", err)
+	}
+	p := &plan.Plan{
+		ProjectName: parsed.ProjectName,
+		Context:     parsed.Context,
+		Glossary:    parsed.Glossary,
+	}
+	for _, step := range parsed.Steps {
+		fresh := plan.Step{
+			Number:      step.Number,
+			Description: step.Description,
+			Status:      plan.StatusPending,
+			After:       step.After,
+			Acceptance:  step.Acceptance,
+		}
+		for _, sub := range step.SubSteps {
+			fresh.SubSteps = append(fresh.SubSteps, plan.SubStep{Description: sub.Description})
+		}
+		p.Steps = append(p.Steps, fresh)
+	}
+	return p, nil
+}
+
+// saveGenerateOutput keeps the agent's full answer under the plan's logs
+// and returns its path
+func saveGenerateOutput(output string) (string, error) {
+	dir := layout.ForPlan(runPlanPath)
+	if err := dir.Ensure(); err != nil {
+		return "", err
+	}
+	path := dir.Path(layout.Logs, "generate", time.Now().Format("20060102-150405")+".log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to save the agent's answer: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+		return "", fmt.Errorf("failed to save the agent's answer: %w", err)
+	}
+	return path, nil
+}
+
+func init() {
+	flags := generateCmd.Flags()
+	flags.StringVarP(&generateGoal, "goal", "g", "", "What to build, in a sentence or two")
+	flags.StringVarP(&runPlanPath, "plan", "p", "plan.md", "Path to write the plan to")
+	flags.StringVarP(&runAgentType, "agent", "a", "claude", "AI agent to use (opencode, claude, codex, gemini, goose, copilot, q, custom, or a ralph-agent-<name> plugin)")
+	flags.StringVarP(&runModel, "model", "m", "", "Model to use (e.g., openai/gpt-5.2, anthropic/claude-sonnet-4-20250514)")
+	flags.StringVar(&runConfigPath, "config", "", "Path to the config file (default .ralph-loop/config.json next to the plan)")
+	flags.StringVar(&runAgentArgs, "agent-args", "", "Extra arguments appended to the agent's command line")
+	flags.BoolVar(&runAnyVersion, "skip-version-check", false, "Run agent CLIs older than the oldest version known to work, with a warning")
+	flags.DurationVarP(&generateTimeout, "timeout", "t", 10*time.Minute, "How long the agent may take to write the plan")
+	flags.BoolVarP(&generateForce, "force", "f", false, "Replace an existing plan")
+
+	rootCmd.AddCommand(generateCmd)
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/export"
 	"github.com/eraldohasanaj/ralph-loop/internal/loop"
 	"github.com/eraldohasanaj/ralph-loop/internal/plan"
 )
@@ -33,7 +37,7 @@ func main() {
 var rootCmd = &cobra.Command{
 	Use:   "ralph-loop",
 	Short: "Meta-orchestrator for AI coding agents",
-	Long: `ralph-loop runs AI coding agents (opencode or claude) in a loop to implement a plan.
+	Long: `ralph-loop runs AI coding agents (opencode, claude, codex, gemini, goose, copilot, q, or a custom command) in a loop to implement a plan.
 
 It solves the context-bloat problem by:
   - Maintaining state in a plan.md file (source of truth)
@@ -49,8 +53,46 @@ var (
 	runPlanPath   string
 	runTimeout    time.Duration
 	runMaxRetries int
+	runTransient  int
+	runMaxSteps   int
+	runMaxDur     time.Duration
+	runMaxCost    string
+	runMaxIters   int
+	runStallKill  time.Duration
+	runPlanAdds   int
+	runPhase      int
+	runOnlyTags   []string
+	runFrom       int
+	runUntil      int
 	runRetryDelay time.Duration
 	runModel      string
+	runWorkDir    string
+	runAgentArgs  string
+	runOrder      string
+	runOnFailure  string
+	runBackend    string
+	runK8s        agent.KubernetesOptions
+	runSandbox    string
+	runPTY        bool
+	runResume     bool
+	runAnyVersion bool
+	runForce      bool
+	runWatch      bool
+	runApprove    bool
+	runWorktree   bool
+	runConfigPath string
+	runVerify     string
+	runNoVerify   bool
+	runUpstream   string
+	runRebase     bool
+	runRollback   bool
+	runNoCheckpt  bool
+	runNotifyDesk bool
+	runWebhook    string
+	runReview     bool
+	runRevAgent   string
+	runRevModel   string
+	runGitHub     bool
 )
 
 var runCmd = &cobra.Command{
@@ -68,16 +110,24 @@ The loop will:
 
 Press Ctrl+C to gracefully stop the loop.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Parse agent type
-		agentType, err := agent.ParseAgentType(runAgentType)
+		// An isolated run works in a worktree of its own from the start
+		var worktree *loop.Worktree
+		if runWorktree {
+			wt, err := enterWorktree()
+			if err != nil {
+				return err
+			}
+			worktree = wt
+		}
+
+		settings, err := resolveRunSettings(cmd.Flags())
 		if err != nil {
 			return err
 		}
-
-		// Create agent with options
-		opts := agent.Options{
-			Model: runModel,
+		if err := settings.checkVersion(settings.AgentType); err != nil {
+			return err
 		}
+		agentType, opts, cfg := settings.AgentType, settings.optionsFor(settings.AgentType), settings.File
 		a, err := agent.New(agentType, opts)
 		if err != nil {
 			return err
@@ -88,30 +138,156 @@ Press Ctrl+C to gracefully stop the loop.`,
 			return fmt.Errorf("plan file not found: %s\nRun 'ralph-loop init' to create one", runPlanPath)
 		}
 
-		// Build config from flags
-		config := loop.DefaultConfig()
-		if runTimeout > 0 {
-			config.Timeout = runTimeout
-		}
-		if runMaxRetries > 0 {
-			config.MaxRetries = runMaxRetries
+		// Catch bad per-step annotations before anything runs
+		if p, err := plan.ParseFile(runPlanPath); err == nil {
+			if p.FormatVersion > plan.FormatVersion {
+				return fmt.Errorf("%s uses plan format %d, which is newer than this ralph-loop knows (%d); upgrade ralph-loop", runPlanPath, p.FormatVersion, plan.FormatVersion)
+			}
+			for _, step := range p.Steps {
+				if step.MetadataError != "" {
+					return fmt.Errorf("step %d: %s", step.Number, step.MetadataError)
+				}
+				if step.Agent == "" {
+					continue
+				}
+				stepType, err := agent.ParseAgentType(step.Agent)
+				if err != nil {
+					return fmt.Errorf("step %d: %w", step.Number, err)
+				}
+				if err := settings.checkVersion(stepType); err != nil {
+					return fmt.Errorf("step %d: %w", step.Number, err)
+				}
+			}
 		}
-		if runRetryDelay > 0 {
-			config.RetryDelay = runRetryDelay
+
+		if settings.Loop.ReviewAgent != "" {
+			reviewType, _ := agent.ParseAgentType(settings.Loop.ReviewAgent)
+			if err := settings.checkVersion(reviewType); err != nil {
+				return fmt.Errorf("--review-agent: %w", err)
+			}
 		}
 
+		config := settings.Loop
+		config.ForceLock = runForce
+		config.Watch = runWatch
+		config.ApproveEach = runApprove
+
 		// Create and run the loop
 		runner := loop.NewRunnerWithConfig(a, runPlanPath, config)
+		if sandbox, ok := opts.Backend.(agent.Sandbox); ok {
+			runner.SetSandbox(sandbox)
+		}
+		runner.SetAgentFactory(func(agentName string, model string) (agent.Agent, error) {
+			stepType, stepOpts := agentType, opts
+			if agentName != "" {
+				if stepType, err = agent.ParseAgentType(agentName); err != nil {
+					return nil, err
+				}
+				stepOpts = settings.optionsFor(stepType)
+			}
+			if model != "" {
+				stepOpts.Model = model
+			}
+			return agent.New(stepType, stepOpts)
+		})
 
 		fmt.Printf("Starting ralph-loop with %s agent\n", a.Name())
-		if runModel != "" {
-			fmt.Printf("Model: %s\n", runModel)
+		if opts.Model != "" {
+			fmt.Printf("Model: %s\n", opts.Model)
+		}
+		if opts.PTY {
+			fmt.Println("PTY mode: on")
+		}
+		if len(opts.ExtraArgs) > 0 {
+			fmt.Printf("Agent args: %s\n", strings.Join(opts.ExtraArgs, " "))
+		}
+		if sandbox, ok := opts.Backend.(*agent.DockerSandbox); ok {
+			fmt.Printf("Sandbox: docker (%s)\n", sandbox.Image())
+		} else if opts.Backend != nil {
+			fmt.Printf("Backend: %s\n", opts.Backend.Name())
+		}
+		if runWorkDir != "" {
+			wd, _ := os.Getwd()
+			fmt.Printf("Working directory: %s\n", wd)
 		}
 		fmt.Printf("Plan file: %s\n", runPlanPath)
 		fmt.Printf("Timeout: %v, Max retries: %d, Retry delay: %v\n", config.Timeout, config.MaxRetries, config.RetryDelay)
+		if config.Stall.Kill > 0 {
+			fmt.Printf("Stall timeout: %s\n", plan.FormatDuration(config.Stall.Kill))
+		}
+		if config.Upstream != "" {
+			fmt.Printf("Watching upstream: %s (rebase: %v)\n", config.Upstream, config.Rebase)
+		}
+		if config.Verify != "" {
+			fmt.Printf("Verify: %s\n", config.Verify)
+		}
+		if config.ResumeSessions {
+			fmt.Println("Resuming sessions on retry")
+		}
+		if config.Rollback {
+			fmt.Println("Rolling back failed attempts")
+		}
+		if config.Order != plan.DefaultOrder {
+			fmt.Printf("Step order: %s\n", config.Order)
+		}
+		if config.OnFailure != loop.DefaultOnFailure {
+			fmt.Printf("On failure: %s\n", config.OnFailure)
+		}
+		if config.MaxPlanAdds != loop.DefaultMaxPlanAdds {
+			fmt.Printf("Max plan adds: %d\n", config.MaxPlanAdds)
+		}
+		if config.Phase > 0 {
+			fmt.Printf("Phase: %d\n", config.Phase)
+		}
+		if config.From > 0 || config.Until > 0 {
+			fmt.Printf("Steps: %s\n", stepRange(config.From, config.Until))
+		}
+		if len(config.OnlyTags) > 0 {
+			fmt.Printf("Tags: #%s\n", strings.Join(config.OnlyTags, ", #"))
+		}
+		if config.Watch {
+			fmt.Println("Watching the plan for new steps once it is done")
+		}
+		if config.Review {
+			fmt.Printf("Reviewing steps with %s\n", reviewerName(config, a.Name()))
+		}
+		if config.ApproveEach {
+			fmt.Println("Asking for approval of each completed step")
+		}
+		if config.Notify.Desktop {
+			fmt.Println("Showing desktop notifications")
+		}
+		if config.Notify.Webhook != nil {
+			fmt.Printf("Posting events to %s\n", webhookHost(config.Notify.Webhook.URL))
+		}
+		if config.Notify.Email != nil {
+			fmt.Printf("Emailing the run summary to %s\n", strings.Join(config.Notify.Email.To, ", "))
+		}
+		if limits := budgetLimits(config.Budget); limits != "" {
+			fmt.Printf("Budget: %s\n", limits)
+		}
 		fmt.Println("Press Ctrl+C to stop gracefully")
 
-		return runner.Run()
+		startedAt := time.Now()
+		runErr := runner.Run()
+
+		if cfg.Export != nil && cfg.Export.OnRunEnd {
+			summary := &export.RunSummary{Agent: a.Name(), StartedAt: startedAt, FinishedAt: time.Now()}
+			if runErr != nil {
+				summary.Error = runErr.Error()
+			}
+			fmt.Println()
+			if exporters, err := configuredExporters(cfg, nil); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			} else if err := exportPlan(context.Background(), exporters, runPlanPath, summary); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+
+		if worktree != nil {
+			printWorktreeSummary(worktree)
+		}
+		return runErr
 	},
 }
 
@@ -142,6 +318,10 @@ Edit the generated file to add your actual implementation steps.`,
 }
 
 // Status command
+var (
+	statusTags []string
+)
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show the current plan status",
@@ -153,6 +333,26 @@ var statusCmd = &cobra.Command{
 		}
 
 		fmt.Printf("Project: %s\n", p.ProjectName)
+		if plan.IsFrozen(p.RawContent) {
+			if plan.CheckFrozen(p.RawContent) != nil {
+				fmt.Println("Frozen: yes, but the plan was edited by hand (run 'ralph-loop freeze' to accept the edits)")
+			} else {
+				fmt.Println("Frozen: yes")
+			}
+		}
+		if note := plan.BudgetNote(p.RawContent); note != "" {
+			fmt.Printf("Last run stopped on its budget: %s\n", note)
+		}
+
+		// A running loop publishes live progress before it updates the plan
+		state, err := loop.ReadState(runPlanPath)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		if state != nil && !state.IsAlive() {
+			fmt.Printf("(Ignoring stale state from a run that is no longer active, PID %d)\n", state.PID)
+			state = nil
+		}
 
 		if p.Context != "" {
 			fmt.Println("\nContext:")
@@ -162,14 +362,35 @@ var statusCmd = &cobra.Command{
 			}
 		}
 
+		tags := make([]string, len(statusTags))
+		for i, tag := range statusTags {
+			tags[i] = plan.NormalizeTag(tag)
+		}
+
 		fmt.Println("\nSteps:")
+		if len(tags) > 0 {
+			fmt.Printf("  (only steps tagged #%s)\n", strings.Join(tags, " or #"))
+		}
 
 		completed := 0
 		failed := 0
 		pending := 0
 		skipped := 0
+		blocked := 0
+		currentPhase := 0
 
 		for _, step := range p.Steps {
+			if len(tags) > 0 && !step.HasAnyTag(tags) {
+				continue
+			}
+			// Head each phase's steps with its progress
+			if step.Phase != currentPhase {
+				currentPhase = step.Phase
+				if phase := p.PhaseByNumber(step.Phase); phase != nil {
+					done, total := p.PhaseProgress(step.Phase)
+					fmt.Printf("\n  %s (%d/%d completed, %d%%)\n", phase.Name(), done, total, done*100/total)
+				}
+			}
 			status := "[ ]"
 			switch step.Status {
 			case plan.StatusCompleted:
@@ -181,6 +402,8 @@ var statusCmd = &cobra.Command{
 			case plan.StatusSkipped:
 				status = "[-]"
 				skipped++
+			case plan.StatusBlocked:
+				blocked++
 			default:
 				pending++
 			}
@@ -188,12 +411,74 @@ var statusCmd = &cobra.Command{
 			if step.RetryCount > 0 {
 				retryInfo = fmt.Sprintf(" (retries: %d)", step.RetryCount)
 			}
-			fmt.Printf("  %s Step %d: %s%s\n", status, step.Number, step.Description, retryInfo)
+			liveInfo := ""
+			if state != nil && state.Step == step.Number {
+				liveInfo = fmt.Sprintf("  <- %s", state.Phase)
+			}
+			overrideInfo := ""
+			if metadata := step.Metadata(); metadata != "" {
+				overrideInfo = " " + metadata
+			}
+			if step.Timeout > 0 {
+				overrideInfo += fmt.Sprintf(" (timeout: %s)", plan.FormatDuration(step.Timeout))
+			}
+			if step.MaxRetries > 0 {
+				overrideInfo += fmt.Sprintf(" (max retries: %d)", step.MaxRetries)
+			}
+			if step.Estimate > 0 && step.Status == plan.StatusCompleted && len(step.Attempts) > 0 {
+				overrideInfo += fmt.Sprintf(" (took %s)", plan.FormatDuration(step.TimeSpent().Round(time.Second)))
+			}
+			blockedInfo := ""
+			if step.Status == plan.StatusBlocked {
+				blockedInfo = fmt.Sprintf(" (blocked: %s)", p.BlockedReason(&step))
+			}
+			fmt.Printf("  %s Step %d: %s%s%s%s%s\n", status, step.Number, step.Description, overrideInfo, retryInfo, blockedInfo, liveInfo)
+			for _, sub := range step.SubSteps {
+				box := "[ ]"
+				if sub.Done {
+					box = "[x]"
+				}
+				fmt.Printf("      %s %s\n", box, sub.Description)
+			}
+			// Point at the full output of the attempt that failed
+			if step.Status == plan.StatusFailed && step.Transcript != "" {
+				fmt.Printf("      output: %s\n", step.Transcript)
+			}
+		}
+
+		fmt.Printf("\nSummary: %d completed, %d failed, %d skipped, %d blocked, %d pending\n", completed, failed, skipped, blocked, pending)
+		if eta := etaLine(p, state); eta != "" {
+			fmt.Println(eta)
 		}
 
-		fmt.Printf("\nSummary: %d completed, %d failed, %d skipped, %d pending\n", completed, failed, skipped, pending)
+		if stale := plan.StaleSteps(p, filepath.Dir(runPlanPath)); len(stale) > 0 {
+			fmt.Println("\nWarning: the context or its referenced files changed after these steps completed:")
+			for _, step := range stale {
+				fmt.Printf("  Step %d: %s\n", step.Number, step.Description)
+			}
+			fmt.Println("Consider resetting them (ralph-loop plan reset N) if the change affects their work.")
+		}
 
-		if p.IsComplete() {
+		if state != nil {
+			fmt.Printf("\nRunning now (PID %d, %s agent, run started %v ago):\n",
+				state.PID, state.Agent, time.Since(state.RunStartedAt).Round(time.Second))
+			switch state.Phase {
+			case loop.PhaseWatching:
+				fmt.Println("  Watching the plan for new steps")
+			case loop.PhaseReviewing:
+				fmt.Printf("  Step %d - %s: waiting for the operator's approval\n", state.Step, state.StepDescription)
+			case loop.PhasePaused:
+				fmt.Printf("  Step %d - %s: paused until manual edits to the frozen plan are reconciled\n",
+					state.Step, state.StepDescription)
+			case loop.PhaseWaiting:
+				fmt.Printf("  Step %d - %s: waiting to retry (attempt %d of %d)\n",
+					state.Step, state.StepDescription, state.Attempt, state.MaxRetries)
+			default:
+				fmt.Printf("  Step %d - %s: attempt %d of %d, running for %v\n",
+					state.Step, state.StepDescription, state.Attempt, state.MaxRetries,
+					time.Since(state.StepStartedAt).Round(time.Second))
+			}
+		} else if p.IsComplete() {
 			fmt.Println("\nAll steps completed!")
 		} else if next := p.NextStep(); next != nil {
 			fmt.Printf("\nNext step: Step %d - %s\n", next.Number, next.Description)
@@ -203,20 +488,146 @@ var statusCmd = &cobra.Command{
 	},
 }
 
+// etaLine projects when the plan's remaining steps finish, or returns ""
+// when there is nothing to project from: no estimates and no completed
+// steps with a recorded time
+func etaLine(p *plan.Plan, state *loop.State) string {
+	f := p.Forecast()
+	if f.Steps == 0 || f.Unknown == f.Steps {
+		return ""
+	}
+	remaining := f.Remaining
+	// The running step is already partway through its projected time
+	if state != nil && state.Phase == loop.PhaseRunning {
+		remaining -= min(time.Since(state.StepStartedAt), f.Projected[state.Step])
+	}
+
+	line := fmt.Sprintf("Estimated time left: %s for %d step(s), finishing around %s",
+		approxDuration(remaining), f.Steps-f.Unknown, time.Now().Add(remaining).Format("Mon 15:04"))
+	switch {
+	case f.Pace != 1:
+		line += fmt.Sprintf(" (estimates scaled by %.1fx, the pace of the completed steps)", f.Pace)
+	case f.Basis == 0:
+		line += " (from the estimates alone; no completed step has a recorded time yet)"
+	}
+	if f.Unknown > 0 {
+		line += fmt.Sprintf("\n  %d step(s) have no estimate and aren't counted", f.Unknown)
+	}
+	return line
+}
+
+// approxDuration rounds d for a projection, e.g. "~1h20m"
+func approxDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "under a minute"
+	}
+	return "~" + plan.FormatDuration(d.Round(time.Minute))
+}
+
+// stepRange describes --from and --until, e.g. "4-8" or "4 onward"
+func stepRange(from int, until int) string {
+	switch {
+	case until == 0:
+		return fmt.Sprintf("%d onward", from)
+	case from == 0:
+		return fmt.Sprintf("up to %d", until)
+	}
+	return fmt.Sprintf("%d-%d", from, until)
+}
+
+// reviewerName describes the agent that reviews steps, e.g. "codex
+// (model o3)"
+func reviewerName(config loop.Config, runAgent string) string {
+	name := config.ReviewAgent
+	if name == "" {
+		name = runAgent
+	}
+	if config.ReviewModel != "" {
+		name += fmt.Sprintf(" (model %s)", config.ReviewModel)
+	}
+	return name
+}
+
+// budgetLimits describes a run budget's limits, e.g. "4h, $10.00, 50
+// attempts", or "" when it has none
+func budgetLimits(b loop.RunBudget) string {
+	var limits []string
+	if b.MaxDuration > 0 {
+		limits = append(limits, plan.FormatDuration(b.MaxDuration))
+	}
+	if b.MaxCost > 0 {
+		limits = append(limits, fmt.Sprintf("$%.2f", b.MaxCost))
+	}
+	if b.MaxIterations > 0 {
+		limits = append(limits, fmt.Sprintf("%d attempts", b.MaxIterations))
+	}
+	return strings.Join(limits, ", ")
+}
+
+// addRunFlags registers the flags that configure a run. They are shared by
+// `run` and `config show --effective`.
+func addRunFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&runAgentType, "agent", "a", "claude", "AI agent to use (opencode, claude, codex, gemini, goose, copilot, q, custom, or a ralph-agent-<name> plugin)")
+	flags.StringVarP(&runPlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	flags.StringVar(&runWorkDir, "workdir", "", "Directory to run in (the target repository); --plan and --config are relative to it")
+	flags.StringVar(&runConfigPath, "config", "", "Path to the config file (default .ralph-loop/config.json next to the plan)")
+	flags.StringVar(&runAgentArgs, "agent-args", "", "Extra arguments appended to the agent's command line, e.g. \"--max-turns 30\"")
+	flags.StringVarP(&runModel, "model", "m", "", "Model to use (e.g., openai/gpt-5.2, anthropic/claude-sonnet-4-20250514)")
+	flags.DurationVarP(&runTimeout, "timeout", "t", 30*time.Minute, "Timeout per step")
+	flags.IntVarP(&runMaxRetries, "max-retries", "r", 3, "Max retry attempts per step")
+	flags.DurationVar(&runStallKill, "stall-timeout", 0, "Stop an attempt whose agent has been silent this long, e.g. 5m, and retry it; overrides stall.kill in the config file (0 means never)")
+	flags.IntVar(&runTransient, "transient-retries", 2, "Immediate reruns of an attempt whose agent crashed, failed to start or lost its connection, not counted against --max-retries")
+	flags.IntVar(&runMaxSteps, "max-steps", 0, "Stop after this many steps complete (0 means no limit)")
+	flags.DurationVar(&runMaxDur, "max-duration", 0, "Stop once the run has taken this long, e.g. 4h (0 means no limit)")
+	flags.StringVar(&runMaxCost, "max-cost", "", "Stop once the agents report spending this much in the run, e.g. $10")
+	flags.IntVar(&runMaxIters, "max-iterations", 0, "Stop after this many agent attempts (0 means no limit)")
+	flags.IntVar(&runPlanAdds, "max-plan-adds", loop.DefaultMaxPlanAdds, "Steps agents may append to the plan with PLAN_ADD lines in one run (0 disables them)")
+	flags.IntVar(&runPhase, "phase", 0, "Only run the steps of this phase (## Phase N header); 0 runs every step")
+	flags.IntVar(&runFrom, "from", 0, "Start at this step, passing over the ones before it; 0 starts at the first")
+	flags.IntVar(&runUntil, "until", 0, "Stop after this step, leaving the ones after it; 0 runs to the last")
+	flags.StringSliceVar(&runOnlyTags, "only-tag", nil, "Only run the steps with one of these #tags, e.g. backend (repeatable or comma-separated)")
+	flags.DurationVar(&runRetryDelay, "retry-delay", 5*time.Second, "Initial delay between retries")
+	flags.StringVar(&runBackend, "backend", "local", "Where agents run (local, kubernetes)")
+	flags.StringVar(&runK8s.Image, "k8s-image", "", "Container image with the agent CLI (kubernetes backend)")
+	flags.StringVar(&runK8s.Namespace, "k8s-namespace", "", "Namespace for agent Jobs (kubernetes backend)")
+	flags.StringVar(&runK8s.CPU, "k8s-cpu", "", "CPU request/limit for agent Jobs, e.g. 2 (kubernetes backend)")
+	flags.StringVar(&runK8s.Memory, "k8s-memory", "", "Memory request/limit for agent Jobs, e.g. 4Gi (kubernetes backend)")
+	flags.StringVar(&runK8s.PVC, "k8s-pvc", "", "PersistentVolumeClaim with the repository, mounted at /workspace; required, and must be the run's working directory too (kubernetes backend)")
+	flags.StringVar(&runK8s.Secret, "k8s-secret", "", "Secret exposed as environment variables, e.g. API keys (kubernetes backend)")
+	flags.BoolVar(&runResume, "resume-sessions", false, "On retry, resume the failed attempt's agent session instead of starting cold (claude)")
+	flags.BoolVar(&runAnyVersion, "skip-version-check", false, "Run agent CLIs older than the oldest version known to work, with a warning")
+	flags.BoolVar(&runPTY, "pty", false, "Run the agent attached to a pseudo-terminal, for CLIs that need a TTY")
+	flags.StringVar(&runSandbox, "sandbox", "", "Run agents in a container with the repository mounted: docker or docker:<image>")
+	flags.StringVar(&runVerify, "verify", "", "Command that must pass before a step counts as complete (default: detected from the project type)")
+	flags.BoolVar(&runNoVerify, "no-verify", false, "Skip the verification command")
+	flags.StringVar(&runUpstream, "upstream", "", "Branch to watch for changes between steps, e.g. origin/main")
+	flags.BoolVar(&runRebase, "rebase", false, "Rebase onto --upstream when it moves, handing conflicts to the agent")
+	flags.BoolVar(&runReview, "review", false, "Have a reviewer agent check each finished step against its diff and acceptance criteria before it is marked complete")
+	flags.StringVar(&runRevAgent, "review-agent", "", "Agent that reviews steps with --review (default: the run's agent)")
+	flags.StringVar(&runRevModel, "review-model", "", "Model of the reviewer agent with --review")
+	flags.BoolVar(&runRollback, "rollback", false, "Reset the working tree to its state before a failed attempt, so the retry starts clean (git)")
+	flags.BoolVar(&runNotifyDesk, "notify-desktop", false, "Show a desktop notification when a step finishes or fails, or the agent asks for input")
+	flags.StringVar(&runWebhook, "webhook", "", "Post run and step events as JSON to this URL; overrides notify.webhook.url in the config file")
+	flags.BoolVar(&runNoCheckpt, "no-checkpoints", false, "Don't record the repository before each step for 'ralph-loop restore'")
+	flags.BoolVar(&runGitHub, "github-status", false, "Publish per-step progress as commit statuses on the GitHub commit being built (needs GITHUB_TOKEN and GITHUB_REPOSITORY)")
+	flags.StringVar(&runOrder, "order", plan.DefaultOrder, "Step ordering strategy ("+strings.Join(plan.OrderStrategyNames(), ", ")+")")
+	flags.StringVar(&runOnFailure, "on-failure", loop.DefaultOnFailure, "After a failed attempt: retry the step, or continue with the other steps and retry it once they have run")
+}
+
 func init() {
 	// Run command flags
-	runCmd.Flags().StringVarP(&runAgentType, "agent", "a", "claude", "AI agent to use (opencode, claude, or codex)")
-	runCmd.Flags().StringVarP(&runPlanPath, "plan", "p", "plan.md", "Path to the plan file")
-	runCmd.Flags().StringVarP(&runModel, "model", "m", "", "Model to use (e.g., openai/gpt-5.2, anthropic/claude-sonnet-4-20250514)")
-	runCmd.Flags().DurationVarP(&runTimeout, "timeout", "t", 30*time.Minute, "Timeout per step")
-	runCmd.Flags().IntVarP(&runMaxRetries, "max-retries", "r", 3, "Max retry attempts per step")
-	runCmd.Flags().DurationVar(&runRetryDelay, "retry-delay", 5*time.Second, "Initial delay between retries")
+	addRunFlags(runCmd.Flags())
+	runCmd.Flags().BoolVar(&runForce, "force", false, "Start even if the plan is locked by a loop that looks alive, taking over its lock")
+	runCmd.Flags().BoolVar(&runWorktree, "worktree", false, "Run in a new git worktree on a branch of its own, leaving your checkout free")
+	runCmd.Flags().BoolVar(&runWatch, "watch", false, "Once no step can run, keep watching the plan and run steps added to it")
+	runCmd.Flags().BoolVar(&runApprove, "approve-each", false, "Show the diff after each step the agent finishes and ask to approve, retry or edit it before it is marked complete")
 
 	// Init command flags
 	initCmd.Flags().StringVarP(&initOutputPath, "output", "o", "plan.md", "Output path for the plan template")
 
 	// Status command uses same plan path flag
 	statusCmd.Flags().StringVarP(&runPlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	statusCmd.Flags().StringSliceVar(&statusTags, "tag", nil, "Only list the steps with one of these #tags (repeatable or comma-separated)")
 
 	// Add commands
 	rootCmd.AddCommand(runCmd)
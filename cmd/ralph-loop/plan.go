@@ -0,0 +1,747 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/importer"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Plan command
+var (
+	planPath    string
+	planReplace bool
+	planDryRun  bool
+	planYes     bool
+	planJSON    bool
+	planOutput  string
+	planForce   bool
+	planFailed  bool
+	planAll     bool
+	planReason  string
+	planJQL     string
+	planJiraURL string
+	planTitle   string
+	planLimit   int
+	planRev     string
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Edit the plan",
+}
+
+var planEditCmd = &cobra.Command{
+	Use:   "edit --replace OLD NEW",
+	Short: "Rewrite text across the plan",
+	Long: `Rewrite text across the plan, e.g. when a project or service is renamed
+mid-plan.
+
+With --replace, every occurrence of OLD in the project name, context, step
+descriptions and notes becomes NEW. Structure is never touched: headers,
+step markers, (agent: ...) annotations and recorded values such as the
+status and retries stay as they are. The changes are previewed as a diff
+and applied after confirmation (or with --yes).
+
+Changing the context makes completed steps stale (see 'ralph-loop status').
+Frozen plans must be unfrozen first.`,
+	Example: `  ralph-loop plan edit --replace old-service new-service
+  ralph-loop plan edit --replace "user API" "account API" --dry-run`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !planReplace {
+			return fmt.Errorf("nothing to do; use --replace OLD NEW")
+		}
+		old, new := args[0], args[1]
+		if old == "" {
+			return fmt.Errorf("the text to replace is empty")
+		}
+		if strings.ContainsAny(old+new, "\r\n") {
+			return fmt.Errorf("replacement text must be a single line")
+		}
+
+		changes, err := plan.ReplaceInFile(planPath, old, new, true)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			fmt.Printf("No occurrences of %q in %s\n", old, planPath)
+			return nil
+		}
+
+		printReplacements(changes)
+		if planDryRun {
+			return nil
+		}
+		if !planYes && !confirm(fmt.Sprintf("Apply %d change(s) to %s?", len(changes), planPath)) {
+			fmt.Println("No changes made.")
+			return nil
+		}
+
+		if _, err := plan.ReplaceInFile(planPath, old, new, false); err != nil {
+			return err
+		}
+		fmt.Printf("Updated %d line(s) in %s\n", len(changes), planPath)
+		return nil
+	},
+}
+
+var planExportCmd = &cobra.Command{
+	Use:   "export --json",
+	Short: "Export the plan as JSON",
+	Long: `Export the plan as JSON, for project trackers, dashboards and other tools
+that consume plans programmatically.
+
+The export includes the project name, context, glossary and every step with
+its annotation (agent, model, max_cost, max_duration, estimate, priority) and recorded progress
+(status, last run, notes, retries, artifacts, session). It is written to
+stdout unless --output is given.`,
+	Example: `  ralph-loop plan export --json
+  ralph-loop plan export --json -o plan.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !planJSON {
+			return fmt.Errorf("nothing to do; use --json")
+		}
+		p, err := plan.ParseFile(planPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		data, err := plan.MarshalJSON(p)
+		if err != nil {
+			return err
+		}
+		if planOutput == "" || planOutput == "-" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(planOutput, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", planOutput, err)
+		}
+		fmt.Printf("Exported %d step(s) to %s\n", len(p.Steps), planOutput)
+		return nil
+	},
+}
+
+var planImportCmd = &cobra.Command{
+	Use:   "import FILE.json",
+	Short: "Create the plan from JSON",
+	Long: `Create the plan from JSON, as written by 'ralph-loop plan export --json'
+or generated by another tool. Use - to read from stdin.
+
+Only "project" and each step's "description" are required; a missing status
+means pending. Steps are numbered by their position in the list. Unknown
+fields are rejected so typos don't go unnoticed.
+
+An existing plan is only replaced with --force, and a frozen plan must be
+unfrozen first.`,
+	Example: `  ralph-loop plan import plan.json
+  tracker-export | ralph-loop plan import - --plan sprint.md`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read plan JSON: %w", err)
+		}
+		p, err := plan.UnmarshalJSON(data)
+		if err != nil {
+			return err
+		}
+		if err := checkImportTarget(); err != nil {
+			return err
+		}
+		return writeImportedPlan(p)
+	},
+}
+
+var planImportJiraCmd = &cobra.Command{
+	Use:   "jira --jql QUERY",
+	Short: "Create the plan from Jira issues",
+	Long: `Create the plan from the Jira issues a JQL query finds, one pending step
+per issue in the order Jira returns them. Each step's description is the
+issue's summary, and its (ticket: KEY) annotation keeps the issue key, which
+run records carry so results can be mapped back to the board.
+
+Credentials are read from the environment:
+  JIRA_URL        Site URL, e.g. https://acme.atlassian.net (or --url)
+  JIRA_USER       Account email, for Jira Cloud
+  JIRA_API_TOKEN  API token, or a personal access token on Jira Server
+
+An existing plan is only replaced with --force, and a frozen plan must be
+unfrozen first.`,
+	Example: `  ralph-loop plan import jira --jql "sprint in openSprints() AND assignee = currentUser() ORDER BY rank"
+  ralph-loop plan import jira --jql "project = API AND statusCategory != Done" --title "API backlog" -p api.md`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if strings.TrimSpace(planJQL) == "" {
+			return fmt.Errorf("--jql is required")
+		}
+		if planLimit < 0 {
+			return fmt.Errorf("--limit must not be negative")
+		}
+		if err := checkImportTarget(); err != nil {
+			return err
+		}
+		if planJiraURL == "" {
+			planJiraURL = os.Getenv("JIRA_URL")
+		}
+		client, err := importer.NewJiraClient(importer.JiraOptions{
+			BaseURL:  planJiraURL,
+			User:     os.Getenv("JIRA_USER"),
+			APIToken: os.Getenv("JIRA_API_TOKEN"),
+		})
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		tickets, err := client.Search(ctx, planJQL, planLimit)
+		if err != nil {
+			return fmt.Errorf("jira search failed: %w", err)
+		}
+		if len(tickets) == 0 {
+			return fmt.Errorf("no issues match the query")
+		}
+		return writeImportedPlan(importer.JiraPlan(planTitle, planJQL, tickets))
+	},
+}
+
+// checkImportTarget refuses to import over an existing plan without --force,
+// and over a frozen one
+func checkImportTarget() error {
+	existing, err := os.ReadFile(planPath)
+	if err != nil {
+		return nil
+	}
+	if plan.IsFrozen(string(existing)) {
+		return plan.ErrPlanFrozen
+	}
+	if !planForce {
+		return fmt.Errorf("%s already exists; use --force to replace it", planPath)
+	}
+	return nil
+}
+
+// writeImportedPlan writes an imported plan to the plan path
+func writeImportedPlan(p *plan.Plan) error {
+	if err := plan.WriteFile(planPath, p); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s with %d step(s)\n", planPath, len(p.Steps))
+	return nil
+}
+
+var planAddCmd = &cobra.Command{
+	Use:   "add DESCRIPTION",
+	Short: "Append a step to the plan",
+	Long: `Append a pending step to the end of the plan, with its notes section.
+
+Use 'ralph-loop step add' to add the steps of a template.`,
+	Example: `  ralph-loop plan add "Add rate limiting to the API"`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		description, err := stepDescription(args[0])
+		if err != nil {
+			return err
+		}
+		numbers, err := plan.AppendSteps(planPath, []string{description})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added Step %d: %s\n", numbers[0], description)
+		return nil
+	},
+}
+
+var planInsertCmd = &cobra.Command{
+	Use:   "insert N DESCRIPTION",
+	Short: "Insert a step at a position in the plan",
+	Long: `Insert a pending step as step N. Step N and the steps after it move down
+by one: their labels, notes sections and (after: ...) references are
+renumbered to match.`,
+	Example: `  ralph-loop plan insert 2 "Add the database migration"`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		position, err := stepNumberArg(args[0])
+		if err != nil {
+			return err
+		}
+		description, err := stepDescription(args[1])
+		if err != nil {
+			return err
+		}
+		if err := checkNotRunning(planPath); err != nil {
+			return err
+		}
+		if err := plan.InsertStep(planPath, position, description); err != nil {
+			return err
+		}
+		fmt.Printf("Inserted Step %d: %s\n", position, description)
+		return nil
+	},
+}
+
+var planRemoveCmd = &cobra.Command{
+	Use:   "remove N",
+	Short: "Remove a step from the plan",
+	Long: `Remove step N and its notes section. The steps after it move up by one:
+their labels, notes sections and (after: ...) references are renumbered to
+match.
+
+A step that other steps depend on can't be removed until their (after: ...)
+annotations no longer name it. The removal is confirmed first, unless --yes
+is given.`,
+	Example: `  ralph-loop plan remove 3
+  ralph-loop plan remove 3 --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		number, err := stepNumberArg(args[0])
+		if err != nil {
+			return err
+		}
+		if err := checkNotRunning(planPath); err != nil {
+			return err
+		}
+		p, err := plan.ParseFile(planPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		if number > len(p.Steps) {
+			return fmt.Errorf("step %d does not exist (the plan has %d)", number, len(p.Steps))
+		}
+		if plan.IsFrozen(p.RawContent) {
+			return plan.ErrPlanFrozen
+		}
+		step := p.Steps[number-1]
+		if !planYes && !confirm(fmt.Sprintf("Remove Step %d: %s (%s)?", number, step.Description, step.Status)) {
+			fmt.Println("No changes made.")
+			return nil
+		}
+
+		if err := plan.RemoveStep(planPath, number); err != nil {
+			return err
+		}
+		fmt.Printf("Removed Step %d: %s\n", number, step.Description)
+		return nil
+	},
+}
+
+var planMoveCmd = &cobra.Command{
+	Use:   "move N M",
+	Short: "Move a step to another position in the plan",
+	Long: `Move step N to position M, shifting the steps in between. Labels, notes
+sections and (after: ...) references are renumbered to match, so every
+step keeps its status, notes and dependencies.
+
+Moving a step ahead of a step it depends on doesn't make it run earlier:
+it still waits for its dependencies.`,
+	Example: `  ralph-loop plan move 5 2`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, err := stepNumberArg(args[0])
+		if err != nil {
+			return err
+		}
+		to, err := stepNumberArg(args[1])
+		if err != nil {
+			return err
+		}
+		if from == to {
+			fmt.Printf("Step %d is already at position %d\n", from, to)
+			return nil
+		}
+		if err := checkNotRunning(planPath); err != nil {
+			return err
+		}
+		if err := plan.MoveStep(planPath, from, to); err != nil {
+			return err
+		}
+		fmt.Printf("Moved Step %d to position %d\n", from, to)
+		return nil
+	},
+}
+
+var planResetCmd = &cobra.Command{
+	Use:   "reset [N...] [--failed | --all]",
+	Short: "Set steps back to pending",
+	Long: `Set steps back to pending with their retry count at zero, so the loop
+runs them again from scratch, e.g. after fixing the environment that made
+them fail.
+
+Give step numbers to reset those steps, --failed to reset every failed and
+skipped step, or --all to reset every step and start the plan over. A
+completed step's sub-steps are unchecked too; a failed or skipped step
+keeps the ones it finished. Notes are kept for reference. Resetting a
+completed step asks for confirmation first, unless --yes is given.`,
+	Example: `  ralph-loop plan reset --failed
+  ralph-loop plan reset 3 4
+  ralph-loop plan reset --all --yes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		selectors := 0
+		for _, given := range []bool{len(args) > 0, planFailed, planAll} {
+			if given {
+				selectors++
+			}
+		}
+		if selectors != 1 {
+			return fmt.Errorf("give step numbers, --failed or --all")
+		}
+		if err := checkNotRunning(planPath); err != nil {
+			return err
+		}
+		p, err := plan.ParseFile(planPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		if plan.IsFrozen(p.RawContent) {
+			return plan.ErrPlanFrozen
+		}
+
+		var steps []plan.Step
+		for _, arg := range args {
+			number, err := stepNumberArg(arg)
+			if err != nil {
+				return err
+			}
+			if number > len(p.Steps) {
+				return fmt.Errorf("step %d does not exist (the plan has %d)", number, len(p.Steps))
+			}
+			steps = append(steps, p.Steps[number-1])
+		}
+		for _, step := range p.Steps {
+			failed := step.Status == plan.StatusFailed || step.Status == plan.StatusSkipped ||
+				step.Status == plan.StatusBlocked && step.RetryCount > 0
+			if planAll || planFailed && failed {
+				steps = append(steps, step)
+			}
+		}
+
+		var numbers []int
+		completed := 0
+		for _, step := range steps {
+			if step.Status == plan.StatusPending && step.RetryCount == 0 {
+				continue
+			}
+			numbers = append(numbers, step.Number)
+			if step.Status == plan.StatusCompleted {
+				completed++
+			}
+		}
+		if len(numbers) == 0 {
+			fmt.Println("Nothing to reset")
+			return nil
+		}
+		if completed > 0 && !planYes && !confirm(fmt.Sprintf("Reset %d completed step(s) so they run again?", completed)) {
+			fmt.Println("No changes made.")
+			return nil
+		}
+
+		if err := plan.ResetSteps(planPath, numbers); err != nil {
+			return err
+		}
+		for _, number := range numbers {
+			step := p.Steps[number-1]
+			fmt.Printf("Reset Step %d: %s (was %s, %d retries)\n", number, step.Description, step.Status, step.RetryCount)
+		}
+		return nil
+	},
+}
+
+var planSkipCmd = &cobra.Command{
+	Use:   "skip N --reason REASON",
+	Short: "Mark a step as skipped",
+	Long: `Mark step N as skipped, with the reason recorded in its notes, so the loop
+moves past it, e.g. for a step you will do yourself.
+
+Steps that depend on it become blocked, as they would if the loop had
+skipped it. Use 'ralph-loop plan reset N' to run it after all.`,
+	Example: `  ralph-loop plan skip 4 --reason "Doing the DNS change by hand"`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		number, err := stepNumberArg(args[0])
+		if err != nil {
+			return err
+		}
+		reason, err := stepDescription(planReason)
+		if err != nil {
+			return fmt.Errorf("give a one-line --reason, so the plan records why the step was skipped")
+		}
+		if err := checkNotRunning(planPath); err != nil {
+			return err
+		}
+		p, err := plan.ParseFile(planPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		if number > len(p.Steps) {
+			return fmt.Errorf("step %d does not exist (the plan has %d)", number, len(p.Steps))
+		}
+		step := p.Steps[number-1]
+		switch step.Status {
+		case plan.StatusSkipped:
+			fmt.Printf("Step %d is already skipped\n", number)
+			return nil
+		case plan.StatusCompleted:
+			return fmt.Errorf("step %d is completed; reset it first to skip it", number)
+		}
+
+		if err := plan.SkipStep(planPath, number, reason); err != nil {
+			return err
+		}
+		fmt.Printf("Skipped Step %d: %s\n", number, step.Description)
+
+		wasBlocked := make(map[int]bool)
+		for _, blocked := range p.Blocked() {
+			wasBlocked[blocked.Number] = true
+		}
+		if updated, err := plan.ParseFile(planPath); err == nil {
+			for _, blocked := range updated.Blocked() {
+				if !wasBlocked[blocked.Number] {
+					fmt.Printf("  Step %d is now blocked: %s\n", blocked.Number, updated.BlockedReason(&blocked))
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var planMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the plan to the current format",
+	Long: fmt.Sprintf(`Upgrade a plan written by an older ralph-loop to the current plan format
+(version %d), so newer features find the structure they expect: a Context
+section, a notes section with a **Retries** field for every step, and the
+format marker that records the version.
+
+Only missing structure is added; steps, notes and anything else you wrote
+are kept. The old version is kept in .ralph-loop/backups.`, plan.FormatVersion),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkNotRunning(planPath); err != nil {
+			return err
+		}
+		applied, err := plan.Migrate(planPath)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Printf("%s already uses plan format %d\n", planPath, plan.FormatVersion)
+			return nil
+		}
+		fmt.Printf("Migrated %s to plan format %d:\n", planPath, plan.FormatVersion)
+		for _, summary := range applied {
+			fmt.Printf("  %s\n", summary)
+		}
+		return nil
+	},
+}
+
+var planDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show how the steps changed since the last run",
+	Long: `Compare the plan with an earlier version and list the steps that were
+added, removed, or changed: status, notes, retries, annotation, checked
+sub-steps, or position. Steps are matched by description, so a reworded
+step shows as removed and added.
+
+By default the earlier version is the plan as it was when the last run
+started, taken from the plan backups in .ralph-loop/backups. Without a
+recorded run, the newest backup is used. --rev compares with the plan in a
+git revision instead.`,
+	Example: `  ralph-loop plan diff
+  ralph-loop plan diff --rev HEAD~1`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		current, err := plan.ParseFile(planPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		content, label, err := diffBase()
+		if err != nil {
+			return err
+		}
+		earlier, err := plan.Parse(content)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", label, err)
+		}
+
+		fmt.Printf("Comparing %s with %s\n\n", planPath, label)
+		diffs := plan.DiffSteps(earlier, current)
+		if len(diffs) == 0 {
+			fmt.Println("No step changes.")
+			return nil
+		}
+		added, removed, changed := 0, 0, 0
+		for _, d := range diffs {
+			switch {
+			case d.Old == nil:
+				added++
+				fmt.Printf("  + Step %d: %s\n", d.New.Number, d.New.Description)
+			case d.New == nil:
+				removed++
+				fmt.Printf("  - Step %d: %s\n", d.Old.Number, d.Old.Description)
+			default:
+				changed++
+				fmt.Printf("  ~ Step %d: %s\n", d.New.Number, d.New.Description)
+				for _, change := range d.Changes() {
+					fmt.Printf("      %s\n", change)
+				}
+			}
+		}
+		fmt.Printf("\nSummary: %d added, %d removed, %d changed\n", added, removed, changed)
+		return nil
+	},
+}
+
+// diffBase returns the earlier version of the plan that plan diff compares
+// with, and what it is
+func diffBase() (content string, label string, err error) {
+	if planRev != "" {
+		git := exec.Command("git", "show", planRev+":./"+filepath.Base(planPath))
+		git.Dir = filepath.Dir(planPath)
+		var stderr bytes.Buffer
+		git.Stderr = &stderr
+		out, err := git.Output()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read the plan at %s: %s", planRev, strings.TrimSpace(stderr.String()))
+		}
+		return string(out), "the plan at " + planRev, nil
+	}
+
+	backups, err := plan.Backups(planPath)
+	if err != nil {
+		return "", "", err
+	}
+	runs, err := loop.PlanRuns(planPath)
+	if err != nil {
+		return "", "", err
+	}
+	if len(runs) > 0 {
+		// The first backup taken during the run holds the plan it started from
+		last := runs[len(runs)-1]
+		label = fmt.Sprintf("the plan when the last run started (%s, run %s)", last.StartedAt.Local().Format("2006-01-02 15:04"), last.RunID)
+		for _, backup := range backups {
+			if !backup.Taken.Before(last.StartedAt) {
+				read, err := os.ReadFile(backup.Path)
+				return string(read), label, err
+			}
+		}
+		// The plan hasn't been written since
+		read, err := os.ReadFile(planPath)
+		return string(read), label, err
+	}
+	if len(backups) == 0 {
+		return "", "", fmt.Errorf("no earlier version of %s to compare with: it has no recorded runs or backups yet (use --rev to compare with a git revision)", planPath)
+	}
+	newest := backups[len(backups)-1]
+	read, err := os.ReadFile(newest.Path)
+	return string(read), fmt.Sprintf("its last backup (%s)", newest.Taken.Format("2006-01-02 15:04:05")), err
+}
+
+// stepNumberArg parses a step number or position argument
+func stepNumberArg(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid step number %q", arg)
+	}
+	return n, nil
+}
+
+// stepDescription checks a step description given on the command line
+func stepDescription(arg string) (string, error) {
+	description := strings.TrimSpace(arg)
+	if description == "" {
+		return "", fmt.Errorf("the step description is empty")
+	}
+	if strings.ContainsAny(description, "\r\n") {
+		return "", fmt.Errorf("the step description must be a single line")
+	}
+	return description, nil
+}
+
+// checkNotRunning refuses to change steps under a running loop, which
+// would record its results over the change
+func checkNotRunning(path string) error {
+	lock, err := loop.ReadLock(path)
+	if err == nil && lock != nil && lock.IsAlive() {
+		return fmt.Errorf("a loop is running this plan (PID %d); stop it before changing its steps", lock.PID)
+	}
+	return nil
+}
+
+// printReplacements shows replacements as a line diff
+func printReplacements(changes []plan.Replacement) {
+	for _, change := range changes {
+		fmt.Printf("%s:%d (%s)\n", planPath, change.Line, change.Section)
+		fmt.Printf("- %s\n", change.Before)
+		fmt.Printf("+ %s\n\n", change.After)
+	}
+}
+
+// confirm asks a yes/no question on stdin; anything but y or yes is no
+func confirm(question string) bool {
+	return confirmWith(bufio.NewReader(os.Stdin), question)
+}
+
+func init() {
+	planCmd.PersistentFlags().StringVarP(&planPath, "plan", "p", "plan.md", "Path to the plan file")
+
+	planEditCmd.Flags().BoolVar(&planReplace, "replace", false, "Replace OLD with NEW across the plan's prose")
+	planEditCmd.Flags().BoolVar(&planDryRun, "dry-run", false, "Show the changes without applying them")
+	planEditCmd.Flags().BoolVarP(&planYes, "yes", "y", false, "Apply without asking for confirmation")
+
+	planExportCmd.Flags().BoolVar(&planJSON, "json", false, "Export as JSON")
+	planExportCmd.Flags().StringVarP(&planOutput, "output", "o", "", "Write to this file instead of stdout")
+
+	planImportCmd.Flags().BoolVarP(&planForce, "force", "f", false, "Replace an existing plan")
+
+	planImportJiraCmd.Flags().StringVar(&planJQL, "jql", "", "JQL query selecting the issues to import")
+	planImportJiraCmd.Flags().StringVar(&planJiraURL, "url", "", "Jira site URL (default $JIRA_URL)")
+	planImportJiraCmd.Flags().StringVar(&planTitle, "title", "Jira import", "Project title of the new plan")
+	planImportJiraCmd.Flags().IntVar(&planLimit, "limit", 0, "Import at most this many issues (0 means all)")
+	planImportJiraCmd.Flags().BoolVarP(&planForce, "force", "f", false, "Replace an existing plan")
+	planImportCmd.AddCommand(planImportJiraCmd)
+
+	planRemoveCmd.Flags().BoolVarP(&planYes, "yes", "y", false, "Remove without asking for confirmation")
+
+	planResetCmd.Flags().BoolVar(&planFailed, "failed", false, "Reset every failed and skipped step")
+	planResetCmd.Flags().BoolVar(&planAll, "all", false, "Reset every step")
+	planResetCmd.Flags().BoolVarP(&planYes, "yes", "y", false, "Reset completed steps without asking for confirmation")
+
+	planDiffCmd.Flags().StringVar(&planRev, "rev", "", "Compare with the plan in this git revision, e.g. HEAD~1")
+
+	planSkipCmd.Flags().StringVar(&planReason, "reason", "", "Why the step is skipped, recorded in its notes")
+
+	planCmd.AddCommand(planAddCmd)
+	planCmd.AddCommand(planInsertCmd)
+	planCmd.AddCommand(planRemoveCmd)
+	planCmd.AddCommand(planMoveCmd)
+	planCmd.AddCommand(planResetCmd)
+	planCmd.AddCommand(planSkipCmd)
+	planCmd.AddCommand(planMigrateCmd)
+	planCmd.AddCommand(planDiffCmd)
+	planCmd.AddCommand(planEditCmd)
+	planCmd.AddCommand(planExportCmd)
+	planCmd.AddCommand(planImportCmd)
+	rootCmd.AddCommand(planCmd)
+}
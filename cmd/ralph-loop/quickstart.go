@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/config"
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Quickstart command
+var quickstartPlanPath string
+
+var quickstartCmd = &cobra.Command{
+	Use:   "quickstart",
+	Short: "Set up ralph-loop in this repository, step by step",
+	Long: `Walk through a first run in the current repository.
+
+quickstart detects the project type and its verification command, checks
+which agent CLIs are installed and authenticated, and asks a few questions.
+It then writes .ralph-loop/config.json and a starter plan, and offers to
+run the first step on its own so you can review the result before running
+the rest.
+
+Existing config and plan files are never overwritten.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		in := bufio.NewReader(os.Stdin)
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Repository:")
+		kind, verify := loop.DetectProject(wd)
+		switch {
+		case kind == "":
+			doctorWarn("project type not recognized; enter a build/test command below")
+		case verify == "":
+			doctorWarn("%s project, but no test command found", kind)
+		default:
+			doctorOK("%s project, verified with: %s", kind, verify)
+		}
+		if _, err := exec.LookPath("git"); err != nil {
+			doctorWarn("git not found on PATH (failure bundles and context diffs need it)")
+		}
+
+		fmt.Println("\nAgents:")
+		agents := usableAgents()
+		if len(agents) == 0 {
+			fmt.Println("\nNo usable agent found. Install one of the CLIs above, then run quickstart again.")
+			return nil
+		}
+
+		fmt.Println()
+		agentName := ask(in, "Agent", agents[0])
+		agentType, err := agent.ParseAgentType(agentName)
+		if err != nil {
+			return err
+		}
+		name := ask(in, "Project name", filepath.Base(wd))
+		verify = ask(in, "Verification command (- for none)", verify)
+		if verify == "-" {
+			verify = ""
+		}
+
+		fmt.Println("\nDescribe the first steps, one per line. An empty line finishes.")
+		var steps []string
+		for {
+			line := ask(in, fmt.Sprintf("Step %d", len(steps)+1), "")
+			if line == "" {
+				break
+			}
+			steps = append(steps, line)
+		}
+		if len(steps) == 0 {
+			steps = starterSteps(verify)
+			fmt.Printf("Using a starter step: %s\n", steps[0])
+		}
+
+		fmt.Println()
+		if err := layout.ForPlan(quickstartPlanPath).Ensure(); err != nil {
+			return err
+		}
+		configPath := configPathFor("", quickstartPlanPath)
+		if err := writeQuickstartConfig(configPath, verify); err != nil {
+			return err
+		}
+		if err := writeQuickstartPlan(quickstartPlanPath, name, kind, verify, steps); err != nil {
+			return err
+		}
+
+		runArgs := fmt.Sprintf("--agent %s", agentType)
+		if quickstartPlanPath != "plan.md" {
+			runArgs += " --plan " + quickstartPlanPath
+		}
+		if verify == "" {
+			runArgs += " --no-verify"
+		}
+		fmt.Println()
+		if !confirmWith(in, fmt.Sprintf("Run the first step now with %s?", agentType)) {
+			fmt.Printf("When you're ready: ralph-loop run %s\n", runArgs)
+			return nil
+		}
+
+		runAgentType, runPlanPath, runMaxSteps, runNoVerify = string(agentType), quickstartPlanPath, 1, verify == ""
+		if err := runCmd.RunE(runCmd, nil); err != nil {
+			return err
+		}
+		fmt.Printf("\nReview the changes (git diff), then continue with: ralph-loop run %s\n", runArgs)
+		return nil
+	},
+}
+
+// usableAgents reports on each agent CLI and returns the names of those
+// that are installed and, where they require it, authenticated
+func usableAgents() []string {
+	var usable []string
+	for _, req := range agent.Requirements() {
+		path, err := exec.LookPath(req.Binary)
+		if err != nil {
+			doctorWarn("%s: not installed (%s)", req.Type, req.Install)
+			continue
+		}
+		if err := agent.CheckMinVersion(req.Type, agent.ProbeVersion(path)); err != nil {
+			doctorWarn("%v", err)
+			continue
+		}
+		switch {
+		case req.Credentials() != "":
+			doctorOK("%s: ready", req.Type)
+		case req.EnvRequired:
+			doctorWarn("%s: installed, but no credentials (%s)", req.Type, req.Login)
+			continue
+		default:
+			doctorOK("%s: installed; no credentials found, it may ask you to log in (%s)", req.Type, req.Login)
+		}
+		usable = append(usable, string(req.Type))
+	}
+	for _, name := range agent.Plugins() {
+		doctorOK("%s: plugin %s", name, agent.LookupPlugin(name))
+		usable = append(usable, name)
+	}
+	return usable
+}
+
+// starterSteps is the plan used when no steps are given: a small, safe
+// first task that exercises the verification gate
+func starterSteps(verify string) []string {
+	if verify == "" {
+		return []string{"Add a Development section to the README explaining how to build and test the project"}
+	}
+	return []string{fmt.Sprintf("Make `%s` pass on a clean checkout, changing as little as possible", verify)}
+}
+
+// writeQuickstartConfig writes a config file with the verification
+// command, unless one already exists or there is nothing to configure
+func writeQuickstartConfig(path string, verify string) error {
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("Kept the existing config file %s\n", path)
+		return nil
+	}
+	if verify == "" {
+		return nil
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false) // Keep && readable in the verify command
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(config.Config{Verify: verify}); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return nil
+}
+
+// writeQuickstartPlan writes the starter plan, unless the file exists
+func writeQuickstartPlan(path, name, kind, verify string, steps []string) error {
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("Kept the existing plan %s\n", path)
+		return nil
+	}
+
+	var context []string
+	if kind != "" {
+		context = append(context, fmt.Sprintf("This is a %s project.", kind))
+	}
+	if verify != "" {
+		context = append(context, fmt.Sprintf("`%s` must pass after every step.", verify))
+	}
+
+	p := &plan.Plan{ProjectName: name, Context: strings.Join(context, " ")}
+	for i, desc := range steps {
+		p.Steps = append(p.Steps, plan.Step{Number: i + 1, Description: desc, Status: plan.StatusPending})
+	}
+	if err := plan.WriteFile(path, p); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s with %d step(s)\n", path, len(steps))
+	return nil
+}
+
+// ask reads one answer from in, offering a default
+func ask(in *bufio.Reader, question string, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, _ := in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+	return answer
+}
+
+// confirmWith is confirm reading from a shared reader, so answers piped in
+// ahead of the question aren't lost to another reader's buffer
+func confirmWith(in *bufio.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	quickstartCmd.Flags().StringVarP(&quickstartPlanPath, "plan", "p", "plan.md", "Path of the plan to create")
+
+	rootCmd.AddCommand(quickstartCmd)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/export"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Report commands
+var (
+	reportPlanPath string
+	reportFormat   string
+	reportOutput   string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Render a shareable report of the plan's progress",
+	Long: `Render the plan's progress as a report for readers who don't read the
+plan file: each step's status, time spent, retries, cost and notes, with a
+link to the output of its last attempt.
+
+Time comes from each step's attempt history, and cost from the records of
+the runs kept in .ralph-loop/logs/transcripts. The latest run is
+summarized at the top, and the warnings it raised are listed at the end.
+
+The report is Markdown, or a standalone HTML page with --format html (the
+default when --output ends in .html).`,
+	Example: `  ralph-loop report > progress.md
+  ralph-loop report -o progress.html`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := reportFormat
+		if format == "" {
+			format = "markdown"
+			if ext := strings.ToLower(filepath.Ext(reportOutput)); ext == ".html" || ext == ".htm" {
+				format = "html"
+			}
+		}
+		if format != "markdown" && format != "html" {
+			return fmt.Errorf("unknown report format: %s (valid: markdown, html)", format)
+		}
+
+		p, err := plan.ParseFile(reportPlanPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		runs, err := loop.PlanRuns(reportPlanPath)
+		if err != nil {
+			return err
+		}
+
+		var run *export.RunSummary
+		if len(runs) > 0 {
+			last := runs[len(runs)-1]
+			run = &export.RunSummary{Agent: agentLabel(last.Agent, last.Model), StartedAt: last.StartedAt, FinishedAt: last.FinishedAt}
+			for _, warning := range last.Warnings {
+				run.Warnings = append(run.Warnings, warning.String())
+			}
+		}
+		report := export.NewReport(p, run)
+		report.Costs = make(map[int]float64)
+		for _, rec := range runs {
+			for _, a := range rec.Attempts {
+				report.Costs[a.Step] += a.CostUSD
+			}
+		}
+		// Transcript links are relative to the plan; keep them working from
+		// wherever the report is written
+		for i := range p.Steps {
+			p.Steps[i].Transcript = reportLink(p.Steps[i].Transcript, reportPlanPath, reportOutput)
+		}
+
+		content := export.Markdown(report)
+		if format == "html" {
+			content = export.HTML(report)
+		}
+		if reportOutput == "" {
+			fmt.Print(content)
+			return nil
+		}
+		if err := os.WriteFile(reportOutput, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", reportOutput, err)
+		}
+		fmt.Printf("Wrote %s\n", reportOutput)
+		return nil
+	},
+}
+
+// reportLink rewrites a location relative to the plan so it resolves from
+// the report's directory. URLs, absolute paths and reports printed to
+// stdout keep it as is.
+func reportLink(location string, planPath string, outputPath string) string {
+	if location == "" || outputPath == "" || strings.Contains(location, "://") || filepath.IsAbs(location) {
+		return location
+	}
+	planDir, err := filepath.Abs(filepath.Dir(planPath))
+	if err != nil {
+		return location
+	}
+	outputDir, err := filepath.Abs(filepath.Dir(outputPath))
+	if err != nil {
+		return location
+	}
+	rel, err := filepath.Rel(outputDir, filepath.Join(planDir, location))
+	if err != nil {
+		return location
+	}
+	return filepath.ToSlash(rel)
+}
+
+var reportCompareCmd = &cobra.Command{
+	Use:   "compare <run-a> <run-b>",
+	Short: "Compare two runs of a plan",
+	Long: `Contrast two runs of the same plan, e.g. with different agents, models
+or on different branches: per-step outcome, attempts, duration and cost,
+followed by the files each run changed.
+
+Every run stores a record as .ralph-loop/logs/transcripts/<run>/run.json. A run
+can be given as its run ID, a prefix of its directory name, or a path to
+its directory or run.json (e.g. one downloaded from a logs bucket).`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var runs [2]*loop.RunRecord
+		for i, arg := range args {
+			path, err := loop.FindRun(reportPlanPath, arg)
+			if err != nil {
+				return err
+			}
+			if runs[i], err = loop.ReadRunRecord(path); err != nil {
+				return err
+			}
+		}
+		printComparison(runs[0], runs[1])
+		return nil
+	},
+}
+
+// runTotals are a run's figures summed over its attempts
+type runTotals struct {
+	steps, completed, attempts int
+	tokens                     int
+	cost                       float64
+}
+
+func totals(rec *loop.RunRecord) runTotals {
+	var t runTotals
+	steps, attempts := rec.StepAttempts()
+	t.steps = len(steps)
+	for _, n := range steps {
+		if attempts[n][len(attempts[n])-1].Success {
+			t.completed++
+		}
+	}
+	for _, a := range rec.Attempts {
+		t.attempts++
+		t.tokens += a.InputTokens + a.OutputTokens
+		t.cost += a.CostUSD
+	}
+	return t
+}
+
+func printComparison(a, b *loop.RunRecord) {
+	ta, tb := totals(a), totals(b)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	row := func(label, va, vb string) {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", label, va, vb)
+	}
+
+	row("", "A", "B")
+	row("Run", a.RunID, b.RunID)
+	row("Branch", orDash(a.Branch), orDash(b.Branch))
+	row("Agent", agentLabel(a.Agent, a.Model), agentLabel(b.Agent, b.Model))
+	row("Started", a.StartedAt.Format("2006-01-02 15:04"), b.StartedAt.Format("2006-01-02 15:04"))
+	row("Duration", a.Duration().Round(time.Second).String(), b.Duration().Round(time.Second).String())
+	row("Steps completed", fmt.Sprintf("%d/%d", ta.completed, ta.steps), fmt.Sprintf("%d/%d", tb.completed, tb.steps))
+	row("Attempts", fmt.Sprint(ta.attempts), fmt.Sprint(tb.attempts))
+	row("Tokens", tokenLabel(ta.tokens), tokenLabel(tb.tokens))
+	row("Cost", costLabel(ta.cost), costLabel(tb.cost))
+	row("Changes", orDash(a.DiffStat), orDash(b.DiffStat))
+	w.Flush()
+
+	// Per-step outcomes, over the steps either run attempted
+	stepsA, attemptsA := a.StepAttempts()
+	stepsB, attemptsB := b.StepAttempts()
+	steps := append(slices.Clone(stepsA), stepsB...)
+	slices.Sort(steps)
+	steps = slices.Compact(steps)
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "Step\tA\tB")
+	for _, n := range steps {
+		first := attemptsB[n]
+		if len(attemptsA[n]) > 0 {
+			first = attemptsA[n]
+		}
+		description := first[0].Description
+		if first[0].Ticket != "" {
+			description = first[0].Ticket + " " + description
+		}
+		fmt.Fprintf(w, "%d. %s\t%s\t%s\n", n, truncate(description, 40), stepLabel(attemptsA[n]), stepLabel(attemptsB[n]))
+	}
+	w.Flush()
+
+	// Files changed by each run
+	fmt.Println()
+	onlyA, onlyB, both := splitFiles(a.FilesChanged, b.FilesChanged)
+	fmt.Printf("Files changed by both runs: %d\n", len(both))
+	printFiles("Only in A", onlyA)
+	printFiles("Only in B", onlyB)
+}
+
+// stepLabel summarizes a step's attempts in one run, e.g.
+// "done, 2 attempts, 4m10s, $0.31"
+func stepLabel(attempts []loop.AttemptRecord) string {
+	if len(attempts) == 0 {
+		return "-"
+	}
+	var seconds, cost float64
+	for _, a := range attempts {
+		seconds += a.DurationSec
+		cost += a.CostUSD
+	}
+	outcome := "failed"
+	if attempts[len(attempts)-1].Success {
+		outcome = "done"
+	}
+	parts := []string{outcome}
+	if len(attempts) == 1 {
+		parts = append(parts, "1 attempt")
+	} else {
+		parts = append(parts, fmt.Sprintf("%d attempts", len(attempts)))
+	}
+	parts = append(parts, (time.Duration(seconds * float64(time.Second))).Round(time.Second).String())
+	if cost > 0 {
+		parts = append(parts, costLabel(cost))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// splitFiles separates the files only one run changed from those both did
+func splitFiles(a, b []string) (onlyA, onlyB, both []string) {
+	for _, f := range a {
+		if slices.Contains(b, f) {
+			both = append(both, f)
+		} else {
+			onlyA = append(onlyA, f)
+		}
+	}
+	for _, f := range b {
+		if !slices.Contains(a, f) {
+			onlyB = append(onlyB, f)
+		}
+	}
+	return onlyA, onlyB, both
+}
+
+func printFiles(label string, files []string) {
+	if len(files) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", label)
+	for _, f := range files {
+		fmt.Printf("  %s\n", f)
+	}
+}
+
+func agentLabel(name, model string) string {
+	if model == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, model)
+}
+
+func tokenLabel(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fk", float64(n)/1000)
+}
+
+func costLabel(cost float64) string {
+	if cost == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+func init() {
+	reportCmd.PersistentFlags().StringVarP(&reportPlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	reportCmd.Flags().StringVar(&reportFormat, "format", "", "Report format: markdown or html (default markdown, or html for an .html --output)")
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "Write to this file instead of stdout")
+
+	reportCmd.AddCommand(reportCompareCmd)
+	rootCmd.AddCommand(reportCmd)
+}
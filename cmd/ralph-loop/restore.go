@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Restore command
+var (
+	restorePlanPath string
+	restoreStep     int
+	restoreYes      bool
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [--step N]",
+	Short: "Roll the repository back to how it was before a step ran",
+	Long: `Roll the repository back to the checkpoint taken before step N first ran.
+
+Before each step's first attempt, ralph-loop records the commit checked out
+and the working tree, untracked files included, as a git ref under
+refs/ralph-loop/checkpoints/. Restoring one moves the branch back to that
+commit and the working tree to what it held. Ignored files and the plan file
+are left alone; step N and the steps that ran after it are set back to
+pending, so the next run does them again.
+
+Commits made since the checkpoint leave the branch, and uncommitted changes
+are lost, so the restore is confirmed first, unless --yes is given. Without
+--step, the plan's checkpoints are listed.`,
+	Example: `  ralph-loop restore
+  ralph-loop restore --step 4`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := plan.ParseFile(restorePlanPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		checkpoints, err := loop.Checkpoints(restorePlanPath)
+		if err != nil {
+			return err
+		}
+		if restoreStep == 0 {
+			printCheckpoints(p, checkpoints)
+			return nil
+		}
+
+		if err := checkNotRunning(restorePlanPath); err != nil {
+			return err
+		}
+		if plan.IsFrozen(p.RawContent) {
+			return plan.ErrPlanFrozen
+		}
+		at := -1
+		for i, cp := range checkpoints {
+			if cp.Step == restoreStep {
+				at = i
+			}
+		}
+		if at < 0 {
+			return fmt.Errorf("there is no checkpoint for step %d; run 'ralph-loop restore' to list them", restoreStep)
+		}
+		cp := checkpoints[at]
+
+		// The step and every step that ran after it lose their work
+		var numbers []int
+		for _, later := range checkpoints[at:] {
+			if later.Step > len(p.Steps) {
+				continue
+			}
+			step := p.Steps[later.Step-1]
+			if step.Status != plan.StatusPending || step.RetryCount > 0 {
+				numbers = append(numbers, step.Number)
+			}
+		}
+
+		fmt.Printf("Checkpoint before Step %d, taken %s at commit %s\n", cp.Step, cp.CreatedAt.Format("2006-01-02 15:04:05"), shortCommit(cp.Head))
+		diff, err := loop.CheckpointDiff(restorePlanPath, cp)
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			fmt.Println("\nThe working tree already matches the checkpoint.")
+		} else {
+			fmt.Printf("\nChanges to the working tree:\n%s\n", diff)
+		}
+		if len(numbers) > 0 {
+			fmt.Printf("\nSteps set back to pending: %s\n", joinInts(numbers))
+		}
+		if !restoreYes && !confirm(fmt.Sprintf("\nRestore the repository to before Step %d? Later commits leave the branch and uncommitted changes are lost.", cp.Step)) {
+			fmt.Println("No changes made.")
+			return nil
+		}
+
+		if err := loop.RestoreCheckpoint(restorePlanPath, cp); err != nil {
+			return err
+		}
+		if len(numbers) > 0 {
+			if err := plan.ResetSteps(restorePlanPath, numbers); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Restored the repository to before Step %d (HEAD at %s)\n", cp.Step, shortCommit(cp.Head))
+		return nil
+	},
+}
+
+// printCheckpoints lists a plan's checkpoints, oldest first
+func printCheckpoints(p *plan.Plan, checkpoints []loop.Checkpoint) {
+	if len(checkpoints) == 0 {
+		fmt.Println("No checkpoints yet; one is taken before each step runs")
+		return
+	}
+	fmt.Println("Checkpoints, oldest first:")
+	for _, cp := range checkpoints {
+		description := "(no longer in the plan)"
+		if cp.Step <= len(p.Steps) {
+			description = p.Steps[cp.Step-1].Description
+		}
+		fmt.Printf("  Step %-3d %s  %s  %s\n", cp.Step, cp.CreatedAt.Format("2006-01-02 15:04"), shortCommit(cp.Head), description)
+	}
+	fmt.Println("\nRun 'ralph-loop restore --step N' to go back to before step N.")
+}
+
+// joinInts formats numbers as a comma-separated list
+func joinInts(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, n := range numbers {
+		parts[i] = fmt.Sprint(n)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	restoreCmd.Flags().StringVarP(&restorePlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	restoreCmd.Flags().IntVar(&restoreStep, "step", 0, "Restore the checkpoint taken before this step ran")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Restore without asking for confirmation")
+
+	rootCmd.AddCommand(restoreCmd)
+}
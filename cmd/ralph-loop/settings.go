@@ -0,0 +1,475 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/config"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// runSettings is the fully resolved configuration of a run: flags first,
+// then the config file, then defaults and project detection
+type runSettings struct {
+	ConfigPath string
+	File       *config.Config
+	AgentType  agent.AgentType
+	Agent      agent.Options
+	Loop       loop.Config
+
+	// agentArgs holds pass-through arguments per agent type, so per-step
+	// agent overrides get their own
+	agentArgs map[agent.AgentType][]string
+
+	// ptyAgents are the agent types run in a PTY
+	ptyAgents map[agent.AgentType]bool
+
+	// caps caches the capabilities probed for each agent type, and
+	// versionErrs the CLIs found too old to run
+	caps        map[agent.AgentType]*agent.Capabilities
+	versionErrs map[agent.AgentType]error
+}
+
+// optionsFor returns the agent options for a step run by agentType
+func (s *runSettings) optionsFor(agentType agent.AgentType) agent.Options {
+	opts := s.Agent
+	opts.ExtraArgs = s.agentArgs[agentType]
+	opts.PTY = s.ptyAgents[agentType]
+	opts.Capabilities = s.capabilities(agentType)
+	return opts
+}
+
+// capabilities probes the installed version of an agent's CLI, once per
+// agent type, and prints the features it falls back from. Agents on a
+// remote backend aren't probed.
+func (s *runSettings) capabilities(agentType agent.AgentType) *agent.Capabilities {
+	if s.Agent.Backend != nil {
+		return nil
+	}
+	if caps, ok := s.caps[agentType]; ok {
+		return caps
+	}
+	caps, notes, err := agent.DetectCapabilities(agentType)
+	for _, note := range notes {
+		fmt.Printf("Note: %s\n", note)
+	}
+	if err != nil && runAnyVersion {
+		fmt.Printf("Warning: %v; running it anyway (--skip-version-check)\n", err)
+		err = nil
+	}
+	if s.caps == nil {
+		s.caps = make(map[agent.AgentType]*agent.Capabilities)
+		s.versionErrs = make(map[agent.AgentType]error)
+	}
+	s.caps[agentType] = caps
+	s.versionErrs[agentType] = err
+	return caps
+}
+
+// checkVersion refuses an agent whose installed CLI is too old for the
+// flags it is run with, unless --skip-version-check is set
+func (s *runSettings) checkVersion(agentType agent.AgentType) error {
+	s.capabilities(agentType)
+	if err := s.versionErrs[agentType]; err != nil {
+		return fmt.Errorf("%w\nPass --skip-version-check to run it anyway", err)
+	}
+	return nil
+}
+
+// resolveRunSettings merges the run flags with the plan's frontmatter and
+// the config file. The frontmatter supplies the settings whose flags were
+// not given on the command line; with nil flags, for a plan yet to be
+// written, it isn't read. With --workdir it first changes into the target directory, so the
+// plan, the config file, the agent and verification all work there.
+func resolveRunSettings(flags *pflag.FlagSet) (*runSettings, error) {
+	if runWorkDir != "" {
+		if err := os.Chdir(runWorkDir); err != nil {
+			return nil, fmt.Errorf("--workdir: %w", err)
+		}
+	}
+
+	// Run defaults from the plan, for the flags not given
+	frontmatter := &plan.Frontmatter{}
+	if flags != nil {
+		fm, err := plan.ReadFrontmatter(runPlanPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", runPlanPath, err)
+		}
+		if fm != nil {
+			frontmatter = fm
+		}
+	}
+	given := func(name string) bool {
+		return flags != nil && flags.Changed(name)
+	}
+	agentName, model := runAgentType, runModel
+	if frontmatter.Agent != "" && !given("agent") {
+		agentName = frontmatter.Agent
+	}
+	// The plan's model is meant for the plan's agent
+	if frontmatter.Model != "" && !given("model") && (frontmatter.Agent == "" || frontmatter.Agent == agentName) {
+		model = frontmatter.Model
+	}
+
+	// Parse agent type
+	agentType, err := agent.ParseAgentType(agentName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load project config
+	configPath := configPathFor(runConfigPath, runPlanPath)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Agent options
+	opts := agent.Options{
+		Model: model,
+	}
+	if cfg.CustomAgent != nil {
+		opts.Command = cfg.CustomAgent.Command
+		opts.CommandName = cfg.CustomAgent.Name
+	}
+	// Agent environment from the config file
+	if cfg.Env != nil {
+		for _, name := range slices.Sorted(maps.Keys(cfg.Env.Set)) {
+			opts.Env = append(opts.Env, name+"="+os.ExpandEnv(cfg.Env.Set[name]))
+		}
+		opts.UnsetEnv = cfg.Env.Unset
+	}
+
+	// Pass-through arguments: the config file per agent, then --agent-args
+	agentArgs := make(map[agent.AgentType][]string)
+	for name, line := range cfg.AgentArgs {
+		t, err := agent.ParseAgentType(name)
+		if err != nil {
+			return nil, fmt.Errorf("agent_args: %w", err)
+		}
+		if agentArgs[t], err = agent.ParseArgs(line); err != nil {
+			return nil, fmt.Errorf("agent_args.%s: %w", name, err)
+		}
+	}
+	if runAgentArgs != "" {
+		if agentArgs[agentType], err = agent.ParseArgs(runAgentArgs); err != nil {
+			return nil, fmt.Errorf("--agent-args: %w", err)
+		}
+	}
+	opts.ExtraArgs = agentArgs[agentType]
+
+	// PTY mode: the config file lists agents, --pty adds the run's agent
+	ptyAgents := make(map[agent.AgentType]bool)
+	for _, name := range cfg.PTY {
+		t, err := agent.ParseAgentType(name)
+		if err != nil {
+			return nil, fmt.Errorf("pty: %w", err)
+		}
+		ptyAgents[t] = true
+	}
+	if runPTY {
+		ptyAgents[agentType] = true
+	}
+	opts.PTY = ptyAgents[agentType]
+
+	switch runBackend {
+	case "local":
+	case "kubernetes":
+		backend, err := agent.NewKubernetesBackend(runK8s)
+		if err != nil {
+			return nil, err
+		}
+		opts.Backend = backend
+	default:
+		return nil, fmt.Errorf("unknown backend: %s (valid: local, kubernetes)", runBackend)
+	}
+	sandbox, err := agent.ParseSandbox(runSandbox)
+	if err != nil {
+		return nil, err
+	}
+	if sandbox != nil {
+		if opts.Backend != nil {
+			return nil, fmt.Errorf("--sandbox cannot be combined with --backend %s", runBackend)
+		}
+		opts.Backend = sandbox
+	}
+	if opts.Backend != nil && len(ptyAgents) > 0 {
+		return nil, fmt.Errorf("PTY mode only works with the local backend, not %s", opts.Backend.Name())
+	}
+
+	// Loop config from flags
+	loopConfig := loop.DefaultConfig()
+	loopConfig.Model = model
+	loopConfig.ResumeSessions = runResume
+	if runTimeout > 0 {
+		loopConfig.Timeout = runTimeout
+	}
+	if frontmatter.Timeout > 0 && !given("timeout") {
+		loopConfig.Timeout = frontmatter.Timeout
+	}
+	if runMaxRetries > 0 {
+		loopConfig.MaxRetries = runMaxRetries
+	}
+	if frontmatter.MaxRetries > 0 && !given("max-retries") {
+		loopConfig.MaxRetries = frontmatter.MaxRetries
+	}
+	if runTransient < 0 {
+		return nil, fmt.Errorf("--transient-retries must not be negative")
+	}
+	loopConfig.TransientRetries = runTransient
+	loopConfig.MaxSteps = runMaxSteps
+	if runPlanAdds < 0 {
+		return nil, fmt.Errorf("--max-plan-adds must not be negative")
+	}
+	loopConfig.MaxPlanAdds = runPlanAdds
+	if loopConfig.Budget, err = runBudget(cfg.Budget, given); err != nil {
+		return nil, err
+	}
+	if runPhase < 0 {
+		return nil, fmt.Errorf("--phase must not be negative")
+	}
+	loopConfig.Phase = runPhase
+	if runFrom < 0 || runUntil < 0 {
+		return nil, fmt.Errorf("--from and --until must not be negative")
+	}
+	if runUntil > 0 && runFrom > runUntil {
+		return nil, fmt.Errorf("--from %d is after --until %d", runFrom, runUntil)
+	}
+	loopConfig.From, loopConfig.Until = runFrom, runUntil
+	loopConfig.OnlyTags = nil
+	for _, tag := range runOnlyTags {
+		tag = plan.NormalizeTag(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("--only-tag must not be empty")
+		}
+		if !slices.Contains(loopConfig.OnlyTags, tag) {
+			loopConfig.OnlyTags = append(loopConfig.OnlyTags, tag)
+		}
+	}
+	if runRetryDelay > 0 {
+		loopConfig.RetryDelay = runRetryDelay
+	}
+	order := runOrder
+	if cfg.Order != "" && !given("order") {
+		order = cfg.Order
+	}
+	if _, err := plan.ParseOrderStrategy(order); err != nil {
+		return nil, err
+	}
+	loopConfig.Order = order
+	if !slices.Contains(loop.OnFailureModes(), runOnFailure) {
+		return nil, fmt.Errorf("unknown --on-failure mode: %s (valid: %s)", runOnFailure, strings.Join(loop.OnFailureModes(), ", "))
+	}
+	loopConfig.OnFailure = runOnFailure
+
+	if runRebase && runUpstream == "" {
+		return nil, fmt.Errorf("--rebase requires --upstream")
+	}
+	loopConfig.Upstream = runUpstream
+	loopConfig.Rebase = runRebase
+	loopConfig.Rollback = runRollback
+	loopConfig.Checkpoints = !runNoCheckpt
+	if (runRevAgent != "" || runRevModel != "") && !runReview {
+		return nil, fmt.Errorf("--review-agent and --review-model require --review")
+	}
+	if runRevAgent != "" {
+		if _, err := agent.ParseAgentType(runRevAgent); err != nil {
+			return nil, fmt.Errorf("--review-agent: %w", err)
+		}
+	}
+	loopConfig.Review, loopConfig.ReviewAgent, loopConfig.ReviewModel = runReview, runRevAgent, runRevModel
+	loopConfig.GitHubStatus = runGitHub
+
+	if cfg.Artifacts != nil {
+		loopConfig.Artifacts = cfg.Artifacts.Paths
+		loopConfig.ArtifactDest = cfg.Artifacts.Destination
+	}
+	if cfg.Logs != nil {
+		loopConfig.LogDest = cfg.Logs.Destination
+	}
+	loopConfig.Glossary = cfg.Glossary
+	for _, provider := range cfg.ContextProviders {
+		source := loop.ContextSource{Name: provider.Name, MaxTokens: provider.MaxTokens}
+		if err := loop.CheckContextSource(source); err != nil {
+			return nil, err
+		}
+		loopConfig.ContextProviders = append(loopConfig.ContextProviders, source)
+	}
+	if cfg.Denylist != nil {
+		if cfg.Denylist.ReplaceDefaults {
+			loopConfig.Denylist = nil
+		}
+		loopConfig.Denylist = append(slices.Clone(loopConfig.Denylist), cfg.Denylist.Patterns...)
+		if _, err := loop.CompileDenylist(loopConfig.Denylist); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Stall != nil {
+		stall, err := stallPolicy(cfg.Stall, loopConfig.Stall)
+		if err != nil {
+			return nil, err
+		}
+		loopConfig.Stall = stall
+	}
+	if given("stall-timeout") {
+		if runStallKill < 0 {
+			return nil, fmt.Errorf("--stall-timeout must not be negative")
+		}
+		loopConfig.Stall.Kill = runStallKill
+	}
+
+	loopConfig.Notify.Desktop = runNotifyDesk || cfg.Notify != nil && cfg.Notify.Desktop
+	if loopConfig.Notify.Webhook, err = webhookSettings(cfg, given("webhook")); err != nil {
+		return nil, err
+	}
+	if cfg.Notify != nil && cfg.Notify.Email != nil {
+		email := cfg.Notify.Email
+		loopConfig.Notify.Email = &loop.EmailSettings{
+			To:       email.To,
+			From:     email.From,
+			Host:     email.Host,
+			Port:     email.Port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	}
+
+	if h := cfg.Hooks; h != nil {
+		loopConfig.Hooks = loop.Hooks{PreStep: h.PreStep, PostStep: h.PostStep, OnFailure: h.OnFailure, OnComplete: h.OnComplete}
+		if h.Timeout != "" {
+			d, err := time.ParseDuration(h.Timeout)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("hooks.timeout: invalid duration %q (e.g. 90s or 10m)", h.Timeout)
+			}
+			loopConfig.Hooks.Timeout = d
+		}
+	}
+
+	// Verification: flag, then the plan, then the config file, then the
+	// project type's default
+	switch {
+	case runNoVerify:
+	case runVerify != "":
+		loopConfig.Verify = runVerify
+	case frontmatter.Verify != "":
+		loopConfig.Verify = frontmatter.Verify
+	case cfg.Verify != "":
+		loopConfig.Verify = cfg.Verify
+	default:
+		wd, _ := os.Getwd()
+		loopConfig.Verify = loop.DetectVerifyCommand(wd)
+	}
+
+	return &runSettings{
+		ConfigPath: configPath,
+		File:       cfg,
+		AgentType:  agentType,
+		Agent:      opts,
+		Loop:       loopConfig,
+		agentArgs:  agentArgs,
+		ptyAgents:  ptyAgents,
+	}, nil
+}
+
+// runBudget combines the config file's run budget with the --max-duration,
+// --max-cost and --max-iterations flags, which take precedence
+func runBudget(c *config.Budget, given func(name string) bool) (loop.RunBudget, error) {
+	var budget loop.RunBudget
+	if c != nil {
+		if c.MaxDuration != "" {
+			d, err := time.ParseDuration(c.MaxDuration)
+			if err != nil || d <= 0 {
+				return budget, fmt.Errorf("budget.max_duration: invalid duration %q (e.g. 4h or 90m)", c.MaxDuration)
+			}
+			budget.MaxDuration = d
+		}
+		budget.MaxCost, budget.MaxIterations = c.MaxCost, c.MaxIterations
+	}
+
+	if given("max-duration") {
+		if runMaxDur < 0 {
+			return budget, fmt.Errorf("--max-duration must not be negative")
+		}
+		budget.MaxDuration = runMaxDur
+	}
+	if given("max-cost") {
+		cost, err := strconv.ParseFloat(strings.TrimPrefix(runMaxCost, "$"), 64)
+		if err != nil || cost < 0 {
+			return budget, fmt.Errorf("invalid --max-cost %q (want a dollar amount, e.g. $10)", runMaxCost)
+		}
+		budget.MaxCost = cost
+	}
+	if given("max-iterations") {
+		budget.MaxIterations = runMaxIters
+	}
+	if budget.MaxCost < 0 || budget.MaxIterations < 0 {
+		return budget, fmt.Errorf("the run's max cost and max iterations must not be negative")
+	}
+	return budget, nil
+}
+
+// stallPolicy applies the config file's stall tiers over the defaults
+func stallPolicy(c *config.Stall, defaults loop.StallPolicy) (loop.StallPolicy, error) {
+	policy := defaults
+	policy.NotifyCommand = c.NotifyCommand
+	var err error
+	if policy.Warn, err = config.ParseStallDuration(c.Warn, defaults.Warn); err != nil {
+		return policy, fmt.Errorf("stall.warn: %w", err)
+	}
+	if policy.Notify, err = config.ParseStallDuration(c.Notify, defaults.Notify); err != nil {
+		return policy, fmt.Errorf("stall.notify: %w", err)
+	}
+	if policy.Answer, err = config.ParseStallDuration(c.Answer, defaults.Answer); err != nil {
+		return policy, fmt.Errorf("stall.answer: %w", err)
+	}
+	if c.AnswerText != "" {
+		policy.AnswerText = c.AnswerText
+	}
+	if policy.Kill, err = config.ParseStallDuration(c.Kill, defaults.Kill); err != nil {
+		return policy, fmt.Errorf("stall.kill: %w", err)
+	}
+	return policy, nil
+}
+
+// webhookSettings resolves where run events are posted: --webhook, else
+// notify.webhook in the config file. It returns nil when neither is set.
+func webhookSettings(cfg *config.Config, flagGiven bool) (*loop.WebhookSettings, error) {
+	settings := &loop.WebhookSettings{Retries: loop.DefaultWebhookRetries, Secret: os.Getenv(loop.WebhookSecretEnv)}
+	if cfg.Notify != nil && cfg.Notify.Webhook != nil {
+		w := cfg.Notify.Webhook
+		settings.URL, settings.Headers, settings.Events = w.URL, w.Headers, w.Events
+		if w.Retries > 0 {
+			settings.Retries = w.Retries
+		}
+	}
+	if flagGiven {
+		settings.URL = runWebhook
+	}
+	if settings.URL == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(settings.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("the webhook URL must be an http or https URL")
+	}
+	return settings, nil
+}
+
+// webhookHost returns a webhook URL's host, for printing: the path of a
+// chat webhook is often its secret
+func webhookHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "(invalid URL)"
+	}
+	return u.Host
+}
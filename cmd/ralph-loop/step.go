@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Step command
+var (
+	stepPlanPath     string
+	stepTemplate     string
+	stepParams       []string
+	stepTemplatesDir string
+)
+
+var stepCmd = &cobra.Command{
+	Use:   "step",
+	Short: "Add steps to the plan",
+}
+
+var stepAddCmd = &cobra.Command{
+	Use:   "add [description]",
+	Short: "Append a step, or the steps of a template, to the plan",
+	Long: `Append a pending step to the plan, or expand a step template.
+
+Templates are markdown files in the templates directory
+(.ralph-loop/templates next to the plan by default), one step per line
+with {{param}} placeholders:
+
+  # Add CRUD endpoints for {{entity}}
+  - Create the {{entity}} model and migration
+  - Add list and get endpoints for {{entity}}
+  - Add create, update and delete endpoints for {{entity}}
+
+Parameters not given with --param are prompted for.`,
+	Example: `  ralph-loop step add "Add rate limiting to the API"
+  ralph-loop step add --template crud --param entity=Invoice`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var descriptions []string
+
+		switch {
+		case stepTemplate != "" && len(args) > 0:
+			return fmt.Errorf("give either a description or --template, not both")
+		case stepTemplate != "":
+			t, err := plan.LoadStepTemplate(templatesDir(), stepTemplate)
+			if err != nil {
+				return err
+			}
+			params, err := parseParams(stepParams)
+			if err != nil {
+				return err
+			}
+			if err := promptMissingParams(t, params); err != nil {
+				return err
+			}
+			expanded, err := t.Expand(params)
+			if err != nil {
+				return err
+			}
+			// Parameters could otherwise smuggle extra step lines into the plan
+			for i, step := range expanded {
+				description, err := stepDescription(step)
+				if err != nil {
+					return fmt.Errorf("template step %d: %w", i+1, err)
+				}
+				descriptions = append(descriptions, description)
+			}
+		case len(args) == 1:
+			description, err := stepDescription(args[0])
+			if err != nil {
+				return err
+			}
+			descriptions = []string{description}
+		default:
+			return fmt.Errorf("give a step description or --template")
+		}
+
+		numbers, err := plan.AppendSteps(stepPlanPath, descriptions)
+		if err != nil {
+			return err
+		}
+
+		for i, num := range numbers {
+			fmt.Printf("Added Step %d: %s\n", num, descriptions[i])
+		}
+		return nil
+	},
+}
+
+var stepTemplatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List available step templates",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := templatesDir()
+		names, err := plan.ListStepTemplates(dir)
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			fmt.Printf("No templates found in %s\n", dir)
+			return nil
+		}
+
+		for _, name := range names {
+			t, err := plan.LoadStepTemplate(dir, name)
+			if err != nil {
+				fmt.Printf("  %s (invalid: %v)\n", name, err)
+				continue
+			}
+			params := ""
+			if p := t.Params(); len(p) > 0 {
+				params = fmt.Sprintf(" [params: %s]", strings.Join(p, ", "))
+			}
+			fmt.Printf("  %s - %d step(s)%s\n", name, len(t.Steps), params)
+			if t.Description != "" {
+				fmt.Printf("      %s\n", t.Description)
+			}
+		}
+		return nil
+	},
+}
+
+// templatesDir returns the configured templates directory or the default next to the plan
+func templatesDir() string {
+	if stepTemplatesDir != "" {
+		return stepTemplatesDir
+	}
+	return layout.ForPlan(stepPlanPath).Path(layout.Templates)
+}
+
+// parseParams parses key=value pairs from --param flags
+func parseParams(pairs []string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid --param %q (expected key=value)", pair)
+		}
+		if strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("empty value for template parameter: %s", strings.TrimSpace(key))
+		}
+		params[strings.TrimSpace(key)] = value
+	}
+	return params, nil
+}
+
+// promptMissingParams asks on stdin for template parameters not given as flags
+func promptMissingParams(t *plan.StepTemplate, params map[string]string) error {
+	reader := bufio.NewReader(os.Stdin)
+	for _, name := range t.Params() {
+		if _, ok := params[name]; ok {
+			continue
+		}
+		fmt.Printf("%s: ", name)
+		value, err := reader.ReadString('\n')
+		value = strings.TrimSpace(value)
+		if value == "" {
+			if err != nil {
+				return fmt.Errorf("missing value for template parameter: %s", name)
+			}
+			return fmt.Errorf("empty value for template parameter: %s", name)
+		}
+		params[name] = value
+	}
+	return nil
+}
+
+func init() {
+	stepCmd.PersistentFlags().StringVarP(&stepPlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	stepCmd.PersistentFlags().StringVar(&stepTemplatesDir, "templates-dir", "", "Directory containing step templates (default .ralph-loop/templates next to the plan)")
+
+	stepAddCmd.Flags().StringVar(&stepTemplate, "template", "", "Name of the step template to expand")
+	stepAddCmd.Flags().StringArrayVar(&stepParams, "param", nil, "Template parameter as key=value (repeatable)")
+
+	stepCmd.AddCommand(stepAddCmd)
+	stepCmd.AddCommand(stepTemplatesCmd)
+	rootCmd.AddCommand(stepCmd)
+}
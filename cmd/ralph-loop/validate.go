@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/config"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Validate command
+var (
+	validatePlanPath string
+	validateFix      bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the plan for structural problems and lint issues",
+	Long: `Check the plan file for structural problems and lint issues.
+
+Structural checks:
+  - Missing project name or steps
+  - Step labels that don't match their position
+  - Missing notes sections or **Retries** fields
+  - Completed steps whose context has changed since they ran
+
+Lint rules:
+  - Step descriptions that are too short or vague
+  - Steps that are likely too large (many action verbs)
+  - Code steps with no verification command to check them: none in the
+    plan's frontmatter or the config file, and none detected from the
+    project type
+
+With --fix, safe rewrites are applied: step labels are renumbered and
+missing notes sections and fields are scaffolded.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		content, err := os.ReadFile(validatePlanPath)
+		if err != nil {
+			return fmt.Errorf("failed to read plan file: %w", err)
+		}
+
+		if validateFix {
+			fixed, changes, err := plan.Fix(string(content))
+			if err != nil {
+				return fmt.Errorf("failed to fix plan: %w", err)
+			}
+			if changes > 0 {
+				if plan.IsFrozen(string(content)) {
+					return plan.ErrPlanFrozen
+				}
+				if err := plan.SaveContent(validatePlanPath, fixed); err != nil {
+					return err
+				}
+				fmt.Printf("Applied %d fix(es) to %s\n\n", changes, validatePlanPath)
+			}
+			content = []byte(fixed)
+		}
+
+		issues, err := plan.Validate(string(content), filepath.Dir(validatePlanPath))
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		if p, err := plan.Parse(string(content)); err == nil {
+			issues = append(issues, plan.CheckVerify(p, planVerifyCommand(p, validatePlanPath))...)
+		}
+
+		if len(issues) == 0 {
+			fmt.Printf("%s: no issues found\n", validatePlanPath)
+			return nil
+		}
+
+		errors := 0
+		fixable := 0
+		for _, issue := range issues {
+			fmt.Printf("  %s\n", issue)
+			if issue.Severity == plan.SeverityError {
+				errors++
+			}
+			if issue.Fixable {
+				fixable++
+			}
+		}
+
+		fmt.Printf("\n%d issue(s) found (%d error(s))\n", len(issues), errors)
+		if fixable > 0 && !validateFix {
+			fmt.Printf("Run 'ralph-loop validate --fix' to fix %d of them automatically\n", fixable)
+		}
+
+		if errors > 0 {
+			return fmt.Errorf("plan has %d error(s)", errors)
+		}
+		return nil
+	},
+}
+
+// planVerifyCommand returns the verification command a run of the plan
+// would use without --verify: the frontmatter's, the config file's, or the
+// one detected from the project type. An unreadable config file counts as
+// none, since validate reports on the plan.
+func planVerifyCommand(p *plan.Plan, planPath string) string {
+	if p.Frontmatter != nil && p.Frontmatter.Verify != "" {
+		return p.Frontmatter.Verify
+	}
+	if cfg, err := config.Load(configPathFor("", planPath)); err == nil && cfg.Verify != "" {
+		return cfg.Verify
+	}
+	return loop.DetectVerifyCommand(filepath.Dir(planPath))
+}
+
+func init() {
+	validateCmd.Flags().StringVarP(&validatePlanPath, "plan", "p", "plan.md", "Path to the plan file")
+	validateCmd.Flags().BoolVar(&validateFix, "fix", false, "Apply safe fixes (renumbering, notes scaffolding)")
+
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+)
+
+// enterWorktree starts a --worktree run: it creates the worktree, copies
+// the plan and the config file into it, since either may have edits that
+// aren't committed, and changes into it. The rest of the run then works in
+// the worktree as it would in the checkout.
+func enterWorktree() (*loop.Worktree, error) {
+	if runWorkDir != "" {
+		if err := os.Chdir(runWorkDir); err != nil {
+			return nil, fmt.Errorf("--workdir: %w", err)
+		}
+		runWorkDir = ""
+	}
+	if _, err := os.Stat(runPlanPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("plan file not found: %s\nRun 'ralph-loop init' to create one", runPlanPath)
+	}
+
+	wt, err := loop.CreateWorktree(runPlanPath)
+	if err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := wt.Dir(wd)
+	if err != nil {
+		return nil, err
+	}
+
+	configPath := runConfigPath
+	if configPath == "" {
+		configPath = layout.ForPlan(runPlanPath).Path(layout.ConfigFile)
+	}
+	for _, file := range []string{runPlanPath, configPath} {
+		if filepath.IsAbs(file) {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		target := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to copy %s into the worktree: %w", file, err)
+		}
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Running in worktree %s on branch %s\n", wt.Path, wt.Branch)
+	if wt.Dirty {
+		fmt.Println("Note: uncommitted changes in your checkout, other than to the plan and config, are not in the worktree")
+	}
+	return wt, nil
+}
+
+// printWorktreeSummary says where an isolated run's work is and how to
+// review, merge, continue and remove it. It runs in the worktree.
+func printWorktreeSummary(wt *loop.Worktree) {
+	fmt.Printf("\n=== Worktree run finished on branch %s ===\n", wt.Branch)
+	fmt.Printf("Worktree: %s\n", wt.Path)
+	fmt.Printf("Review:   git log --stat %s..%s\n", shortCommit(wt.Base), wt.Branch)
+	fmt.Printf("Merge:    git merge %s\n", wt.Branch)
+	if wd, err := os.Getwd(); err == nil {
+		fmt.Printf("Continue: ralph-loop run --workdir %s\n", wd)
+	}
+	fmt.Printf("Remove:   git worktree remove %s && git branch -D %s\n", wt.Path, wt.Branch)
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(rev string) string {
+	if len(rev) > 8 {
+		return rev[:8]
+	}
+	return rev
+}
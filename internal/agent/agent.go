@@ -24,6 +24,11 @@ const (
 	AgentTypeOpencode AgentType = "opencode"
 	AgentTypeClaude   AgentType = "claude"
 	AgentTypeCodex    AgentType = "codex"
+	AgentTypeGemini   AgentType = "gemini"
+	AgentTypeGoose    AgentType = "goose"
+	AgentTypeCopilot  AgentType = "copilot"
+	AgentTypeAmazonQ  AgentType = "q"
+	AgentTypeCustom   AgentType = "custom"
 )
 
 // Options configures agent behavior
@@ -31,6 +36,33 @@ type Options struct {
 	// Model specifies the model to use in format "provider/model"
 	// e.g., "openai/gpt-5.2", "anthropic/claude-sonnet-4-20250514"
 	Model string
+
+	// Backend runs the agent somewhere other than a local subprocess
+	// (e.g. a Kubernetes Job). nil runs it locally.
+	Backend Backend
+
+	// Command is the command template run by the custom agent, and
+	// CommandName its display name (see NewCustomAgent)
+	Command     string
+	CommandName string
+
+	// ExtraArgs are passed through to a built-in agent's CLI, before the
+	// prompt (see ParseArgs)
+	ExtraArgs []string
+
+	// Env sets extra environment variables for the agent process, as
+	// KEY=value, and UnsetEnv removes inherited ones (including the
+	// default CI and NONINTERACTIVE)
+	Env      []string
+	UnsetEnv []string
+
+	// Capabilities are the optional features the installed CLI supports
+	// (see DetectCapabilities). nil assumes all of them.
+	Capabilities *Capabilities
+
+	// PTY runs the agent attached to a pseudo-terminal, for CLIs that
+	// refuse to run or behave differently without a TTY. Unix only.
+	PTY bool
 }
 
 // New creates a new agent of the specified type with options
@@ -42,12 +74,26 @@ func New(agentType AgentType, opts Options) (Agent, error) {
 		return NewClaudeAgent(opts), nil
 	case AgentTypeCodex:
 		return NewCodexAgent(opts), nil
+	case AgentTypeGemini:
+		return NewGeminiAgent(opts), nil
+	case AgentTypeGoose:
+		return NewGooseAgent(opts), nil
+	case AgentTypeCopilot:
+		return NewCopilotAgent(opts), nil
+	case AgentTypeAmazonQ:
+		return NewAmazonQAgent(opts), nil
+	case AgentTypeCustom:
+		return NewCustomAgent(opts)
 	default:
+		if LookupPlugin(string(agentType)) != "" {
+			return NewPluginAgent(string(agentType), opts), nil
+		}
 		return nil, fmt.Errorf("unknown agent type: %s", agentType)
 	}
 }
 
-// ParseAgentType parses a string into an AgentType
+// ParseAgentType parses a string into an AgentType. Names other than the
+// built-in agents resolve to a ralph-agent-<name> plugin on PATH.
 func ParseAgentType(s string) (AgentType, error) {
 	switch s {
 	case "opencode":
@@ -56,7 +102,20 @@ func ParseAgentType(s string) (AgentType, error) {
 		return AgentTypeClaude, nil
 	case "codex":
 		return AgentTypeCodex, nil
+	case "gemini":
+		return AgentTypeGemini, nil
+	case "goose":
+		return AgentTypeGoose, nil
+	case "copilot":
+		return AgentTypeCopilot, nil
+	case "q", "amazonq":
+		return AgentTypeAmazonQ, nil
+	case "custom":
+		return AgentTypeCustom, nil
 	default:
-		return "", fmt.Errorf("unknown agent type: %s (valid: opencode, claude, codex)", s)
+		if LookupPlugin(s) != "" {
+			return AgentType(s), nil
+		}
+		return "", fmt.Errorf("unknown agent type: %s (valid: opencode, claude, codex, gemini, goose, copilot, q, custom, or a %s<name> plugin on PATH)", s, PluginPrefix)
 	}
 }
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"context"
+	"io"
+)
+
+// AmazonQAgent implements the Agent interface for the Amazon Q Developer CLI
+type AmazonQAgent struct {
+	processTracker
+	opts Options
+}
+
+// NewAmazonQAgent creates a new Amazon Q agent
+func NewAmazonQAgent(opts Options) *AmazonQAgent {
+	return &AmazonQAgent{opts: opts}
+}
+
+// Name returns the agent's name
+func (a *AmazonQAgent) Name() string {
+	return "q"
+}
+
+// Run executes q chat with the given prompt
+func (a *AmazonQAgent) Run(ctx context.Context, prompt string, output io.Writer) (string, error) {
+	// Build command args
+	// --no-interactive prints the response and exits; --trust-all-tools skips tool confirmations
+	args := []string{"chat", "--no-interactive", "--trust-all-tools"}
+
+	// Add model flag if specified
+	if a.opts.Model != "" {
+		args = append(args, "--model", a.opts.Model)
+	}
+
+	// Pass-through arguments (--agent-args)
+	args = append(args, a.opts.ExtraArgs...)
+
+	args = append(args, prompt)
+	return runCommand(ctx, &a.processTracker, a.opts, "q", args, output)
+}
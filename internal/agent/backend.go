@@ -0,0 +1,26 @@
+package agent
+
+import "context"
+
+// Backend decides where an agent command runs. Without a backend, agents run
+// as local subprocesses; a backend can instead run them in an isolated
+// environment and stream the output back through a local command.
+type Backend interface {
+	// Name identifies the backend in diagnostics
+	Name() string
+
+	// Prepare turns an agent invocation (binary, args, extra environment)
+	// into the local command whose output should be streamed
+	Prepare(ctx context.Context, binary string, args []string, env []string) (*Invocation, error)
+}
+
+// Invocation is a prepared local command for a backend
+type Invocation struct {
+	Binary string
+	Args   []string
+	Stdin  bool // The command forwards its standard input to the agent
+
+	// Cleanup releases backend resources once the command has exited.
+	// It may be nil.
+	Cleanup func()
+}
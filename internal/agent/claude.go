@@ -1,23 +1,22 @@
 package agent
 
 import (
-	"bufio"
 	"context"
-	"fmt"
 	"io"
-	"os/exec"
-	"strings"
-	"sync"
 )
 
 // ClaudeAgent implements the Agent interface for claude CLI
 type ClaudeAgent struct {
+	processTracker
+	structuredOutput
 	opts Options
 }
 
 // NewClaudeAgent creates a new claude agent
 func NewClaudeAgent(opts Options) *ClaudeAgent {
-	return &ClaudeAgent{opts: opts}
+	a := &ClaudeAgent{opts: opts}
+	a.textOnly = !opts.Capabilities.Supports(FeatureStreamJSON)
+	return a
 }
 
 // Name returns the agent's name
@@ -25,110 +24,43 @@ func (a *ClaudeAgent) Name() string {
 	return "claude"
 }
 
-// Run executes claude with the given prompt
+// Run executes claude with the given prompt, decoding its stream-json
+// output unless the CLI doesn't support it
 func (a *ClaudeAgent) Run(ctx context.Context, prompt string, output io.Writer) (string, error) {
-	// Build command args
+	session := resumeSession(ctx)
+	return a.runStructured(ctx, &a.processTracker, a.opts, "claude", "--output-format",
+		a.args(prompt, session, true), a.args(prompt, session, false), &claudeStream{}, output)
+}
+
+// ResumesSessions reports that claude can continue a session (--resume)
+func (a *ClaudeAgent) ResumesSessions() bool {
+	return true
+}
+
+// args builds the command line, with or without stream-json output,
+// resuming session if it is set
+func (a *ClaudeAgent) args(prompt string, session string, streamJSON bool) []string {
 	// --dangerously-skip-permissions bypasses all permission prompts
 	args := []string{"-p", "--dangerously-skip-permissions"}
 
+	// stream-json needs --verbose in print mode
+	if streamJSON {
+		args = append(args, "--output-format", "stream-json", "--verbose")
+	}
+
 	// Add model flag if specified
 	if a.opts.Model != "" {
 		args = append(args, "--model", a.opts.Model)
 	}
 
-	args = append(args, prompt)
-	cmd := exec.CommandContext(ctx, "claude", args...)
-	cmd.Stdin = nil // Prevent hanging on user input prompts
-	cmd.Env = append(cmd.Environ(), "CI=true", "NONINTERACTIVE=1") // Signal non-interactive mode
-
-	// Create pipes for stdout and stderr
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
-
-	// Diagnostic: show that we're starting
-	if output != nil {
-		fmt.Fprintln(output, "[ralph-loop] Starting claude agent...")
-	}
-
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("failed to start claude: %w", err)
+	// Continue the previous attempt's conversation
+	if session != "" {
+		args = append(args, "--resume", session)
 	}
 
-	if output != nil {
-		fmt.Fprintf(output, "[ralph-loop] claude started (PID: %d)\n", cmd.Process.Pid)
-	}
-
-	// Collect output while streaming - with proper synchronization
-	var fullOutput strings.Builder
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	// Stream stdout
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		scanner := bufio.NewScanner(stdout)
-		scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer
-		for scanner.Scan() {
-			line := scanner.Text()
-			mu.Lock()
-			fullOutput.WriteString(line)
-			fullOutput.WriteString("\n")
-			mu.Unlock()
-			if output != nil {
-				fmt.Fprintln(output, line)
-			}
-		}
-	}()
+	// Pass-through arguments (--agent-args)
+	args = append(args, a.opts.ExtraArgs...)
 
-	// Stream stderr
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		scanner := bufio.NewScanner(stderr)
-		scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer
-		for scanner.Scan() {
-			line := scanner.Text()
-			mu.Lock()
-			fullOutput.WriteString(line)
-			fullOutput.WriteString("\n")
-			mu.Unlock()
-			if output != nil {
-				fmt.Fprintln(output, line)
-			}
-		}
-	}()
-
-	// Wait for goroutines to finish reading all output
-	wg.Wait()
-
-	// Wait for command to complete
-	if err := cmd.Wait(); err != nil {
-		// Check if it was cancelled
-		if ctx.Err() != nil {
-			if output != nil {
-				fmt.Fprintln(output, "[ralph-loop] claude cancelled")
-			}
-			return fullOutput.String(), ctx.Err()
-		}
-		// Non-zero exit is not necessarily an error for our purposes
-		// The output parsing will determine success/failure
-		if output != nil {
-			fmt.Fprintf(output, "[ralph-loop] claude exited with error: %v\n", err)
-		}
-	} else {
-		if output != nil {
-			fmt.Fprintln(output, "[ralph-loop] claude completed")
-		}
-	}
-
-	return fullOutput.String(), nil
+	args = append(args, prompt)
+	return args
 }
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// claudeEvent is one line of `claude --output-format stream-json`
+type claudeEvent struct {
+	Type      string         `json:"type"`
+	Subtype   string         `json:"subtype"`
+	SessionID string         `json:"session_id"`
+	Model     string         `json:"model"`
+	Message   *claudeMessage `json:"message"`
+
+	// Final "result" event
+	Result   string       `json:"result"`
+	IsError  bool         `json:"is_error"`
+	CostUSD  float64      `json:"total_cost_usd"`
+	NumTurns int          `json:"num_turns"`
+	Usage    *claudeUsage `json:"usage"`
+}
+
+type claudeMessage struct {
+	Content []claudeContent `json:"content"`
+}
+
+type claudeContent struct {
+	Type    string          `json:"type"` // text, tool_use, tool_result, thinking
+	Text    string          `json:"text"`
+	Name    string          `json:"name"`    // tool_use
+	Input   json.RawMessage `json:"input"`   // tool_use
+	Content json.RawMessage `json:"content"` // tool_result: a string or content blocks
+}
+
+type claudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// claudeStream decodes a stream-json run. Assistant text is shown and
+// collected for marker parsing; tool calls and results are only shown, in
+// summary, so markers quoted in tool output can't be mistaken for the
+// agent's own.
+type claudeStream struct {
+	events int    // JSON events seen
+	result *Usage // From the final result event
+}
+
+func (s *claudeStream) eventCount() int { return s.events }
+func (s *claudeStream) usage() *Usage   { return s.result }
+
+// decode implements streamDecoder
+func (s *claudeStream) decode(line string) (string, string) {
+	var event claudeEvent
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") || json.Unmarshal([]byte(line), &event) != nil {
+		return line, line // Not an event, e.g. a CLI error
+	}
+	s.events++
+
+	switch event.Type {
+	case "system":
+		if event.Subtype == "init" {
+			return fmt.Sprintf("[claude] session %s (model %s)", event.SessionID, event.Model), ""
+		}
+
+	case "assistant":
+		if event.Message == nil {
+			return "", ""
+		}
+		var display, text []string
+		for _, block := range event.Message.Content {
+			switch block.Type {
+			case "text":
+				display = append(display, block.Text)
+				text = append(text, block.Text)
+			case "tool_use":
+				display = append(display, fmt.Sprintf("-> %s %s", block.Name, summarize(toolInput(block.Input))))
+			}
+		}
+		return strings.Join(display, "\n"), strings.Join(text, "\n")
+
+	case "user":
+		if event.Message == nil {
+			return "", ""
+		}
+		var display []string
+		for _, block := range event.Message.Content {
+			if block.Type == "tool_result" {
+				display = append(display, "   "+summarize(toolResult(block.Content)))
+			}
+		}
+		return strings.Join(display, "\n"), ""
+
+	case "result":
+		s.result = &Usage{CostUSD: event.CostUSD, SessionID: event.SessionID}
+		if u := event.Usage; u != nil {
+			s.result.InputTokens = u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+			s.result.OutputTokens = u.OutputTokens
+		}
+		status := "finished"
+		if event.IsError {
+			status = "stopped: " + event.Subtype
+		}
+		// The result repeats the last assistant message; collect it so the
+		// marker is found even if that message was split across events
+		return fmt.Sprintf("[claude] %s after %d turn(s)", status, event.NumTurns), event.Result
+	}
+	return "", ""
+}
+
+// toolResult flattens a tool result's content to text
+func toolResult(raw json.RawMessage) string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return text
+	}
+	var blocks []claudeContent
+	if json.Unmarshal(raw, &blocks) == nil {
+		var parts []string
+		for _, block := range blocks {
+			parts = append(parts, block.Text)
+		}
+		return strings.Join(parts, " ")
+	}
+	return string(raw)
+}
@@ -1,18 +1,15 @@
 package agent
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
-	"strings"
-	"sync"
 )
 
 // CodexAgent implements the Agent interface for OpenAI Codex CLI
 type CodexAgent struct {
+	processTracker
 	opts Options
 }
 
@@ -41,99 +38,9 @@ func (a *CodexAgent) Run(ctx context.Context, prompt string, output io.Writer) (
 		args = append(args, "--model", a.opts.Model)
 	}
 
-	args = append(args, prompt)
-	cmd := exec.CommandContext(ctx, "codex", args...)
-	cmd.Stdin = nil // Prevent hanging on user input prompts
-	cmd.Env = append(cmd.Environ(), "CI=true", "NONINTERACTIVE=1") // Signal non-interactive mode
-
-	// Create pipes for stdout and stderr
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
-
-	// Diagnostic: show that we're starting
-	if output != nil {
-		fmt.Fprintln(output, "[ralph-loop] Starting codex agent...")
-	}
-
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("failed to start codex: %w", err)
-	}
+	// Pass-through arguments (--agent-args)
+	args = append(args, a.opts.ExtraArgs...)
 
-	if output != nil {
-		fmt.Fprintf(output, "[ralph-loop] codex started (PID: %d)\n", cmd.Process.Pid)
-	}
-
-	// Collect output while streaming - with proper synchronization
-	var fullOutput strings.Builder
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	// Stream stdout
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		scanner := bufio.NewScanner(stdout)
-		scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer
-		for scanner.Scan() {
-			line := scanner.Text()
-			mu.Lock()
-			fullOutput.WriteString(line)
-			fullOutput.WriteString("\n")
-			mu.Unlock()
-			if output != nil {
-				fmt.Fprintln(output, line)
-			}
-		}
-	}()
-
-	// Stream stderr
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		scanner := bufio.NewScanner(stderr)
-		scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer
-		for scanner.Scan() {
-			line := scanner.Text()
-			mu.Lock()
-			fullOutput.WriteString(line)
-			fullOutput.WriteString("\n")
-			mu.Unlock()
-			if output != nil {
-				fmt.Fprintln(output, line)
-			}
-		}
-	}()
-
-	// Wait for goroutines to finish reading all output
-	wg.Wait()
-
-	// Wait for command to complete
-	if err := cmd.Wait(); err != nil {
-		// Check if it was cancelled
-		if ctx.Err() != nil {
-			if output != nil {
-				fmt.Fprintln(output, "[ralph-loop] codex cancelled")
-			}
-			return fullOutput.String(), ctx.Err()
-		}
-		// Non-zero exit is not necessarily an error for our purposes
-		// The output parsing will determine success/failure
-		if output != nil {
-			fmt.Fprintf(output, "[ralph-loop] codex exited with error: %v\n", err)
-		}
-	} else {
-		if output != nil {
-			fmt.Fprintln(output, "[ralph-loop] codex completed")
-		}
-	}
-
-	return fullOutput.String(), nil
+	args = append(args, prompt)
+	return runCommand(ctx, &a.processTracker, a.opts, "codex", args, output)
 }
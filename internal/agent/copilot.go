@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"io"
+)
+
+// CopilotAgent implements the Agent interface for the GitHub Copilot CLI
+type CopilotAgent struct {
+	processTracker
+	opts Options
+}
+
+// NewCopilotAgent creates a new copilot agent
+func NewCopilotAgent(opts Options) *CopilotAgent {
+	return &CopilotAgent{opts: opts}
+}
+
+// Name returns the agent's name
+func (a *CopilotAgent) Name() string {
+	return "copilot"
+}
+
+// Run executes copilot with the given prompt
+func (a *CopilotAgent) Run(ctx context.Context, prompt string, output io.Writer) (string, error) {
+	// Build command args
+	// --allow-all-tools lets programmatic mode run tools without asking for approval
+	args := []string{"--allow-all-tools"}
+
+	// Add model flag if specified
+	if a.opts.Model != "" {
+		args = append(args, "--model", a.opts.Model)
+	}
+
+	// Pass-through arguments (--agent-args)
+	args = append(args, a.opts.ExtraArgs...)
+
+	// -p runs a single prompt in programmatic mode and exits
+	args = append(args, "-p", prompt)
+	return runCommand(ctx, &a.processTracker, a.opts, "copilot", args, output)
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// CustomAgent implements the Agent interface for any CLI described by a
+// command template, so new tools can be used without new Go code
+type CustomAgent struct {
+	processTracker
+	opts Options
+	name string
+	args []*template.Template
+}
+
+// customVars are the placeholders available to a command template
+type customVars struct {
+	Prompt  string
+	Model   string
+	WorkDir string
+}
+
+// NewCustomAgent creates a custom agent from opts.Command
+func NewCustomAgent(opts Options) (*CustomAgent, error) {
+	if strings.TrimSpace(opts.Command) == "" {
+		return nil, fmt.Errorf("custom agent requires a command template (custom_agent.command in the config file)")
+	}
+
+	words, err := splitCommand(opts.Command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid custom agent command: %w", err)
+	}
+
+	args := make([]*template.Template, len(words))
+	for i, word := range words {
+		tmpl, err := template.New(fmt.Sprintf("arg%d", i)).Option("missingkey=error").Parse(word)
+		if err != nil {
+			return nil, fmt.Errorf("invalid custom agent command: %w", err)
+		}
+		args[i] = tmpl
+	}
+
+	name := opts.CommandName
+	if name == "" {
+		name = "custom"
+	}
+	return &CustomAgent{opts: opts, name: name, args: args}, nil
+}
+
+// Name returns the agent's name
+func (a *CustomAgent) Name() string {
+	return a.name
+}
+
+// Run renders the command template and executes it
+func (a *CustomAgent) Run(ctx context.Context, prompt string, output io.Writer) (string, error) {
+	wd, _ := os.Getwd()
+	vars := customVars{Prompt: prompt, Model: a.opts.Model, WorkDir: wd}
+
+	var command []string
+	for _, tmpl := range a.args {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			return "", fmt.Errorf("failed to render custom agent command: %w", err)
+		}
+		// Drop empty arguments so optional flags can be left out with {{if}}
+		if buf.Len() > 0 {
+			command = append(command, buf.String())
+		}
+	}
+	if len(command) == 0 {
+		return "", fmt.Errorf("custom agent command rendered to nothing")
+	}
+
+	return runCommand(ctx, &a.processTracker, a.opts, command[0], command[1:], output)
+}
+
+// ParseArgs splits a pass-through argument string such as
+// "--max-turns 30 --allowedTools 'Bash Edit'" into words, honoring quotes
+func ParseArgs(s string) ([]string, error) {
+	return splitCommand(s)
+}
+
+// splitCommand splits a command line into words. Whitespace separates words
+// except inside single or double quotes and inside {{ }} template actions.
+func splitCommand(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	depth := 0
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case depth == 0 && quote == 0 && (r == '\'' || r == '"'):
+			quote = r
+			inWord = true
+		case depth == 0 && quote != 0 && r == quote:
+			quote = 0
+		case r == '{' && i+1 < len(runes) && runes[i+1] == '{':
+			depth++
+			word.WriteString("{{")
+			inWord = true
+			i++
+		case depth > 0 && r == '}' && i+1 < len(runes) && runes[i+1] == '}':
+			depth--
+			word.WriteString("}}")
+			i++
+		case depth == 0 && quote == 0 && (r == ' ' || r == '\t' || r == '\n'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unterminated {{ action")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
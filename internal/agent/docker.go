@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sandbox is a Backend with resources that live for a whole run, such as a
+// container image. The runner starts it before the first step and stops it
+// when the run ends, however it ends.
+type Sandbox interface {
+	Backend
+
+	// Start checks and prepares the sandbox before any agent runs
+	Start(ctx context.Context) error
+
+	// Stop removes anything the sandbox left behind, e.g. containers of
+	// invocations that were interrupted
+	Stop()
+}
+
+// DefaultSandboxImage is the image used by `--sandbox docker` when none is
+// given. Build it from sandbox/Dockerfile with `make sandbox-image`.
+const DefaultSandboxImage = "ralph-loop-sandbox"
+
+// sandboxLabel marks containers started by ralph-loop; its value
+// identifies the run
+const sandboxLabel = "dev.ralph-loop.sandbox"
+
+// DockerSandbox runs each agent invocation in a fresh Docker container with
+// the working directory mounted at the same path, so an agent running with
+// its permission prompts disabled can only modify the repository
+type DockerSandbox struct {
+	image string
+	dir   string // Host directory mounted into the container
+	id    string // Label value shared by this run's containers
+}
+
+// ParseSandbox parses a --sandbox value: "docker" or "docker:<image>".
+// An empty value means no sandbox.
+func ParseSandbox(value string) (*DockerSandbox, error) {
+	if value == "" || value == "none" {
+		return nil, nil
+	}
+	kind, image, _ := strings.Cut(value, ":")
+	if kind != "docker" {
+		return nil, fmt.Errorf("unknown sandbox: %s (valid: docker, docker:<image>)", value)
+	}
+	if image == "" {
+		image = DefaultSandboxImage
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return &DockerSandbox{
+		image: image,
+		dir:   dir,
+		id:    strconv.FormatInt(time.Now().UnixNano(), 36),
+	}, nil
+}
+
+// Name returns the backend's name
+func (s *DockerSandbox) Name() string {
+	return "docker"
+}
+
+// Image returns the container image agents run in
+func (s *DockerSandbox) Image() string {
+	return s.image
+}
+
+// Start checks that Docker is running and the image is available, pulling
+// it if needed, so the first step's timeout isn't spent downloading it
+func (s *DockerSandbox) Start(ctx context.Context) error {
+	if out, err := exec.CommandContext(ctx, "docker", "version", "--format", "{{.Server.Version}}").CombinedOutput(); err != nil {
+		return fmt.Errorf("docker is not available: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	if exec.CommandContext(ctx, "docker", "image", "inspect", s.image).Run() == nil {
+		return nil
+	}
+	fmt.Printf("Pulling sandbox image %s...\n", s.image)
+	if out, err := exec.CommandContext(ctx, "docker", "pull", s.image).CombinedOutput(); err != nil {
+		hint := ""
+		if s.image == DefaultSandboxImage {
+			hint = " (build it with `make sandbox-image`)"
+		}
+		return fmt.Errorf("sandbox image %s not found%s: %s", s.image, hint, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Stop force-removes containers of this run that are still around
+func (s *DockerSandbox) Stop() {
+	out, err := exec.Command("docker", "ps", "-aq", "--filter", "label="+sandboxLabel+"="+s.id).Output()
+	if err != nil {
+		return
+	}
+	if ids := strings.Fields(string(out)); len(ids) > 0 {
+		exec.Command("docker", append([]string{"rm", "-f"}, ids...)...).Run()
+	}
+}
+
+// Prepare returns a `docker run` command for one agent invocation. Input
+// is forwarded, and interrupts are proxied to the agent in the container.
+func (s *DockerSandbox) Prepare(ctx context.Context, binary string, args []string, env []string) (*Invocation, error) {
+	name := "ralph-loop-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	dockerArgs := []string{
+		"run", "--rm", "-i", "--init",
+		"--name", name,
+		"--label", sandboxLabel + "=" + s.id,
+		"-v", s.dir + ":" + s.dir,
+		"-w", s.dir,
+		"-e", "HOME=/tmp/home",
+	}
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 {
+		// Files the agent creates belong to the user, not root
+		dockerArgs = append(dockerArgs, "--user", fmt.Sprintf("%d:%d", uid, gid))
+	}
+	for _, name := range credentialEnvVars() {
+		if _, ok := os.LookupEnv(name); ok {
+			dockerArgs = append(dockerArgs, "-e", name) // Value is taken from our environment
+		}
+	}
+	for _, kv := range env {
+		dockerArgs = append(dockerArgs, "-e", kv)
+	}
+	dockerArgs = append(dockerArgs, s.image, binary)
+	dockerArgs = append(dockerArgs, args...)
+
+	return &Invocation{
+		Binary: "docker",
+		Args:   dockerArgs,
+		Stdin:  true,
+		Cleanup: func() {
+			// The container outlives a killed docker client; make sure it's gone
+			exec.Command("docker", "rm", "-f", name).Run()
+		},
+	}, nil
+}
+
+// credentialEnvVars lists the API key variables of all built-in agents
+func credentialEnvVars() []string {
+	var names []string
+	for _, req := range Requirements() {
+		for _, name := range req.EnvVars {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
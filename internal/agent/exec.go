@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLineSize bounds a single output line. Structured event streams put a
+// whole tool result on one line, so this is generous.
+const maxLineSize = 16 * 1024 * 1024
+
+// processWaitDelay bounds how long a cancelled agent's output is still read
+// once its process group is killed, in case a process that left the group
+// holds the output open
+const processWaitDelay = 5 * time.Second
+
+// commandInput is what an agent process receives besides its arguments
+type commandInput struct {
+	Stdin string   // Written to standard input; empty means no input
+	Env   []string // Extra environment variables, as KEY=value
+
+	// Decode converts structured stdout lines (e.g. JSON events); nil
+	// passes them through unchanged
+	Decode streamDecoder
+}
+
+// streamDecoder converts one line of a CLI's structured output. display is
+// shown to the user and collect is added to the output returned for marker
+// parsing; either may be empty to drop the line from that destination.
+type streamDecoder func(line string) (display string, collect string)
+
+// runCommand runs an agent CLI non-interactively, streaming stdout and stderr
+// to output while collecting them for parsing. The process is registered with
+// tracker so it can be interrupted gracefully while running. If opts has a
+// Backend, the invocation is handed to it and its local command is streamed.
+func runCommand(ctx context.Context, tracker *processTracker, opts Options, binary string, args []string, output io.Writer) (string, error) {
+	return runCommandInput(ctx, tracker, opts, binary, args, commandInput{}, output)
+}
+
+// nonInteractiveEnv signals non-interactive mode to agent CLIs. The
+// variables can be removed with Options.UnsetEnv.
+var nonInteractiveEnv = []string{"CI=true", "NONINTERACTIVE=1"}
+
+// agentEnv returns the variables set for an agent process, as KEY=value:
+// the non-interactive signals, then opts.Env, then extra. Later entries
+// win.
+func agentEnv(opts Options, extra []string) []string {
+	var env []string
+	for _, kv := range nonInteractiveEnv {
+		key, _, _ := strings.Cut(kv, "=")
+		if !slices.Contains(opts.UnsetEnv, key) {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, opts.Env...)
+	return append(env, extra...)
+}
+
+// envKey is the context key for per-invocation environment variables
+type envKey struct{}
+
+// WithEnv returns a context whose agent invocations also get the given
+// variables, as KEY=value. They are set after the agent's own Options.Env.
+func WithEnv(ctx context.Context, env ...string) context.Context {
+	if prev, ok := ctx.Value(envKey{}).([]string); ok {
+		env = append(slices.Clone(prev), env...)
+	}
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+// contextEnv returns the variables added with WithEnv
+func contextEnv(ctx context.Context) []string {
+	env, _ := ctx.Value(envKey{}).([]string)
+	return env
+}
+
+// withoutEnv removes the named variables from an environment list
+func withoutEnv(env []string, names []string) []string {
+	if len(names) == 0 {
+		return env
+	}
+	return slices.DeleteFunc(env, func(kv string) bool {
+		key, _, _ := strings.Cut(kv, "=")
+		return slices.Contains(names, key)
+	})
+}
+
+// runCommandInput is runCommand with standard input and extra environment
+func runCommandInput(ctx context.Context, tracker *processTracker, opts Options, binary string, args []string, input commandInput, output io.Writer) (string, error) {
+	input.Env = append(contextEnv(ctx), input.Env...)
+	command, commandArgs := binary, args
+	if opts.Backend != nil {
+		invocation, err := opts.Backend.Prepare(ctx, binary, args, agentEnv(opts, input.Env))
+		if err != nil {
+			return "", fmt.Errorf("%s backend: %w", opts.Backend.Name(), err)
+		}
+		if input.Stdin != "" && !invocation.Stdin {
+			if invocation.Cleanup != nil {
+				invocation.Cleanup()
+			}
+			return "", fmt.Errorf("%s backend cannot pass standard input to %s", opts.Backend.Name(), binary)
+		}
+		if invocation.Cleanup != nil {
+			defer invocation.Cleanup()
+		}
+		command, commandArgs = invocation.Binary, invocation.Args
+		if output != nil {
+			fmt.Fprintf(output, "[ralph-loop] Running %s on the %s backend\n", binary, opts.Backend.Name())
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, command, commandArgs...)
+	cmd.Stdin = nil // Prevent hanging on user input prompts
+	if input.Stdin != "" {
+		cmd.Stdin = strings.NewReader(input.Stdin) // Closed after the input, so reads can't hang
+	}
+	if opts.Backend == nil {
+		cmd.Env = append(withoutEnv(cmd.Environ(), opts.UnsetEnv), agentEnv(opts, input.Env)...)
+	}
+	if opts.PTY {
+		if opts.Backend != nil {
+			return "", fmt.Errorf("%s backend cannot run %s in a PTY", opts.Backend.Name(), binary)
+		}
+		return runPTY(ctx, tracker, cmd, binary, input, output)
+	}
+	detachProcessGroup(cmd) // Ctrl+C is handled by the runner
+
+	// Output is copied into pipes by exec, so Wait stops copying WaitDelay
+	// after a cancellation even if a stray process still holds the output
+	stdout, stdoutWriter := io.Pipe()
+	stderr, stderrWriter := io.Pipe()
+	cmd.Stdout, cmd.Stderr = stdoutWriter, stderrWriter
+
+	// Diagnostic: show that we're starting
+	if output != nil {
+		fmt.Fprintf(output, "[ralph-loop] Starting %s agent...\n", binary)
+	}
+
+	// Start the command
+	tracker.recordExit(nil)
+	startedAt := time.Now()
+	if err := cmd.Start(); err != nil {
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		return "", fmt.Errorf("failed to start %s: %w", binary, err)
+	}
+
+	if output != nil {
+		fmt.Fprintf(output, "[ralph-loop] %s started (PID: %d)\n", binary, cmd.Process.Pid)
+	}
+
+	// Track the process so it can be interrupted gracefully
+	tracker.track(cmd.Process)
+	defer tracker.track(nil)
+
+	// Stream stdout and stderr, collecting them for parsing
+	var collected lineCollector
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		streamLines(binary, "stdout", stdout, input.Decode, &collected, output)
+	}()
+	go func() {
+		defer wg.Done()
+		streamLines(binary, "stderr", stderr, nil, &collected, output)
+	}()
+
+	// Wait for the process and the copying of its output, then for the
+	// goroutines to finish reading it
+	err := waitCommand(ctx, tracker, cmd, binary, startedAt, output, func() {
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		wg.Wait()
+	})
+
+	return collected.String(), err
+}
+
+// lineCollector gathers the output returned for marker parsing from
+// concurrently read streams
+type lineCollector struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (c *lineCollector) add(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sb.WriteString(line)
+	c.sb.WriteString("\n")
+}
+
+func (c *lineCollector) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sb.String()
+}
+
+// streamLines reads lines from r until it ends, showing them on output and
+// adding them to collected. decode, if set, converts each line first.
+func streamLines(binary string, name string, r io.Reader, decode streamDecoder, collected *lineCollector, output io.Writer) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		display, collect := line, line
+		show, keep := true, true
+		if decode != nil {
+			display, collect = decode(line)
+			show, keep = display != "", collect != ""
+		}
+		if keep {
+			collected.add(collect)
+		}
+		if output != nil && show {
+			fmt.Fprintln(output, display)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if output != nil {
+			fmt.Fprintf(output, "[ralph-loop] warning: %s %s truncated: %v\n", binary, name, err)
+		}
+		// Keep reading so the agent isn't blocked writing the rest
+		io.Copy(io.Discard, r)
+	}
+}
+
+// waitCommand waits for a started command and records how it exited.
+// drain, if set, runs after the wait and finishes reading the output. It returns the context's error if the command was
+// cancelled; a non-zero exit is not an error, since the output decides
+// whether the step succeeded.
+func waitCommand(ctx context.Context, tracker *processTracker, cmd *exec.Cmd, binary string, startedAt time.Time, output io.Writer, drain func()) error {
+	err := cmd.Wait()
+	if drain != nil {
+		drain()
+	}
+	if ctx.Err() == nil && cmd.ProcessState != nil {
+		tracker.recordExit(&ExitStatus{
+			Code:     cmd.ProcessState.ExitCode(),
+			State:    cmd.ProcessState.String(),
+			Duration: time.Since(startedAt),
+		})
+	}
+	if err != nil {
+		// Check if it was cancelled
+		if ctx.Err() != nil {
+			if output != nil {
+				fmt.Fprintf(output, "[ralph-loop] %s cancelled\n", binary)
+			}
+			return ctx.Err()
+		}
+		// Non-zero exit is not necessarily an error for our purposes
+		// The output parsing will determine success/failure
+		if output != nil {
+			fmt.Fprintf(output, "[ralph-loop] %s exited with error: %v\n", binary, err)
+		}
+	} else {
+		if output != nil {
+			fmt.Fprintf(output, "[ralph-loop] %s completed\n", binary)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"io"
+)
+
+// GeminiAgent implements the Agent interface for Google's gemini CLI
+type GeminiAgent struct {
+	processTracker
+	opts Options
+}
+
+// NewGeminiAgent creates a new gemini agent
+func NewGeminiAgent(opts Options) *GeminiAgent {
+	return &GeminiAgent{opts: opts}
+}
+
+// Name returns the agent's name
+func (a *GeminiAgent) Name() string {
+	return "gemini"
+}
+
+// Run executes gemini with the given prompt
+func (a *GeminiAgent) Run(ctx context.Context, prompt string, output io.Writer) (string, error) {
+	// Build command args
+	// --yolo auto-approves all tool calls so the agent never waits for confirmation
+	args := []string{"--yolo"}
+
+	// Add model flag if specified
+	if a.opts.Model != "" {
+		args = append(args, "--model", a.opts.Model)
+	}
+
+	// Pass-through arguments (--agent-args)
+	args = append(args, a.opts.ExtraArgs...)
+
+	// -p runs a single prompt non-interactively
+	args = append(args, "-p", prompt)
+	return runCommand(ctx, &a.processTracker, a.opts, "gemini", args, output)
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"io"
+)
+
+// GooseAgent implements the Agent interface for Block's goose CLI
+type GooseAgent struct {
+	processTracker
+	opts Options
+}
+
+// NewGooseAgent creates a new goose agent
+func NewGooseAgent(opts Options) *GooseAgent {
+	return &GooseAgent{opts: opts}
+}
+
+// Name returns the agent's name
+func (a *GooseAgent) Name() string {
+	return "goose"
+}
+
+// Run executes goose with the given prompt
+func (a *GooseAgent) Run(ctx context.Context, prompt string, output io.Writer) (string, error) {
+	// Build command args
+	// "run" executes headless and exits when done; stdin is detached by runCommand
+	args := []string{"run"}
+
+	// Add model flag if specified
+	if a.opts.Model != "" {
+		args = append(args, "--model", a.opts.Model)
+	}
+
+	// Pass-through arguments (--agent-args)
+	args = append(args, a.opts.ExtraArgs...)
+
+	// -t passes the prompt text directly instead of an instructions file
+	args = append(args, "-t", prompt)
+	return runCommand(ctx, &a.processTracker, a.opts, "goose", args, output)
+}
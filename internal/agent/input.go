@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrNoInput is returned when answering an agent that can't read input: it
+// isn't running, or doesn't run in a PTY
+var ErrNoInput = errors.New("the agent doesn't read input (only agents run in a PTY do)")
+
+// Input passes answers to a running agent that asked a question. An agent
+// run in a PTY with an Input in its context reads its standard input from
+// the terminal; other agents' standard input stays closed.
+type Input struct {
+	mu       sync.Mutex
+	terminal io.Writer // Set while an agent reads from the terminal
+}
+
+// NewInput returns an Input with no agent attached
+func NewInput() *Input {
+	return &Input{}
+}
+
+// inputKey is the context key for the Input of agent invocations
+type inputKey struct{}
+
+// WithInput returns a context whose agent invocations in a PTY can be
+// answered through in
+func WithInput(ctx context.Context, in *Input) context.Context {
+	return context.WithValue(ctx, inputKey{}, in)
+}
+
+// contextInput returns the Input added with WithInput, or nil
+func contextInput(ctx context.Context) *Input {
+	in, _ := ctx.Value(inputKey{}).(*Input)
+	return in
+}
+
+// Answer types text and Enter into the agent's terminal
+func (in *Input) Answer(text string) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.terminal == nil {
+		return ErrNoInput
+	}
+	_, err := io.WriteString(in.terminal, text+"\r")
+	return err
+}
+
+// attach routes answers to an agent's terminal until the returned function
+// is called
+func (in *Input) attach(terminal io.Writer) func() {
+	in.mu.Lock()
+	in.terminal = terminal
+	in.mu.Unlock()
+	return func() {
+		in.mu.Lock()
+		in.terminal = nil
+		in.mu.Unlock()
+	}
+}
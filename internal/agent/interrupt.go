@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Interrupter is implemented by agents that support a graceful stop.
+// Unlike cancelling the Run context, which kills the process, Interrupt asks
+// the agent to finish its current action and exit on its own.
+type Interrupter interface {
+	// Interrupt asks the running agent process to stop gracefully.
+	// It returns an error if no process is running or it can't be signalled,
+	// in which case callers should fall back to context cancellation.
+	Interrupt() error
+}
+
+// ErrNotRunning is returned by Interrupt when no agent process is running
+var ErrNotRunning = errors.New("agent is not running")
+
+// processTracker records the running agent process so it can be interrupted,
+// and how the last one exited. Agents embed it to implement Interrupter and
+// ExitReporter.
+type processTracker struct {
+	mu      sync.Mutex
+	process *os.Process
+	exit    *ExitStatus
+}
+
+// ExitStatus describes how an agent process ended
+type ExitStatus struct {
+	Code     int           // Exit code; -1 when killed by a signal
+	State    string        // e.g. "exit status 2" or "signal: segmentation fault"
+	Duration time.Duration // Time from start to exit
+}
+
+// Signaled reports whether the process was killed by a signal, directly
+// or as reported by a wrapper shell (exit codes above 128)
+func (s *ExitStatus) Signaled() bool {
+	return s.Code == -1 || s.Code > 128
+}
+
+// ExitReporter is implemented by agents that record how their process
+// exited. LastExit returns the exit of the last Run, or nil if no process
+// exited (it failed to start or was cancelled).
+type ExitReporter interface {
+	LastExit() *ExitStatus
+}
+
+// recordExit records how the last process exited
+func (t *processTracker) recordExit(exit *ExitStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exit = exit
+}
+
+// LastExit returns how the last agent process exited
+func (t *processTracker) LastExit() *ExitStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.exit
+}
+
+// track records the started process; pass nil once it has exited
+func (t *processTracker) track(p *os.Process) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.process = p
+}
+
+// Interrupt sends an interrupt signal (the CLI's Ctrl+C handling) to the
+// running process and the processes it started, as a terminal Ctrl+C would
+func (t *processTracker) Interrupt() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.process == nil {
+		return ErrNotRunning
+	}
+	if err := signalGroup(t.process, os.Interrupt); err != nil {
+		return fmt.Errorf("failed to interrupt agent: %w", err)
+	}
+	return nil
+}
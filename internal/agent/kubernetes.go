@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KubernetesOptions configures the Kubernetes Job backend
+type KubernetesOptions struct {
+	Image     string // Container image with the agent CLI installed (required)
+	Namespace string // Namespace for the Job (default: kubectl's current namespace)
+	CPU       string // CPU request and limit, e.g. "2"
+	Memory    string // Memory request and limit, e.g. "4Gi"
+	PVC       string // PersistentVolumeClaim holding the repository, mounted at /workspace (required)
+	Secret    string // Secret whose keys are exposed as environment variables (API keys)
+}
+
+// workspaceDir is where the repository is mounted inside the Job
+const workspaceDir = "/workspace"
+
+// KubernetesBackend runs each agent invocation as a Kubernetes Job using kubectl,
+// streaming the pod's logs back as the agent output. The agent works on the
+// repository in a PVC, which must be the working directory ralph-loop sees
+// too, since that is where the changes are verified and committed.
+type KubernetesBackend struct {
+	opts KubernetesOptions
+}
+
+// NewKubernetesBackend creates a Kubernetes Job backend
+func NewKubernetesBackend(opts KubernetesOptions) (*KubernetesBackend, error) {
+	if opts.Image == "" {
+		return nil, fmt.Errorf("kubernetes backend requires an image (--k8s-image)")
+	}
+	if opts.PVC == "" {
+		return nil, fmt.Errorf("kubernetes backend requires a PVC with the repository (--k8s-pvc)")
+	}
+	return &KubernetesBackend{opts: opts}, nil
+}
+
+// Name returns the backend's name
+func (b *KubernetesBackend) Name() string {
+	return "kubernetes"
+}
+
+// Prepare creates the Job and returns a kubectl command that follows its logs
+func (b *KubernetesBackend) Prepare(ctx context.Context, binary string, args []string, env []string) (*Invocation, error) {
+	name := "ralph-loop-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	manifest, err := json.Marshal(b.jobManifest(name, binary, args, env))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job manifest: %w", err)
+	}
+
+	apply := exec.CommandContext(ctx, "kubectl", b.kubectlArgs("apply", "-f", "-")...)
+	apply.Stdin = bytes.NewReader(manifest)
+	if out, err := apply.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to create job %s: %v: %s", name, err, strings.TrimSpace(string(out)))
+	}
+
+	return &Invocation{
+		Binary: "kubectl",
+		Args:   b.kubectlArgs("logs", "-f", "job/"+name, "--pod-running-timeout=10m", "--all-containers"),
+		Cleanup: func() {
+			// Delete in the background so cancellation doesn't wait on the cluster
+			exec.Command("kubectl", b.kubectlArgs("delete", "job", name, "--wait=false", "--ignore-not-found")...).Run()
+		},
+	}, nil
+}
+
+// kubectlArgs prefixes args with the namespace flag when one is configured
+func (b *KubernetesBackend) kubectlArgs(args ...string) []string {
+	if b.opts.Namespace != "" {
+		return append([]string{"--namespace", b.opts.Namespace}, args...)
+	}
+	return args
+}
+
+// jobManifest builds the Job object for one agent invocation
+func (b *KubernetesBackend) jobManifest(name string, binary string, args []string, env []string) map[string]any {
+	var envVars []map[string]string
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		envVars = append(envVars, map[string]string{"name": key, "value": value})
+	}
+
+	container := map[string]any{
+		"name":         "agent",
+		"image":        b.opts.Image,
+		"command":      []string{binary},
+		"args":         args,
+		"workingDir":   workspaceDir,
+		"env":          envVars,
+		"volumeMounts": []map[string]string{{"name": "workspace", "mountPath": workspaceDir}},
+	}
+	if b.opts.Secret != "" {
+		container["envFrom"] = []map[string]any{{"secretRef": map[string]string{"name": b.opts.Secret}}}
+	}
+	if resources := b.resources(); resources != nil {
+		container["resources"] = map[string]any{"requests": resources, "limits": resources}
+	}
+
+	podSpec := map[string]any{
+		"restartPolicy": "Never",
+		"containers":    []any{container},
+		"volumes": []any{map[string]any{
+			"name":                  "workspace",
+			"persistentVolumeClaim": map[string]string{"claimName": b.opts.PVC},
+		}},
+	}
+
+	return map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata": map[string]any{
+			"name":   name,
+			"labels": map[string]string{"app.kubernetes.io/managed-by": "ralph-loop"},
+		},
+		"spec": map[string]any{
+			"backoffLimit":            0,
+			"ttlSecondsAfterFinished": 3600,
+			"template":                map[string]any{"spec": podSpec},
+		},
+	}
+}
+
+// resources returns the configured CPU/memory quantities, or nil if none
+func (b *KubernetesBackend) resources() map[string]string {
+	resources := make(map[string]string)
+	if b.opts.CPU != "" {
+		resources["cpu"] = b.opts.CPU
+	}
+	if b.opts.Memory != "" {
+		resources["memory"] = b.opts.Memory
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+	return resources
+}
@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// opencodeEvent is one line of `opencode run --format json`
+type opencodeEvent struct {
+	Type      string         `json:"type"` // text, tool_use, step_start, step_finish, error
+	SessionID string         `json:"sessionID"`
+	Part      *opencodePart  `json:"part"`
+	Error     *opencodeError `json:"error"`
+}
+
+type opencodePart struct {
+	Type  string             `json:"type"`
+	Text  string             `json:"text"`  // text parts
+	Tool  string             `json:"tool"`  // tool parts
+	State *opencodeToolState `json:"state"` // tool parts
+
+	// step-finish parts
+	Cost   float64         `json:"cost"`
+	Tokens *opencodeTokens `json:"tokens"`
+}
+
+type opencodeToolState struct {
+	Status string          `json:"status"`
+	Input  json.RawMessage `json:"input"`
+	Output string          `json:"output"`
+	Error  string          `json:"error"`
+}
+
+type opencodeTokens struct {
+	Input     int `json:"input"`
+	Output    int `json:"output"`
+	Reasoning int `json:"reasoning"`
+	Cache     struct {
+		Read  int `json:"read"`
+		Write int `json:"write"`
+	} `json:"cache"`
+}
+
+type opencodeError struct {
+	Name string `json:"name"`
+	Data struct {
+		Message string `json:"message"`
+	} `json:"data"`
+}
+
+// opencodeStream decodes a `--format json` run into readable output.
+// Assistant text is shown and collected for marker parsing; tool calls are
+// shown as one-line summaries. Usage is summed over the run's steps.
+type opencodeStream struct {
+	events int
+	total  *Usage
+}
+
+func (s *opencodeStream) eventCount() int { return s.events }
+func (s *opencodeStream) usage() *Usage   { return s.total }
+
+// decode implements streamDecoder
+func (s *opencodeStream) decode(line string) (string, string) {
+	var event opencodeEvent
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") || json.Unmarshal([]byte(line), &event) != nil {
+		return line, line // Not an event, e.g. a CLI error
+	}
+	s.events++
+
+	switch event.Type {
+	case "text":
+		if event.Part != nil {
+			return event.Part.Text, event.Part.Text
+		}
+
+	case "tool_use":
+		if event.Part == nil || event.Part.State == nil {
+			return "", ""
+		}
+		state := event.Part.State
+		display := fmt.Sprintf("-> %s %s", event.Part.Tool, summarize(toolInput(state.Input)))
+		switch {
+		case state.Error != "":
+			display += "\n   error: " + summarize(state.Error)
+		case state.Output != "":
+			display += "\n   " + summarize(state.Output)
+		}
+		return display, ""
+
+	case "step_finish":
+		if event.Part == nil {
+			return "", ""
+		}
+		if s.total == nil {
+			s.total = &Usage{SessionID: event.SessionID}
+		}
+		s.total.CostUSD += event.Part.Cost
+		if t := event.Part.Tokens; t != nil {
+			s.total.InputTokens += t.Input + t.Cache.Read + t.Cache.Write
+			s.total.OutputTokens += t.Output + t.Reasoning
+		}
+
+	case "error":
+		message := "unknown error"
+		if event.Error != nil {
+			message = strings.TrimSpace(event.Error.Name + ": " + event.Error.Data.Message)
+		}
+		return "[opencode] error: " + message, "opencode error: " + message
+	}
+	return "", ""
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PluginPrefix starts the name of an executable that provides an agent:
+// ralph-agent-<name> on PATH can be used as `--agent <name>`
+const PluginPrefix = "ralph-agent-"
+
+// PluginAgent implements the Agent interface for an external executable.
+// The prompt is written to its standard input and its standard output and
+// error are read like any other agent's.
+type PluginAgent struct {
+	processTracker
+	opts Options
+	name string
+}
+
+// NewPluginAgent creates an agent for the ralph-agent-<name> plugin
+func NewPluginAgent(name string, opts Options) *PluginAgent {
+	return &PluginAgent{opts: opts, name: name}
+}
+
+// Name returns the agent's name
+func (a *PluginAgent) Name() string {
+	return a.name
+}
+
+// Run executes the plugin with the prompt on standard input. The model and
+// working directory are passed as RALPH_MODEL and RALPH_WORKDIR, and
+// --agent-args as arguments.
+func (a *PluginAgent) Run(ctx context.Context, prompt string, output io.Writer) (string, error) {
+	wd, _ := os.Getwd()
+	input := commandInput{
+		Stdin: prompt,
+		Env:   []string{"RALPH_MODEL=" + a.opts.Model, "RALPH_WORKDIR=" + wd},
+	}
+	return runCommandInput(ctx, &a.processTracker, a.opts, PluginPrefix+a.name, a.opts.ExtraArgs, input, output)
+}
+
+// LookupPlugin returns the path of the ralph-agent-<name> executable on
+// PATH, or "" if there is none
+func LookupPlugin(name string) string {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return ""
+	}
+	path, err := exec.LookPath(PluginPrefix + name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// Plugins lists the names of the agent plugins found on PATH
+func Plugins() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+			// Windows executables carry an extension
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			if !ok || name == "" || seen[name] || LookupPlugin(name) == "" {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// detachProcessGroup starts the agent in its own process group so a terminal
+// Ctrl+C reaches ralph-loop only, which then decides how to stop the agent
+func detachProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	killGroupOnCancel(cmd)
+}
+
+// killGroupOnCancel makes cancelling the command's context kill the agent's
+// whole process group, so the tools it started, such as test runners and dev
+// servers, don't outlive it. The agent must lead its group.
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return signalGroup(cmd.Process, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = processWaitDelay
+}
+
+// signalGroup sends sig to the process group led by p
+func signalGroup(p *os.Process, sig os.Signal) error {
+	return syscall.Kill(-p.Pid, sig.(syscall.Signal))
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package agent
+
+import (
+	"os"
+	"os/exec"
+)
+
+// detachProcessGroup only bounds the wait for output on Windows, where
+// console Ctrl+C events are delivered differently and there are no process
+// groups to kill
+func detachProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = processWaitDelay
+}
+
+// signalGroup signals the process itself; interrupts aren't supported on
+// Windows, so callers fall back to cancelling
+func signalGroup(p *os.Process, sig os.Signal) error {
+	return p.Signal(sig)
+}
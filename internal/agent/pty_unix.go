@@ -0,0 +1,109 @@
+//go:build !windows
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// ptySize is the terminal size reported to agents in a PTY; wide enough
+// that CLIs don't wrap the markers
+var ptySize = &pty.Winsize{Rows: 50, Cols: 200}
+
+// terminalEscape matches ANSI escape sequences (colors, cursor movement,
+// titles) that TTY-aware CLIs emit
+var terminalEscape = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// runPTY runs a prepared command with its standard output and error
+// attached to a pseudo-terminal. They arrive merged; lines are shown as they
+// are and collected without terminal escapes, so markers can be parsed.
+// Standard input stays as prepared (a pipe or nothing), so the terminal
+// never echoes the prompt back into the output, unless the context has an
+// Input and no prompt is written to standard input: then it's the terminal,
+// so the agent's questions can be answered.
+func runPTY(ctx context.Context, tracker *processTracker, cmd *exec.Cmd, binary string, input commandInput, output io.Writer) (string, error) {
+	terminal, tty, err := pty.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open a PTY for %s: %w", binary, err)
+	}
+	defer terminal.Close()
+	if err := pty.Setsize(terminal, ptySize); err != nil {
+		tty.Close()
+		return "", fmt.Errorf("failed to size the PTY for %s: %w", binary, err)
+	}
+
+	// The agent gets its own session with the terminal as its controlling
+	// TTY (on its stdout, fd 1), so a terminal Ctrl+C reaches ralph-loop only
+	cmd.Stdout, cmd.Stderr = tty, tty
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 1}
+	killGroupOnCancel(cmd) // A new session is also a new process group
+	answers := contextInput(ctx)
+	if answers != nil && input.Stdin == "" {
+		cmd.Stdin = tty
+	} else {
+		answers = nil
+	}
+	if os.Getenv("TERM") == "" {
+		cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+	}
+
+	if output != nil {
+		fmt.Fprintf(output, "[ralph-loop] Starting %s agent in a PTY...\n", binary)
+	}
+
+	tracker.recordExit(nil)
+	startedAt := time.Now()
+	err = cmd.Start()
+	tty.Close() // The agent holds its own copy
+	if err != nil {
+		return "", fmt.Errorf("failed to start %s in a PTY: %w", binary, err)
+	}
+
+	if output != nil {
+		fmt.Fprintf(output, "[ralph-loop] %s started (PID: %d)\n", binary, cmd.Process.Pid)
+	}
+
+	tracker.track(cmd.Process)
+	defer tracker.track(nil)
+	if answers != nil {
+		defer answers.attach(terminal)()
+	}
+
+	decode := func(line string) (string, string) {
+		line = strings.TrimRight(line, "\r")
+		plain := terminalEscape.ReplaceAllString(line, "")
+		if input.Decode != nil {
+			return input.Decode(plain)
+		}
+		return line, plain
+	}
+	var collected lineCollector
+	streamLines(binary, "terminal", ptyReader{terminal}, decode, &collected, output)
+
+	return collected.String(), waitCommand(ctx, tracker, cmd, binary, startedAt, output, nil)
+}
+
+// ptyReader reads a PTY's controlling side. Once the agent exits, Linux
+// reports EIO rather than end-of-file.
+type ptyReader struct {
+	r io.Reader
+}
+
+func (p ptyReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if errors.Is(err, syscall.EIO) {
+		err = io.EOF
+	}
+	return n, err
+}
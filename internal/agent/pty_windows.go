@@ -0,0 +1,15 @@
+//go:build windows
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// runPTY is unavailable on Windows
+func runPTY(ctx context.Context, tracker *processTracker, cmd *exec.Cmd, binary string, input commandInput, output io.Writer) (string, error) {
+	return "", fmt.Errorf("cannot run %s in a PTY: PTY mode is not supported on Windows", binary)
+}
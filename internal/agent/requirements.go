@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Requirement describes what an agent CLI needs to run: its binary and how
+// it authenticates. It is used by `ralph-loop doctor`.
+type Requirement struct {
+	Type    AgentType
+	Binary  string
+	Install string // How to install the CLI
+
+	// Credentials: any one of these environment variables or files
+	// (relative to the home directory) counts as authenticated
+	EnvVars         []string
+	CredentialFiles []string
+	Login           string // How to authenticate when nothing is found
+	EnvRequired     bool   // The agent refuses to run without one of EnvVars
+}
+
+// Requirements lists the requirements of the built-in agents
+func Requirements() []Requirement {
+	return []Requirement{
+		{
+			Type: AgentTypeClaude, Binary: "claude", Install: "npm install -g @anthropic-ai/claude-code",
+			EnvVars:         []string{"ANTHROPIC_API_KEY", "CLAUDE_CODE_OAUTH_TOKEN"},
+			CredentialFiles: []string{".claude/.credentials.json", ".claude.json"},
+			Login:           "run `claude` and log in, or set ANTHROPIC_API_KEY",
+		},
+		{
+			Type: AgentTypeOpencode, Binary: "opencode", Install: "go install github.com/opencode-ai/opencode@latest",
+			EnvVars:         []string{"ANTHROPIC_API_KEY", "OPENAI_API_KEY", "GEMINI_API_KEY"},
+			CredentialFiles: []string{".local/share/opencode/auth.json"},
+			Login:           "run `opencode auth login` or set a provider API key",
+		},
+		{
+			Type: AgentTypeCodex, Binary: "codex", Install: "npm install -g @openai/codex",
+			EnvVars:     []string{"OPENAI_API_KEY"},
+			Login:       "export OPENAI_API_KEY=<your key>",
+			EnvRequired: true,
+		},
+		{
+			Type: AgentTypeGemini, Binary: "gemini", Install: "npm install -g @google/gemini-cli",
+			EnvVars:         []string{"GEMINI_API_KEY", "GOOGLE_API_KEY", "GOOGLE_CLOUD_PROJECT"},
+			CredentialFiles: []string{".gemini/oauth_creds.json"},
+			Login:           "run `gemini` and log in, or set GEMINI_API_KEY",
+		},
+		{
+			Type: AgentTypeGoose, Binary: "goose", Install: "see https://github.com/block/goose",
+			CredentialFiles: []string{".config/goose/config.yaml"},
+			Login:           "run `goose configure`",
+		},
+		{
+			Type: AgentTypeCopilot, Binary: "copilot", Install: "npm install -g @github/copilot",
+			EnvVars:         []string{"GH_TOKEN", "GITHUB_TOKEN", "COPILOT_GITHUB_TOKEN"},
+			CredentialFiles: []string{".copilot/config.json"},
+			Login:           "run `copilot` and use /login",
+		},
+		{
+			Type: AgentTypeAmazonQ, Binary: "q", Install: "see https://github.com/aws/amazon-q-developer-cli",
+			CredentialFiles: []string{".local/share/amazon-q/data.sqlite3", "Library/Application Support/amazon-q/data.sqlite3"},
+			Login:           "run `q login`",
+		},
+	}
+}
+
+// Credentials returns the first credential source found for the agent:
+// an environment variable name or a credential file path. It returns ""
+// if none is found.
+func (r Requirement) Credentials() string {
+	for _, name := range r.EnvVars {
+		if os.Getenv(name) != "" {
+			return name
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, file := range r.CredentialFiles {
+		path := filepath.Join(home, file)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package agent
+
+import "context"
+
+// SessionResumer is implemented by agents whose CLI can continue an
+// earlier session, keeping its conversation and investigation context
+// (see WithResume). Session IDs are reported in Usage.SessionID.
+type SessionResumer interface {
+	ResumesSessions() bool
+}
+
+// resumeKey is the context key for the session to resume
+type resumeKey struct{}
+
+// WithResume returns a context whose agent invocations continue the given
+// session instead of starting a new one, for agents that support it
+func WithResume(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, resumeKey{}, sessionID)
+}
+
+// resumeSession returns the session set with WithResume, or ""
+func resumeSession(ctx context.Context) string {
+	id, _ := ctx.Value(resumeKey{}).(string)
+	return id
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// eventDecoder decodes a CLI's structured (JSON lines) output mode
+type eventDecoder interface {
+	decode(line string) (display string, collect string) // A streamDecoder
+	eventCount() int                                     // Structured events seen
+	usage() *Usage                                       // Usage reported by the stream, if any
+}
+
+// structuredOutput is embedded by agents that run their CLI in a structured
+// output mode. It falls back to plain text when the CLI turns out not to
+// support the mode, and records the usage the stream reports.
+type structuredOutput struct {
+	mu        sync.Mutex
+	textOnly  bool
+	lastUsage *Usage
+}
+
+// LastUsage returns the usage reported by the last run, if any
+func (s *structuredOutput) LastUsage() *Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastUsage
+}
+
+// runStructured runs structuredArgs, decoding the output with dec. flag is
+// the option that selects the mode: if the CLI rejects it before emitting
+// any event, the run is retried with textArgs, as are all later runs.
+func (s *structuredOutput) runStructured(ctx context.Context, tracker *processTracker, opts Options, binary string, flag string,
+	structuredArgs []string, textArgs []string, dec eventDecoder, output io.Writer) (string, error) {
+	s.mu.Lock()
+	textOnly := s.textOnly
+	s.lastUsage = nil
+	s.mu.Unlock()
+
+	if textOnly {
+		return runCommand(ctx, tracker, opts, binary, textArgs, output)
+	}
+
+	out, err := runCommandInput(ctx, tracker, opts, binary, structuredArgs, commandInput{Decode: dec.decode}, output)
+	if err == nil && dec.eventCount() == 0 && strings.Contains(out, flag) {
+		if output != nil {
+			fmt.Fprintf(output, "[ralph-loop] %s does not support %s; retrying with plain text output\n", binary, flag)
+		}
+		s.mu.Lock()
+		s.textOnly = true
+		s.mu.Unlock()
+		return runCommand(ctx, tracker, opts, binary, textArgs, output)
+	}
+
+	s.mu.Lock()
+	s.lastUsage = dec.usage()
+	s.mu.Unlock()
+	return out, err
+}
+
+// toolInput picks the most telling field of a tool call's input
+func toolInput(raw json.RawMessage) string {
+	var input map[string]any
+	if json.Unmarshal(raw, &input) != nil {
+		return string(raw)
+	}
+	for _, key := range []string{"command", "file_path", "path", "pattern", "url", "description"} {
+		if v, ok := input[key].(string); ok {
+			return v
+		}
+	}
+	return string(raw)
+}
+
+// summarize shortens s to one line of at most toolSummaryLength runes
+func summarize(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > toolSummaryLength {
+		return string(runes[:toolSummaryLength]) + "..."
+	}
+	return s
+}
+
+// toolSummaryLength bounds tool inputs and results shown from a stream
+const toolSummaryLength = 120
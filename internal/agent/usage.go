@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Usage is what an agent run consumed, as reported by the CLI
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	CostUSD      float64 // 0 when the CLI doesn't report cost
+	SessionID    string
+}
+
+// UsageReporter is implemented by agents whose CLI reports usage. It
+// returns the usage of the last Run, or nil if none was reported.
+type UsageReporter interface {
+	LastUsage() *Usage
+}
+
+// String summarizes the usage for display, e.g.
+// "12.3k input / 4.5k output tokens, $0.12"
+func (u *Usage) String() string {
+	parts := []string{fmt.Sprintf("%s input / %s output tokens", formatTokens(u.InputTokens), formatTokens(u.OutputTokens))}
+	if u.CostUSD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f", u.CostUSD))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatTokens(n int) string {
+	if n >= 1000 {
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprintf("%d", n)
+}
@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versionProbeTimeout bounds `<cli> --version`
+const versionProbeTimeout = 10 * time.Second
+
+var versionRegex = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// Version is a CLI's major.minor.patch version
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion finds the first version number in s, e.g. in
+// "1.0.35 (Claude Code)"
+func ParseVersion(s string) (Version, bool) {
+	m := versionRegex.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, false
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, true
+}
+
+// Less reports whether v is older than o
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// ProbeVersion returns the first line of `<binary> --version`, or "" if
+// the CLI is missing or the command fails
+func ProbeVersion(binary string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, binary, "--version").Output()
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return line
+}
+
+// Feature is an optional invocation mode of an agent CLI
+type Feature string
+
+const (
+	// FeatureStreamJSON is claude's `--output-format stream-json`
+	FeatureStreamJSON Feature = "stream-json"
+
+	// FeatureJSONEvents is opencode's `run --format json`
+	FeatureJSONEvents Feature = "json-events"
+)
+
+// featureRange is the range of CLI versions known to support a feature
+type featureRange struct {
+	Agent    AgentType
+	Feature  Feature
+	Min      Version // Oldest known-good version
+	Fallback string  // What the agent does without the feature
+}
+
+var featureRanges = []featureRange{
+	{AgentTypeClaude, FeatureStreamJSON, Version{1, 0, 0}, "plain text output"},
+	{AgentTypeOpencode, FeatureJSONEvents, Version{0, 15, 0}, "plain text output"},
+}
+
+// minVersion is the oldest CLI version known to accept flags an agent
+// can't run without, such as the one that disables permission prompts.
+// There is no fallback: an older CLI fails every step.
+type minVersion struct {
+	Agent AgentType
+	Min   Version
+	Flags string
+}
+
+var minVersions = []minVersion{
+	{AgentTypeClaude, Version{0, 2, 0}, "-p --dangerously-skip-permissions"},
+	{AgentTypeGoose, Version{1, 0, 0}, "run -t"},
+	{AgentTypeCopilot, Version{0, 0, 330}, "-p --allow-all-tools"},
+	{AgentTypeAmazonQ, Version{1, 9, 0}, "chat --no-interactive --trust-all-tools"},
+}
+
+// IncompatibleError reports a CLI that is older than the oldest version
+// known to accept the flags its agent is run with
+type IncompatibleError struct {
+	Agent   AgentType
+	Version Version
+	Min     Version
+	Flags   string
+	Install string // How to upgrade, if known
+}
+
+func (e *IncompatibleError) Error() string {
+	msg := fmt.Sprintf("%s %s is too old: ralph-loop runs it with %s, which needs %s or newer",
+		e.Agent, e.Version, e.Flags, e.Min)
+	if e.Install != "" {
+		msg += fmt.Sprintf(" (upgrade: %s)", e.Install)
+	}
+	return msg
+}
+
+// Capabilities records which optional features the installed CLI supports.
+// A nil *Capabilities supports everything: the version was not checked.
+type Capabilities struct {
+	Version     string // Output of --version, if known
+	unsupported map[Feature]bool
+}
+
+// Supports reports whether the CLI can use f
+func (c *Capabilities) Supports(f Feature) bool {
+	return c == nil || !c.unsupported[f]
+}
+
+// DetectCapabilities probes the installed CLI of a built-in agent and
+// checks its version (see CapabilitiesFor and CheckMinVersion). The error
+// is an *IncompatibleError.
+func DetectCapabilities(agentType AgentType) (*Capabilities, []string, error) {
+	if !hasFeatureRanges(agentType) && !hasMinVersion(agentType) {
+		return nil, nil, nil
+	}
+	for _, req := range Requirements() {
+		if req.Type == agentType {
+			rawVersion := ProbeVersion(req.Binary)
+			caps, notes := CapabilitiesFor(agentType, rawVersion)
+			return caps, notes, CheckMinVersion(agentType, rawVersion)
+		}
+	}
+	return nil, nil, nil
+}
+
+// CheckMinVersion checks a CLI version string against the oldest version
+// known to accept the flags the agent is run with. If the version can't be
+// parsed, the CLI is assumed to be compatible.
+func CheckMinVersion(agentType AgentType, rawVersion string) error {
+	version, ok := ParseVersion(rawVersion)
+	if !ok {
+		return nil
+	}
+	for _, mv := range minVersions {
+		if mv.Agent != agentType || !version.Less(mv.Min) {
+			continue
+		}
+		err := &IncompatibleError{Agent: agentType, Version: version, Min: mv.Min, Flags: mv.Flags}
+		for _, req := range Requirements() {
+			if req.Type == agentType {
+				err.Install = req.Install
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// CapabilitiesFor checks a CLI version string against the known-good range
+// of each of the agent's features. It returns a note for every feature that
+// falls back to a compatible mode. If the version can't be parsed, all
+// features are assumed to work.
+func CapabilitiesFor(agentType AgentType, rawVersion string) (*Capabilities, []string) {
+	version, ok := ParseVersion(rawVersion)
+	if !ok || !hasFeatureRanges(agentType) {
+		return nil, nil
+	}
+
+	caps := &Capabilities{Version: rawVersion, unsupported: make(map[Feature]bool)}
+	var notes []string
+	for _, fr := range featureRanges {
+		if fr.Agent == agentType && version.Less(fr.Min) {
+			caps.unsupported[fr.Feature] = true
+			notes = append(notes, fmt.Sprintf("%s %s is older than %s, which %s needs; using %s instead",
+				agentType, version, fr.Min, fr.Feature, fr.Fallback))
+		}
+	}
+	return caps, notes
+}
+
+func hasFeatureRanges(agentType AgentType) bool {
+	for _, fr := range featureRanges {
+		if fr.Agent == agentType {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMinVersion(agentType AgentType) bool {
+	for _, mv := range minVersions {
+		if mv.Agent == agentType {
+			return true
+		}
+	}
+	return false
+}
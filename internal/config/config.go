@@ -0,0 +1,244 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+)
+
+// Config holds project settings that don't fit on the command line.
+// It is read from .ralph-loop/config.json next to the plan file.
+type Config struct {
+	// CustomAgent defines the command run by `--agent custom`
+	CustomAgent *CustomAgent `json:"custom_agent,omitempty"`
+
+	// Verify is the shell command that must pass before a step counts as
+	// complete. When empty, a command is chosen from the project type.
+	Verify string `json:"verify,omitempty"`
+
+	// Order is the step ordering strategy, e.g. "priority-first". --order
+	// overrides it.
+	Order string `json:"order,omitempty"`
+
+	// Export publishes progress to external tools (see `ralph-loop export`)
+	Export *Export `json:"export,omitempty"`
+
+	// Artifacts are uploaded after each successful step
+	Artifacts *Artifacts `json:"artifacts,omitempty"`
+
+	// AgentArgs are extra CLI arguments per agent name, e.g.
+	// {"claude": "--max-turns 30"}. --agent-args overrides the entry for
+	// the run's agent.
+	AgentArgs map[string]string `json:"agent_args,omitempty"`
+
+	// Env adjusts the environment of agent processes
+	Env *Env `json:"env,omitempty"`
+
+	// PTY lists agents to run attached to a pseudo-terminal, e.g.
+	// ["goose"]. --pty adds the run's agent.
+	PTY []string `json:"pty,omitempty"`
+
+	// Logs chooses where transcripts and failure bundles are stored
+	Logs *Logs `json:"logs,omitempty"`
+
+	// Glossary is a file of project-specific terms and acronyms, relative
+	// to the working directory, included in every prompt along with the
+	// plan's ## Glossary section
+	Glossary string `json:"glossary,omitempty"`
+
+	// Stall sets the tiers of the response to an agent that goes silent
+	Stall *Stall `json:"stall,omitempty"`
+
+	// ContextProviders add extra context to every prompt, in the order
+	// listed, e.g. [{"name": "git-diff", "max_tokens": 2000}]
+	ContextProviders []ContextProvider `json:"context_providers,omitempty"`
+
+	// Denylist adds commands that stop an attempt when they show in the
+	// agent's output
+	Denylist *Denylist `json:"denylist,omitempty"`
+
+	// Hooks are shell commands run around each step's attempts
+	Hooks *Hooks `json:"hooks,omitempty"`
+
+	// Budget caps each run, e.g. for unattended overnight runs
+	Budget *Budget `json:"budget,omitempty"`
+
+	// Notify tells the operator about progress, e.g. while the terminal is
+	// in the background
+	Notify *Notify `json:"notify,omitempty"`
+}
+
+// Notify chooses the notifications sent during a run
+type Notify struct {
+	Desktop bool         `json:"desktop,omitempty"` // Native desktop notifications on step results and agent questions
+	Email   *EmailNotify `json:"email,omitempty"`   // Email a run summary when the loop exits
+	Webhook *Webhook     `json:"webhook,omitempty"` // Post run and step events as JSON
+}
+
+// EmailNotify sends the run summary over SMTP. The login is read from
+// SMTP_USERNAME and SMTP_PASSWORD, so it stays out of the config file.
+type EmailNotify struct {
+	To   []string `json:"to"`             // Recipients
+	From string   `json:"from"`           // Sender address
+	Host string   `json:"host"`           // SMTP server
+	Port int      `json:"port,omitempty"` // Default 587 (STARTTLS); 465 is TLS from the start
+}
+
+// Webhook posts run events to an HTTP endpoint. Events are signed with
+// RALPH_LOOP_WEBHOOK_SECRET when it is set.
+type Webhook struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"` // e.g. {"Authorization": "Bearer $CHAT_TOKEN"}; $VAR is expanded
+	Events  []string          `json:"events,omitempty"`  // Events to send (default all)
+	Retries int               `json:"retries,omitempty"` // Retries of a failed delivery (default 3)
+}
+
+// Budget limits a whole run. The run stops gracefully once a limit is
+// reached; zero or empty fields are no limit, and flags override them.
+type Budget struct {
+	MaxDuration   string  `json:"max_duration,omitempty"`   // Wall-clock time, e.g. "4h"
+	MaxCost       float64 `json:"max_cost,omitempty"`       // US dollars, summed over the costs the agents report
+	MaxIterations int     `json:"max_iterations,omitempty"` // Agent attempts
+}
+
+// Hooks are shell commands run around steps, with the step's details in
+// RALPH_* environment variables. A failing pre_step hook fails the attempt;
+// the others only warn.
+type Hooks struct {
+	PreStep    string `json:"pre_step,omitempty"`    // Before each attempt, e.g. to snapshot a database
+	PostStep   string `json:"post_step,omitempty"`   // After each attempt, whatever its outcome
+	OnFailure  string `json:"on_failure,omitempty"`  // After an attempt fails
+	OnComplete string `json:"on_complete,omitempty"` // After a step completes, e.g. to tag the build
+	Timeout    string `json:"timeout,omitempty"`     // Limit for each hook command (default 5m)
+}
+
+// Denylist lists regular expressions matched against each line of agent
+// output. They extend the built-in list of destructive commands unless
+// ReplaceDefaults is set; an empty list with ReplaceDefaults disables the
+// check.
+type Denylist struct {
+	Patterns        []string `json:"patterns"`                   // e.g. ["terraform destroy", "kubectl delete (ns|namespace)"]
+	ReplaceDefaults bool     `json:"replace_defaults,omitempty"` // Use only Patterns
+}
+
+// ContextProvider enables a built-in context provider: git-diff,
+// file-hints, instructions or test-output
+type ContextProvider struct {
+	Name      string `json:"name"`
+	MaxTokens int    `json:"max_tokens,omitempty"` // Approximate cap on the provider's output (default: no limit)
+}
+
+// Stall configures how long an agent may be silent before each tier of
+// the stall response. Durations are Go durations like "45s" or "5m"; "off"
+// disables a tier and an empty value keeps its default.
+type Stall struct {
+	Warn          string `json:"warn,omitempty"`           // Show a warning (default 30s)
+	Notify        string `json:"notify,omitempty"`         // Run NotifyCommand (default 2m)
+	NotifyCommand string `json:"notify_command,omitempty"` // Shell command, e.g. a curl to a chat webhook
+	Answer        string `json:"answer,omitempty"`         // Answer an agent in a PTY that asked a question (default 5m)
+	AnswerText    string `json:"answer_text,omitempty"`    // Reply typed at the answer tier (default "y")
+	Kill          string `json:"kill,omitempty"`           // Stop the attempt so it's retried (default 5m)
+}
+
+// ParseStallDuration parses a stall tier's duration: "" yields fallback,
+// "off" yields 0 (tier disabled)
+func ParseStallDuration(value string, fallback time.Duration) (time.Duration, error) {
+	switch value {
+	case "":
+		return fallback, nil
+	case "off":
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q (e.g. 45s, 5m, or off)", value)
+	}
+	return d, nil
+}
+
+// Logs configures the store for transcripts and failure bundles, so they
+// outlive ephemeral CI runners and containers
+type Logs struct {
+	Destination string `json:"destination"` // s3://bucket/prefix, gs://bucket/prefix or a local directory
+}
+
+// Env declares environment variables to set for, or strip from, agent
+// processes. Values may reference ralph-loop's own environment as $VAR or
+// ${VAR}, so secrets need not be written to the file.
+type Env struct {
+	Set   map[string]string `json:"set,omitempty"`   // e.g. {"HTTPS_PROXY": "http://proxy:3128"}
+	Unset []string          `json:"unset,omitempty"` // e.g. ["CI"] to drop the non-interactive signal
+}
+
+// Artifacts declares files to keep from each successful step
+type Artifacts struct {
+	Paths       []string `json:"paths"`       // Glob patterns, relative to the working directory
+	Destination string   `json:"destination"` // s3://bucket/prefix, gs://bucket/prefix or a local directory
+}
+
+// Export configures where plan progress is published. API credentials
+// come from the environment, never from the config file.
+type Export struct {
+	OnRunEnd   bool              `json:"on_run_end,omitempty"` // Export when `run` finishes
+	Notion     *NotionExport     `json:"notion,omitempty"`
+	Confluence *ConfluenceExport `json:"confluence,omitempty"`
+}
+
+// NotionExport identifies the Notion database that holds the plan page
+type NotionExport struct {
+	DatabaseID    string `json:"database_id"`
+	TitleProperty string `json:"title_property,omitempty"` // default "Name"
+}
+
+// ConfluenceExport identifies the Confluence page replaced on each export
+type ConfluenceExport struct {
+	BaseURL string `json:"base_url"` // e.g. https://acme.atlassian.net/wiki
+	PageID  string `json:"page_id"`
+}
+
+// CustomAgent describes an arbitrary agent CLI as a command template.
+// Command is split into arguments like a shell would (quotes group words)
+// and each argument is rendered with text/template, so a placeholder is
+// always passed as a single argument:
+//
+//	mytool run --prompt {{.Prompt}} {{if .Model}}--model={{.Model}}{{end}}
+//
+// Available placeholders are {{.Prompt}}, {{.Model}} and {{.WorkDir}}.
+// Arguments that render to an empty string are dropped.
+type CustomAgent struct {
+	Name    string `json:"name,omitempty"` // Display name (default "custom")
+	Command string `json:"command"`
+}
+
+// Path returns the default config location for a plan
+func Path(planPath string) string {
+	return layout.ForPlan(planPath).Path(layout.ConfigFile)
+}
+
+// Load reads a config file. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if problems := Check(content); len(problems) > 0 {
+		more := ""
+		if len(problems) > 1 {
+			more = fmt.Sprintf(" (and %d more; run 'ralph-loop config check')", len(problems)-1)
+		}
+		return nil, fmt.Errorf("invalid config %s:%s%s", path, problems[0], more)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,455 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/loop"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Problem is an error in a config file, with its location
+type Problem struct {
+	Line    int
+	Column  int
+	Path    string // Dotted key path, e.g. export.notion.database_id
+	Message string
+}
+
+// String formats the problem as "line:column: path: message"
+func (p Problem) String() string {
+	location := fmt.Sprintf("%d:%d", p.Line, p.Column)
+	if p.Path == "" {
+		return fmt.Sprintf("%s: %s", location, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, p.Path, p.Message)
+}
+
+// Check validates config file content against the Config schema: JSON
+// syntax, unknown keys, value types and required fields. Problems are
+// returned in file order.
+func Check(content []byte) []Problem {
+	c := &checker{
+		content:   content,
+		dec:       json.NewDecoder(bytes.NewReader(content)),
+		positions: make(map[string]int64),
+	}
+	c.dec.UseNumber()
+
+	if !c.value(reflect.TypeOf(Config{}), "") {
+		return c.problems
+	}
+	if _, err := c.dec.Token(); err != io.EOF {
+		c.add(c.dec.InputOffset(), "", "unexpected content after the top-level object")
+		return c.problems
+	}
+
+	if len(c.problems) == 0 {
+		var cfg Config
+		if err := json.Unmarshal(content, &cfg); err == nil {
+			c.checkRequired(&cfg)
+		}
+	}
+
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		if c.problems[i].Line != c.problems[j].Line {
+			return c.problems[i].Line < c.problems[j].Line
+		}
+		return c.problems[i].Column < c.problems[j].Column
+	})
+	return c.problems
+}
+
+// checkRequired reports fields that must be set when their section is present
+func (c *checker) checkRequired(cfg *Config) {
+	if cfg.CustomAgent != nil && strings.TrimSpace(cfg.CustomAgent.Command) == "" {
+		c.missing("custom_agent", "command")
+	}
+	if cfg.Artifacts != nil {
+		if len(cfg.Artifacts.Paths) == 0 {
+			c.missing("artifacts", "paths")
+		}
+		if cfg.Artifacts.Destination == "" {
+			c.missing("artifacts", "destination")
+		}
+	}
+	if cfg.Order != "" {
+		if _, err := plan.ParseOrderStrategy(cfg.Order); err != nil {
+			c.addAt("order", fmt.Sprintf("unknown step order (valid: %s)", strings.Join(plan.OrderStrategyNames(), ", ")))
+		}
+	}
+	if cfg.Logs != nil && cfg.Logs.Destination == "" {
+		c.missing("logs", "destination")
+	}
+	if cfg.Env != nil {
+		for name := range cfg.Env.Set {
+			if !validEnvName(name) {
+				c.addAt(joinPath("env.set", name), "invalid environment variable name")
+			}
+		}
+		for i, name := range cfg.Env.Unset {
+			if !validEnvName(name) {
+				c.addAt(fmt.Sprintf("env.unset[%d]", i), "invalid environment variable name")
+			}
+		}
+	}
+	if cfg.Stall != nil {
+		for key, value := range map[string]string{"warn": cfg.Stall.Warn, "notify": cfg.Stall.Notify, "answer": cfg.Stall.Answer, "kill": cfg.Stall.Kill} {
+			if _, err := ParseStallDuration(value, 0); err != nil {
+				c.addAt("stall."+key, err.Error())
+			}
+		}
+		if strings.ContainsAny(cfg.Stall.AnswerText, "\r\n") {
+			c.addAt("stall.answer_text", "must be a single line")
+		}
+	}
+	if cfg.Notify != nil && cfg.Notify.Email != nil {
+		email := cfg.Notify.Email
+		if len(email.To) == 0 {
+			c.missing("notify.email", "to")
+		}
+		if email.From == "" {
+			c.missing("notify.email", "from")
+		}
+		if email.Host == "" {
+			c.missing("notify.email", "host")
+		}
+		if email.Port < 0 || email.Port > 65535 {
+			c.addAt("notify.email.port", "must be between 1 and 65535, or 0 for the default (587)")
+		}
+	}
+	if cfg.Notify != nil && cfg.Notify.Webhook != nil {
+		webhook := cfg.Notify.Webhook
+		if webhook.URL == "" {
+			c.missing("notify.webhook", "url")
+		} else if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			c.addAt("notify.webhook.url", "must be an http or https URL")
+		}
+		for i, event := range webhook.Events {
+			if !slices.Contains(loop.WebhookEvents, event) {
+				c.addAt(fmt.Sprintf("notify.webhook.events[%d]", i), fmt.Sprintf("unknown event (valid: %s)", strings.Join(loop.WebhookEvents, ", ")))
+			}
+		}
+		if webhook.Retries < 0 {
+			c.addAt("notify.webhook.retries", "must not be negative")
+		}
+	}
+	for i, provider := range cfg.ContextProviders {
+		path := fmt.Sprintf("context_providers[%d]", i)
+		if provider.Name == "" {
+			c.missing(path, "name")
+		} else if !slices.Contains(loop.ContextProviderNames(), provider.Name) {
+			c.addAt(path+".name", fmt.Sprintf("unknown context provider (valid: %s)", strings.Join(loop.ContextProviderNames(), ", ")))
+		}
+		if provider.MaxTokens < 0 {
+			c.addAt(path+".max_tokens", "must not be negative")
+		}
+	}
+	if cfg.Hooks != nil && cfg.Hooks.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Hooks.Timeout); err != nil || d <= 0 {
+			c.addAt("hooks.timeout", fmt.Sprintf("invalid duration %q (e.g. 90s or 10m)", cfg.Hooks.Timeout))
+		}
+	}
+	if cfg.Budget != nil {
+		if cfg.Budget.MaxDuration != "" {
+			if d, err := time.ParseDuration(cfg.Budget.MaxDuration); err != nil || d <= 0 {
+				c.addAt("budget.max_duration", fmt.Sprintf("invalid duration %q (e.g. 4h or 90m)", cfg.Budget.MaxDuration))
+			}
+		}
+		if cfg.Budget.MaxCost < 0 {
+			c.addAt("budget.max_cost", "must not be negative")
+		}
+		if cfg.Budget.MaxIterations < 0 {
+			c.addAt("budget.max_iterations", "must not be negative")
+		}
+	}
+	if cfg.Denylist != nil {
+		for i, pattern := range cfg.Denylist.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				c.addAt(fmt.Sprintf("denylist.patterns[%d]", i), "invalid regular expression: "+err.Error())
+			}
+		}
+	}
+	for i, name := range cfg.PTY {
+		if _, err := agent.ParseAgentType(name); err != nil {
+			c.addAt(fmt.Sprintf("pty[%d]", i), err.Error())
+		}
+	}
+	for name := range cfg.AgentArgs {
+		if _, err := agent.ParseAgentType(name); err != nil {
+			c.addAt(joinPath("agent_args", name), err.Error())
+		}
+	}
+	if cfg.Export != nil {
+		if n := cfg.Export.Notion; n != nil && n.DatabaseID == "" {
+			c.missing("export.notion", "database_id")
+		}
+		if conf := cfg.Export.Confluence; conf != nil {
+			if conf.BaseURL == "" {
+				c.missing("export.confluence", "base_url")
+			} else if !strings.HasPrefix(conf.BaseURL, "http://") && !strings.HasPrefix(conf.BaseURL, "https://") {
+				c.addAt("export.confluence.base_url", "must be an http(s) URL")
+			}
+			if conf.PageID == "" {
+				c.missing("export.confluence", "page_id")
+			}
+		}
+	}
+}
+
+// checker walks JSON tokens alongside the Config type
+type checker struct {
+	content   []byte
+	dec       *json.Decoder
+	problems  []Problem
+	positions map[string]int64 // Start offset of each key path's value
+}
+
+// value checks the next JSON value against t. It returns false if the
+// input is not valid JSON, after which checking stops.
+func (c *checker) value(t reflect.Type, path string) bool {
+	start := c.dec.InputOffset()
+	c.positions[path] = start
+
+	tok, err := c.dec.Token()
+	if err != nil {
+		c.syntaxError(err)
+		return false
+	}
+	if tok == nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		return true
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if tok != json.Delim('{') {
+			return c.mismatch(start, path, "an object", tok)
+		}
+		for c.dec.More() {
+			keyStart := c.dec.InputOffset()
+			keyTok, err := c.dec.Token()
+			if err != nil {
+				c.syntaxError(err)
+				return false
+			}
+			key, _ := keyTok.(string)
+			field, ok := fieldByTag(t, key)
+			if !ok {
+				c.add(keyStart, joinPath(path, key), fmt.Sprintf("unknown key (valid keys: %s)", strings.Join(tagNames(t), ", ")))
+				if !c.skip() {
+					return false
+				}
+				continue
+			}
+			if !c.value(field.Type, joinPath(path, key)) {
+				return false
+			}
+		}
+		_, err := c.dec.Token() // '}'
+		if err != nil {
+			c.syntaxError(err)
+			return false
+		}
+
+	case reflect.Map:
+		if tok != json.Delim('{') {
+			return c.mismatch(start, path, "an object", tok)
+		}
+		for c.dec.More() {
+			keyTok, err := c.dec.Token()
+			if err != nil {
+				c.syntaxError(err)
+				return false
+			}
+			key, _ := keyTok.(string)
+			if !c.value(t.Elem(), joinPath(path, key)) {
+				return false
+			}
+		}
+		if _, err := c.dec.Token(); err != nil { // '}'
+			c.syntaxError(err)
+			return false
+		}
+
+	case reflect.Slice:
+		if tok != json.Delim('[') {
+			return c.mismatch(start, path, "an array", tok)
+		}
+		for i := 0; c.dec.More(); i++ {
+			if !c.value(t.Elem(), fmt.Sprintf("%s[%d]", path, i)) {
+				return false
+			}
+		}
+		if _, err := c.dec.Token(); err != nil { // ']'
+			c.syntaxError(err)
+			return false
+		}
+
+	case reflect.String:
+		if _, ok := tok.(string); !ok {
+			return c.mismatch(start, path, "a string", tok)
+		}
+
+	case reflect.Bool:
+		if _, ok := tok.(bool); !ok {
+			return c.mismatch(start, path, "true or false", tok)
+		}
+
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		if _, ok := tok.(json.Number); !ok {
+			return c.mismatch(start, path, "a number", tok)
+		}
+	}
+	return true
+}
+
+// mismatch records a type error and skips the rest of the offending value
+func (c *checker) mismatch(offset int64, path string, want string, tok json.Token) bool {
+	c.add(offset, path, fmt.Sprintf("expected %s, found %s", want, describeToken(tok)))
+	if tok == json.Delim('{') || tok == json.Delim('[') {
+		return c.skipRest()
+	}
+	return true
+}
+
+// skip consumes the next value
+func (c *checker) skip() bool {
+	tok, err := c.dec.Token()
+	if err != nil {
+		c.syntaxError(err)
+		return false
+	}
+	if tok == json.Delim('{') || tok == json.Delim('[') {
+		return c.skipRest()
+	}
+	return true
+}
+
+// skipRest consumes tokens up to the end of an object or array that has
+// just been opened
+func (c *checker) skipRest() bool {
+	for depth := 1; depth > 0; {
+		tok, err := c.dec.Token()
+		if err != nil {
+			c.syntaxError(err)
+			return false
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return true
+}
+
+func (c *checker) syntaxError(err error) {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		c.add(syntax.Offset, "", "invalid JSON: "+syntax.Error())
+		return
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		c.add(int64(len(c.content)), "", "unexpected end of file")
+		return
+	}
+	c.add(c.dec.InputOffset(), "", "invalid JSON: "+err.Error())
+}
+
+// missing reports a required key absent from the object at path
+func (c *checker) missing(path string, key string) {
+	c.addAt(path, fmt.Sprintf("%q is required", key))
+}
+
+// addAt records a problem at the recorded position of path
+func (c *checker) addAt(path string, message string) {
+	c.add(c.positions[path], path, message)
+}
+
+func (c *checker) add(offset int64, path string, message string) {
+	line, column := c.lineColumn(offset)
+	c.problems = append(c.problems, Problem{Line: line, Column: column, Path: path, Message: message})
+}
+
+// lineColumn converts a byte offset to a 1-based line and column, skipping
+// the separators the decoder reports offsets before
+func (c *checker) lineColumn(offset int64) (int, int) {
+	for offset < int64(len(c.content)) && strings.IndexByte(" \t\r\n,:", c.content[offset]) >= 0 {
+		offset++
+	}
+	if offset > int64(len(c.content)) {
+		offset = int64(len(c.content))
+	}
+	before := c.content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// fieldByTag finds the struct field with the given JSON key
+func fieldByTag(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// tagNames lists a struct's JSON keys
+func tagNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// validEnvName reports whether name can be an environment variable
+func validEnvName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "= \t\n\x00")
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describeToken(tok json.Token) string {
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			return "an object"
+		}
+		return "an array"
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	case bool:
+		return fmt.Sprintf("%v", v)
+	case json.Number:
+		return "the number " + v.String()
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%v", tok)
+}
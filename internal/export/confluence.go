@@ -0,0 +1,141 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ConfluenceOptions configures the Confluence exporter
+type ConfluenceOptions struct {
+	BaseURL  string // e.g. https://acme.atlassian.net/wiki
+	PageID   string // Existing page whose body is replaced on each export
+	User     string // Account email (CONFLUENCE_USER)
+	APIToken string // API token (CONFLUENCE_API_TOKEN)
+}
+
+// ConfluenceExporter replaces the body of an existing Confluence page with
+// the plan's progress
+type ConfluenceExporter struct {
+	opts   ConfluenceOptions
+	client *http.Client
+}
+
+// NewConfluenceExporter creates a Confluence exporter
+func NewConfluenceExporter(opts ConfluenceOptions) (*ConfluenceExporter, error) {
+	if opts.BaseURL == "" || opts.PageID == "" {
+		return nil, fmt.Errorf("confluence export requires base_url and page_id")
+	}
+	if opts.User == "" || opts.APIToken == "" {
+		return nil, fmt.Errorf("confluence export requires CONFLUENCE_USER and CONFLUENCE_API_TOKEN")
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	return &ConfluenceExporter{opts: opts, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Name returns the exporter's name
+func (c *ConfluenceExporter) Name() string {
+	return "confluence"
+}
+
+// Export replaces the page body, keeping its title
+func (c *ConfluenceExporter) Export(ctx context.Context, report *Report) error {
+	var page struct {
+		Title   string `json:"title"`
+		Version struct {
+			Number int `json:"number"`
+		} `json:"version"`
+	}
+	path := "/rest/api/content/" + c.opts.PageID
+	if err := c.do(ctx, http.MethodGet, path+"?expand=version", nil, &page); err != nil {
+		return err
+	}
+
+	update := map[string]any{
+		"id":      c.opts.PageID,
+		"type":    "page",
+		"title":   page.Title,
+		"version": map[string]any{"number": page.Version.Number + 1, "message": "Updated by ralph-loop"},
+		"body": map[string]any{"storage": map[string]any{
+			"value":          confluenceBody(report),
+			"representation": "storage",
+		}},
+	}
+	return c.do(ctx, http.MethodPut, path, update, nil)
+}
+
+// do sends a Confluence REST request and decodes the response into out (if non-nil)
+func (c *ConfluenceExporter) do(ctx context.Context, method string, path string, body any, out any) error {
+	var payload io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.opts.BaseURL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.opts.User, c.opts.APIToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(respBody))
+	}
+	if out != nil {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
+
+// confluenceBody renders the report in Confluence storage format (XHTML)
+func confluenceBody(report *Report) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<h1>%s</h1>", html.EscapeString(report.Title()))
+	fmt.Fprintf(&sb, "<p>%s</p>", html.EscapeString(report.SummaryLine()))
+	if line := report.RunLine(); line != "" {
+		fmt.Fprintf(&sb, "<p>%s</p>", html.EscapeString(line))
+	}
+
+	if report.Plan.Context != "" {
+		sb.WriteString("<h2>Context</h2>")
+		for _, paragraph := range strings.Split(report.Plan.Context, "\n\n") {
+			fmt.Fprintf(&sb, "<p>%s</p>", strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br/>"))
+		}
+	}
+
+	sb.WriteString("<h2>Steps</h2><table><tbody>")
+	sb.WriteString("<tr><th>Step</th><th>Description</th><th>Status</th><th>Retries</th><th>Notes</th><th>Artifacts</th></tr>")
+	for _, step := range report.Plan.Steps {
+		var artifacts []string
+		for _, url := range step.Artifacts {
+			artifacts = append(artifacts, html.EscapeString(url))
+		}
+		fmt.Fprintf(&sb, "<tr><td>%d</td><td>%s</td><td>%s</td><td>%d</td><td>%s</td><td>%s</td></tr>",
+			step.Number, html.EscapeString(step.Description), step.Status, step.RetryCount, html.EscapeString(step.Notes),
+			strings.Join(artifacts, "<br/>"))
+	}
+	sb.WriteString("</tbody></table>")
+	return sb.String()
+}
@@ -0,0 +1,106 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Exporter publishes a plan's progress somewhere stakeholders can read it
+type Exporter interface {
+	// Name identifies the destination in messages
+	Name() string
+
+	// Export publishes the report, replacing any previous export of the same plan
+	Export(ctx context.Context, report *Report) error
+}
+
+// Report is the content published by exporters
+type Report struct {
+	Plan        *plan.Plan
+	GeneratedAt time.Time
+
+	// Run describes the run that just ended; nil for standalone exports
+	Run *RunSummary
+
+	// Costs is the agent-reported cost of each step, by step number, over
+	// the recorded runs; nil when not known
+	Costs map[int]float64
+}
+
+// RunSummary describes a finished run
+type RunSummary struct {
+	Agent      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Error      string   // Empty if the run ended normally
+	Warnings   []string // Non-fatal warnings raised during the run, e.g. "Step 2 [stall]: ..."
+}
+
+// Counts tallies the plan's steps by status
+type Counts struct {
+	Completed, Failed, Skipped, Pending int
+}
+
+// NewReport builds a report for p
+func NewReport(p *plan.Plan, run *RunSummary) *Report {
+	return &Report{Plan: p, GeneratedAt: time.Now(), Run: run}
+}
+
+// Counts tallies the report's steps by status
+func (r *Report) Counts() Counts {
+	var c Counts
+	for _, step := range r.Plan.Steps {
+		switch step.Status {
+		case plan.StatusCompleted:
+			c.Completed++
+		case plan.StatusFailed:
+			c.Failed++
+		case plan.StatusSkipped:
+			c.Skipped++
+		default:
+			c.Pending++
+		}
+	}
+	return c
+}
+
+// Title is the page title used by exporters
+func (r *Report) Title() string {
+	if r.Plan.ProjectName != "" {
+		return r.Plan.ProjectName
+	}
+	return "ralph-loop plan"
+}
+
+// SummaryLine describes overall progress in one sentence
+func (r *Report) SummaryLine() string {
+	c := r.Counts()
+	return fmt.Sprintf("%d of %d steps completed (%d failed, %d skipped, %d pending). Updated %s.",
+		c.Completed, len(r.Plan.Steps), c.Failed, c.Skipped, c.Pending, r.GeneratedAt.Format("2006-01-02 15:04:05"))
+}
+
+// RunLine describes the run that produced the report, or "" for none
+func (r *Report) RunLine() string {
+	if r.Run == nil {
+		return ""
+	}
+	line := fmt.Sprintf("Last run: %s agent, %s to %s (%v).", r.Run.Agent,
+		r.Run.StartedAt.Format("2006-01-02 15:04:05"), r.Run.FinishedAt.Format("15:04:05"),
+		r.Run.FinishedAt.Sub(r.Run.StartedAt).Round(time.Second))
+	if r.Run.Error != "" {
+		line += " Ended with error: " + r.Run.Error
+	}
+	return line
+}
+
+// stepLine describes one step in one line
+func stepLine(step plan.Step) string {
+	line := fmt.Sprintf("Step %d: %s [%s]", step.Number, step.Description, step.Status)
+	if step.RetryCount > 0 {
+		line += fmt.Sprintf(" (retries: %d)", step.RetryCount)
+	}
+	return line
+}
@@ -0,0 +1,181 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+const (
+	notionAPI        = "https://api.notion.com/v1"
+	notionVersion    = "2022-06-28"
+	notionMaxBlocks  = 100  // Children per create/append request
+	notionMaxTextLen = 2000 // Characters per rich text object
+)
+
+// NotionOptions configures the Notion exporter
+type NotionOptions struct {
+	Token         string // Integration token (NOTION_TOKEN)
+	DatabaseID    string // Database the plan page lives in
+	TitleProperty string // Name of the database's title property (default "Name")
+}
+
+// NotionExporter publishes the plan as a page in a Notion database. Each
+// export archives the previous page for the plan and creates a fresh one.
+type NotionExporter struct {
+	opts   NotionOptions
+	client *http.Client
+}
+
+// NewNotionExporter creates a Notion exporter
+func NewNotionExporter(opts NotionOptions) (*NotionExporter, error) {
+	if opts.Token == "" {
+		return nil, fmt.Errorf("notion export requires NOTION_TOKEN")
+	}
+	if opts.DatabaseID == "" {
+		return nil, fmt.Errorf("notion export requires a database_id")
+	}
+	if opts.TitleProperty == "" {
+		opts.TitleProperty = "Name"
+	}
+	return &NotionExporter{opts: opts, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Name returns the exporter's name
+func (n *NotionExporter) Name() string {
+	return "notion"
+}
+
+// Export replaces the plan's page in the database
+func (n *NotionExporter) Export(ctx context.Context, report *Report) error {
+	// Archive earlier exports of this plan
+	var query struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+	}
+	filter := map[string]any{"filter": map[string]any{
+		"property": n.opts.TitleProperty,
+		"title":    map[string]any{"equals": report.Title()},
+	}}
+	if err := n.do(ctx, http.MethodPost, "/databases/"+n.opts.DatabaseID+"/query", filter, &query); err != nil {
+		return err
+	}
+	for _, page := range query.Results {
+		if err := n.do(ctx, http.MethodPatch, "/pages/"+page.ID, map[string]any{"archived": true}, nil); err != nil {
+			return err
+		}
+	}
+
+	blocks := notionBlocks(report)
+	first := blocks
+	if len(first) > notionMaxBlocks {
+		first = first[:notionMaxBlocks]
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	page := map[string]any{
+		"parent": map[string]any{"database_id": n.opts.DatabaseID},
+		"properties": map[string]any{
+			n.opts.TitleProperty: map[string]any{"title": notionText(report.Title())},
+		},
+		"children": first,
+	}
+	if err := n.do(ctx, http.MethodPost, "/pages", page, &created); err != nil {
+		return err
+	}
+
+	// Blocks beyond the first request are appended in batches
+	for start := notionMaxBlocks; start < len(blocks); start += notionMaxBlocks {
+		end := min(start+notionMaxBlocks, len(blocks))
+		body := map[string]any{"children": blocks[start:end]}
+		if err := n.do(ctx, http.MethodPatch, "/blocks/"+created.ID+"/children", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends a Notion API request and decodes the response into out (if non-nil)
+func (n *NotionExporter) do(ctx context.Context, method string, path string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, notionAPI+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+n.opts.Token)
+	req.Header.Set("Notion-Version", notionVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(respBody))
+	}
+	if out != nil {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
+
+// notionBlocks renders the report as Notion blocks
+func notionBlocks(report *Report) []any {
+	blocks := []any{notionBlock("paragraph", map[string]any{"rich_text": notionText(report.SummaryLine())})}
+	if line := report.RunLine(); line != "" {
+		blocks = append(blocks, notionBlock("paragraph", map[string]any{"rich_text": notionText(line)}))
+	}
+
+	if report.Plan.Context != "" {
+		blocks = append(blocks,
+			notionBlock("heading_2", map[string]any{"rich_text": notionText("Context")}),
+			notionBlock("paragraph", map[string]any{"rich_text": notionText(report.Plan.Context)}))
+	}
+
+	blocks = append(blocks, notionBlock("heading_2", map[string]any{"rich_text": notionText("Steps")}))
+	for _, step := range report.Plan.Steps {
+		text := stepLine(step)
+		if step.Notes != "" {
+			text += " - " + step.Notes
+		}
+		if len(step.Artifacts) > 0 {
+			text += " (artifacts: " + strings.Join(step.Artifacts, ", ") + ")"
+		}
+		blocks = append(blocks, notionBlock("to_do", map[string]any{
+			"rich_text": notionText(text),
+			"checked":   step.Status == plan.StatusCompleted,
+		}))
+	}
+	return blocks
+}
+
+func notionBlock(kind string, content map[string]any) map[string]any {
+	return map[string]any{"object": "block", "type": kind, kind: content}
+}
+
+// notionText builds a rich text array, truncated to Notion's length limit
+func notionText(s string) []any {
+	if runes := []rune(s); len(runes) > notionMaxTextLen {
+		s = string(runes[:notionMaxTextLen-3]) + "..."
+	}
+	return []any{map[string]any{"type": "text", "text": map[string]any{"content": s}}}
+}
@@ -0,0 +1,153 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// stepRow is a step's figures as shown in a rendered report
+type stepRow struct {
+	step     plan.Step
+	duration string // Time over all recorded attempts, or "-" for none
+	cost     string // Agent-reported cost over the recorded runs, or "-"
+}
+
+// rows prepares the report's steps for rendering
+func (r *Report) rows() []stepRow {
+	var rows []stepRow
+	for i := range r.Plan.Steps {
+		step := &r.Plan.Steps[i]
+		row := stepRow{step: *step, duration: "-", cost: "-"}
+		if len(step.Attempts) > 0 {
+			row.duration = plan.FormatDuration(step.TimeSpent())
+		}
+		if cost := r.Costs[step.Number]; cost > 0 {
+			row.cost = fmt.Sprintf("$%.2f", cost)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// TotalsLine sums the time and cost of all steps in one sentence, or ""
+// when nothing was recorded
+func (r *Report) TotalsLine() string {
+	var duration time.Duration
+	var cost float64
+	for i := range r.Plan.Steps {
+		step := &r.Plan.Steps[i]
+		duration += step.TimeSpent()
+		cost += r.Costs[step.Number]
+	}
+	var parts []string
+	if duration > 0 {
+		parts = append(parts, "Agent time: "+plan.FormatDuration(duration))
+	}
+	if cost > 0 {
+		parts = append(parts, fmt.Sprintf("cost: $%.2f", cost))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, ", ") + "."
+}
+
+// Markdown renders the report as a Markdown document
+func Markdown(report *Report) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", report.Title())
+	fmt.Fprintf(&sb, "%s\n", report.SummaryLine())
+	for _, line := range []string{report.TotalsLine(), report.RunLine()} {
+		if line != "" {
+			fmt.Fprintf(&sb, "\n%s\n", line)
+		}
+	}
+
+	sb.WriteString("\n## Steps\n\n")
+	sb.WriteString("| Step | Description | Status | Time | Retries | Cost | Notes | Transcript |\n")
+	sb.WriteString("|------|-------------|--------|------|---------|------|-------|------------|\n")
+	for _, row := range report.rows() {
+		transcript := "-"
+		if row.step.Transcript != "" {
+			transcript = fmt.Sprintf("[output](%s)", row.step.Transcript)
+		}
+		fmt.Fprintf(&sb, "| %d | %s | %s | %s | %d | %s | %s | %s |\n",
+			row.step.Number, markdownCell(row.step.Description), row.step.Status, row.duration,
+			row.step.RetryCount, row.cost, markdownCell(row.step.Notes), transcript)
+	}
+	if warnings := report.warnings(); len(warnings) > 0 {
+		sb.WriteString("\n## Warnings\n\n")
+		for _, warning := range warnings {
+			fmt.Fprintf(&sb, "- %s\n", strings.Join(strings.Fields(warning), " "))
+		}
+	}
+	return sb.String()
+}
+
+// warnings returns the warnings of the report's run, if any
+func (r *Report) warnings() []string {
+	if r.Run == nil {
+		return nil
+	}
+	return r.Run.Warnings
+}
+
+// markdownCell makes text safe for a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// htmlStyle keeps the page readable without any external assets
+const htmlStyle = `body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Helvetica,Arial,sans-serif;margin:2em auto;max-width:72em;padding:0 1em;color:#1f2328}
+table{border-collapse:collapse;width:100%}
+th,td{border:1px solid #d0d7de;padding:6px 10px;text-align:left;vertical-align:top}
+th{background:#f6f8fa}
+.completed{color:#1a7f37}.failed{color:#cf222e}.skipped,.blocked{color:#9a6700}.pending{color:#59636e}`
+
+// HTML renders the report as a standalone HTML page
+func HTML(report *Report) string {
+	var sb strings.Builder
+	title := html.EscapeString(report.Title())
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", title, htmlStyle)
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", title)
+	for _, line := range []string{report.SummaryLine(), report.TotalsLine(), report.RunLine()} {
+		if line != "" {
+			fmt.Fprintf(&sb, "<p>%s</p>\n", html.EscapeString(line))
+		}
+	}
+
+	sb.WriteString("<h2>Steps</h2>\n<table>\n")
+	sb.WriteString("<tr><th>Step</th><th>Description</th><th>Status</th><th>Time</th><th>Retries</th><th>Cost</th><th>Notes</th><th>Transcript</th></tr>\n")
+	for _, row := range report.rows() {
+		notes := "-"
+		if row.step.Notes != "" {
+			notes = html.EscapeString(row.step.Notes)
+		}
+		transcript := "-"
+		if row.step.Transcript != "" {
+			transcript = fmt.Sprintf(`<a href="%s">output</a>`, html.EscapeString(row.step.Transcript))
+		}
+		fmt.Fprintf(&sb, "<tr><td>%d</td><td>%s</td><td class=\"%s\">%s</td><td>%s</td><td>%d</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			row.step.Number, html.EscapeString(row.step.Description), row.step.Status, row.step.Status, row.duration,
+			row.step.RetryCount, row.cost, notes, transcript)
+	}
+	sb.WriteString("</table>\n")
+	if warnings := report.warnings(); len(warnings) > 0 {
+		sb.WriteString("<h2>Warnings</h2>\n<ul>\n")
+		for _, warning := range warnings {
+			fmt.Fprintf(&sb, "<li>%s</li>\n", html.EscapeString(warning))
+		}
+		sb.WriteString("</ul>\n")
+	}
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
@@ -0,0 +1,216 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// jiraPageSize is how many issues are requested at a time
+const jiraPageSize = 100
+
+// JiraOptions configures the Jira client
+type JiraOptions struct {
+	BaseURL  string // e.g. https://acme.atlassian.net (JIRA_URL)
+	User     string // Account email (JIRA_USER); empty for a personal access token
+	APIToken string // API token, or personal access token on Jira Server (JIRA_API_TOKEN)
+}
+
+// Ticket is a Jira issue imported as a step
+type Ticket struct {
+	Key     string
+	Summary string
+}
+
+// JiraClient searches Jira issues through the REST API
+type JiraClient struct {
+	opts   JiraOptions
+	client *http.Client
+}
+
+// NewJiraClient creates a Jira client
+func NewJiraClient(opts JiraOptions) (*JiraClient, error) {
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("jira import requires --url or JIRA_URL")
+	}
+	if opts.APIToken == "" {
+		return nil, fmt.Errorf("jira import requires JIRA_API_TOKEN (and JIRA_USER on Jira Cloud)")
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	return &JiraClient{opts: opts, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// jiraIssues is the part of a search response that is used
+type jiraIssues struct {
+	Issues []struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+		} `json:"fields"`
+	} `json:"issues"`
+	Total         int    `json:"total"`         // API v2
+	NextPageToken string `json:"nextPageToken"` // API v3
+	IsLast        bool   `json:"isLast"`        // API v3
+}
+
+// Search returns the issues matching jql, in the order Jira returns them,
+// up to limit (0 for all). It uses the search API of Jira Cloud and falls
+// back to the older one of Jira Server and Data Center.
+func (j *JiraClient) Search(ctx context.Context, jql string, limit int) ([]Ticket, error) {
+	tickets, err := j.searchCloud(ctx, jql, limit)
+	var status *statusError
+	if errors.As(err, &status) && status.code == http.StatusNotFound {
+		return j.searchServer(ctx, jql, limit)
+	}
+	return tickets, err
+}
+
+// searchCloud pages through POST /rest/api/3/search/jql
+func (j *JiraClient) searchCloud(ctx context.Context, jql string, limit int) ([]Ticket, error) {
+	var tickets []Ticket
+	token := ""
+	for {
+		request := map[string]any{"jql": jql, "fields": []string{"summary"}, "maxResults": jiraPageSize}
+		if token != "" {
+			request["nextPageToken"] = token
+		}
+		var page jiraIssues
+		if err := j.do(ctx, http.MethodPost, "/rest/api/3/search/jql", request, &page); err != nil {
+			return nil, err
+		}
+		tickets = appendTickets(tickets, page)
+		if page.IsLast || page.NextPageToken == "" || len(page.Issues) == 0 || reached(tickets, limit) {
+			return truncate(tickets, limit), nil
+		}
+		token = page.NextPageToken
+	}
+}
+
+// searchServer pages through GET /rest/api/2/search
+func (j *JiraClient) searchServer(ctx context.Context, jql string, limit int) ([]Ticket, error) {
+	var tickets []Ticket
+	for {
+		query := url.Values{}
+		query.Set("jql", jql)
+		query.Set("fields", "summary")
+		query.Set("startAt", fmt.Sprint(len(tickets)))
+		query.Set("maxResults", fmt.Sprint(jiraPageSize))
+		var page jiraIssues
+		if err := j.do(ctx, http.MethodGet, "/rest/api/2/search?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		tickets = appendTickets(tickets, page)
+		if len(tickets) >= page.Total || len(page.Issues) == 0 || reached(tickets, limit) {
+			return truncate(tickets, limit), nil
+		}
+	}
+}
+
+// appendTickets adds the issues of a search response page
+func appendTickets(tickets []Ticket, page jiraIssues) []Ticket {
+	for _, issue := range page.Issues {
+		tickets = append(tickets, Ticket{Key: issue.Key, Summary: issue.Fields.Summary})
+	}
+	return tickets
+}
+
+// reached reports whether limit tickets have been found (0 is no limit)
+func reached(tickets []Ticket, limit int) bool {
+	return limit > 0 && len(tickets) >= limit
+}
+
+// truncate drops the tickets beyond limit (0 is no limit)
+func truncate(tickets []Ticket, limit int) []Ticket {
+	if reached(tickets, limit) {
+		return tickets[:limit]
+	}
+	return tickets
+}
+
+// statusError is a Jira response with an error status
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+// do sends a Jira REST request and decodes the response into out (if non-nil)
+func (j *JiraClient) do(ctx context.Context, method string, path string, body any, out any) error {
+	var payload io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, j.opts.BaseURL+path, payload)
+	if err != nil {
+		return err
+	}
+	if j.opts.User != "" {
+		req.SetBasicAuth(j.opts.User, j.opts.APIToken)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.opts.APIToken)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		endpoint, _, _ := strings.Cut(path, "?")
+		return &statusError{
+			code:    resp.StatusCode,
+			message: fmt.Sprintf("%s %s: %s: %s", method, endpoint, resp.Status, bytes.TrimSpace(respBody)),
+		}
+	}
+	if out != nil {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
+
+// JiraPlan builds a plan with one pending step per ticket, in order. Each
+// step keeps its ticket's key in a (ticket: ...) annotation.
+func JiraPlan(project string, jql string, tickets []Ticket) *plan.Plan {
+	p := &plan.Plan{
+		ProjectName: project,
+		Context:     fmt.Sprintf("Imported from Jira with: %s", jql),
+	}
+	for i, ticket := range tickets {
+		summary := strings.Join(strings.Fields(ticket.Summary), " ")
+		if summary == "" {
+			summary = ticket.Key
+		}
+		p.Steps = append(p.Steps, plan.Step{
+			Number:      i + 1,
+			Description: summary,
+			Status:      plan.StatusPending,
+			Ticket:      ticket.Key,
+		})
+	}
+	return p
+}
@@ -0,0 +1,193 @@
+// Package layout manages the .ralph-loop directory next to a plan: where
+// each kind of run data lives, the layout version, and migrating
+// directories written by older versions.
+package layout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DirName is the name of the managed directory
+const DirName = ".ralph-loop"
+
+// Version is the current layout version. Directories without a version
+// file predate versioning and are version 1.
+const Version = 2
+
+// Entries of the managed directory
+const (
+	ConfigFile = "config.json" // Project config, meant to be committed
+	Templates  = "templates"   // Step templates, meant to be committed
+	State      = "state"       // Live state of running loops
+	Locks      = "locks"       // Lock files of running loops
+	Logs       = "logs"        // Transcripts, run records and failure bundles
+	Scratch    = "scratch"     // Per-attempt scratch directories for agents
+	Backups    = "backups"     // Copies of files taken before they are rewritten
+	History    = "history"     // Records kept across runs
+	Cache      = "cache"       // Data that can be recomputed at any time
+	Worktrees  = "worktrees"   // Git worktrees that isolated runs work in
+
+	versionFile = "layout"
+	ignoreFile  = ".gitignore"
+)
+
+// gitignore keeps everything but the files meant to be shared out of git
+const gitignore = `# Managed by ralph-loop. Run data stays out of git; the config and
+# step templates are meant to be committed.
+/*
+!/.gitignore
+!/config.json
+!/templates/
+`
+
+// migrations upgrade a directory from the version before each one's
+// target, in order
+var migrations = []struct {
+	to      int
+	apply   func(root string) error
+	summary string
+}{
+	{2, groupLogs, "moved transcripts/ and failures/ into logs/"},
+}
+
+// Dir is a plan's managed directory
+type Dir string
+
+// ForPlan returns the managed directory of a plan: .ralph-loop next to it
+func ForPlan(planPath string) Dir {
+	return Dir(filepath.Join(filepath.Dir(planPath), DirName))
+}
+
+// Path joins elements onto the directory, e.g. Path(Logs, "failures")
+func (d Dir) Path(elem ...string) string {
+	return filepath.Join(append([]string{string(d)}, elem...)...)
+}
+
+// Ensure creates the directory if needed and brings it up to date: it
+// migrates an older layout, records the version and adds the .gitignore.
+// Run it before writing run data.
+func (d Dir) Ensure() error {
+	if _, err := os.Stat(string(d)); os.IsNotExist(err) {
+		// A new directory starts out at the current version
+		if err := os.MkdirAll(string(d), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", d, err)
+		}
+		if err := d.setVersion(Version); err != nil {
+			return err
+		}
+	}
+	return d.upgrade()
+}
+
+// Upgrade brings an existing directory up to date like Ensure, but doesn't
+// create one. Run it before reading run data another version may have
+// written.
+func (d Dir) Upgrade() error {
+	if _, err := os.Stat(string(d)); os.IsNotExist(err) {
+		return nil
+	}
+	return d.upgrade()
+}
+
+func (d Dir) upgrade() error {
+	version, err := d.version()
+	if err != nil {
+		return err
+	}
+	if version > Version {
+		return fmt.Errorf("%s has layout version %d, but this ralph-loop only knows up to %d; upgrade ralph-loop", d, version, Version)
+	}
+
+	for _, m := range migrations {
+		if m.to <= version {
+			continue
+		}
+		if err := m.apply(string(d)); err != nil {
+			return fmt.Errorf("failed to migrate %s to layout version %d: %w", d, m.to, err)
+		}
+		if err := d.setVersion(m.to); err != nil {
+			return err
+		}
+		fmt.Printf("Migrated %s to layout version %d: %s\n", d, m.to, m.summary)
+		version = m.to
+	}
+	if version < Version {
+		if err := d.setVersion(Version); err != nil {
+			return err
+		}
+	}
+
+	ignore := d.Path(ignoreFile)
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte(gitignore), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", ignore, err)
+		}
+	}
+	return nil
+}
+
+// version reads the layout version; 1 when the file is missing
+func (d Dir) version() (int, error) {
+	content, err := os.ReadFile(d.Path(versionFile))
+	if os.IsNotExist(err) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read layout version: %w", err)
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid layout version in %s: %q", d.Path(versionFile), strings.TrimSpace(string(content)))
+	}
+	return version, nil
+}
+
+func (d Dir) setVersion(version int) error {
+	if err := os.WriteFile(d.Path(versionFile), []byte(strconv.Itoa(version)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write layout version: %w", err)
+	}
+	return nil
+}
+
+// groupLogs moves transcripts and failure bundles, which version 1 kept at
+// the top level, under logs/
+func groupLogs(root string) error {
+	for _, name := range []string{"transcripts", "failures"} {
+		if err := moveInto(filepath.Join(root, name), filepath.Join(root, Logs, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveInto moves the directory src to dst. If dst exists, src's entries
+// are moved into it one by one, keeping dst's on a name clash.
+func moveInto(src string, dst string) error {
+	entries, err := os.ReadDir(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dst); os.IsNotExist(err) {
+		return os.Rename(src, dst)
+	}
+	for _, entry := range entries {
+		target := filepath.Join(dst, entry.Name())
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := os.Rename(filepath.Join(src, entry.Name()), target); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(src)
+}
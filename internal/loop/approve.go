@@ -0,0 +1,129 @@
+package loop
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// rejectedInReview is the failure class of attempts the operator sent back
+// with --approve-each
+const rejectedInReview = "Rejected in review"
+
+// reviewOutputLines is how much of the end of the agent's output the review
+// shows as the step's summary
+const reviewOutputLines = 15
+
+// errNoOperator is returned when a review needs an answer and stdin is closed
+var errNoOperator = errors.New("--approve-each needs an answer, but stdin is closed")
+
+// review shows the operator what an attempt that reported success did, and
+// asks them to approve it, send it back for a retry, or edit the changes
+// themselves first. A rejected attempt is returned as a failure, with the
+// operator's feedback as its reason.
+func (r *Runner) review(ctx context.Context, step *plan.Step, result plan.StepResult, before *worktreeSnapshot) (plan.StepResult, error) {
+	r.updateState(step, PhaseReviewing, time.Now())
+	fmt.Printf("\n=== Review Step %d: %s ===\n", step.Number, step.Description)
+	if summary := lastLines(result.Output, reviewOutputLines); summary != "" {
+		fmt.Printf("\nEnd of the agent's output:\n%s\n", summary)
+	}
+	r.printReviewDiff(before, "--stat")
+
+	for {
+		answer, err := r.ask(ctx, fmt.Sprintf("\nApprove Step %d? [a]pprove, [r]etry, [e]dit, show [d]iff: ", step.Number))
+		if err != nil {
+			return result, err
+		}
+		switch strings.ToLower(answer) {
+		case "a", "approve":
+			fmt.Printf("Step %d approved.\n", step.Number)
+			return result, nil
+		case "r", "retry":
+			feedback, err := r.ask(ctx, "Feedback for the next attempt (optional): ")
+			if err != nil {
+				return result, err
+			}
+			reason := rejectedInReview
+			if feedback != "" {
+				reason += ": " + feedback
+			}
+			return plan.StepResult{Success: false, Output: result.Output, Reason: reason, SessionID: result.SessionID}, nil
+		case "e", "edit":
+			if _, err := r.ask(ctx, "Edit the working tree, then press Enter to review again. "); err != nil {
+				return result, err
+			}
+			r.printReviewDiff(before, "--stat")
+		case "d", "diff":
+			r.printReviewDiff(before)
+		default:
+			fmt.Println("Answer a, r, e or d.")
+		}
+	}
+}
+
+// printReviewDiff prints how the working tree changed since the attempt
+// started, including new untracked files
+func (r *Runner) printReviewDiff(before *worktreeSnapshot, options ...string) {
+	diff, err := r.attemptDiff(before, options...)
+	if err != nil {
+		fmt.Printf("\n(No diff to show: %v)\n", err)
+		return
+	}
+	if diff == "" {
+		fmt.Println("\nNo changes to the working tree.")
+		return
+	}
+	fmt.Printf("\nChanges since the attempt started:\n%s\n", diff)
+}
+
+// ask prints a question and waits for a line on stdin. Lines are read in
+// the background, so the wait can be cancelled.
+func (r *Runner) ask(ctx context.Context, question string) (string, error) {
+	if r.answers == nil {
+		answers := make(chan string)
+		go func() {
+			defer close(answers)
+			reader := bufio.NewReader(os.Stdin)
+			for {
+				line, err := reader.ReadString('\n')
+				if line != "" || err == nil {
+					answers <- strings.TrimSpace(line)
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+		r.answers = answers
+	}
+
+	fmt.Print(question)
+	select {
+	case answer, ok := <-r.answers:
+		if !ok {
+			fmt.Println()
+			return "", errNoOperator
+		}
+		return answer, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// lastLines returns the last n non-blank lines of output
+func lastLines(output string, n int) string {
+	var lines []string
+	all := strings.Split(output, "\n")
+	for i := len(all) - 1; i >= 0 && len(lines) < n; i-- {
+		if strings.TrimSpace(all[i]) != "" {
+			lines = append([]string{all[i]}, lines...)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
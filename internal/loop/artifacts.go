@@ -0,0 +1,47 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/storage"
+)
+
+// uploadArtifacts uploads the files matching the configured artifact
+// patterns to <destination>/<run>/step-<n>/ and returns their URLs (see
+// storage.Open). Upload problems are recorded as warnings rather than
+// failing the step.
+func (r *Runner) uploadArtifacts(ctx context.Context, stepNum int) []string {
+	if len(r.config.Artifacts) == 0 || r.config.ArtifactDest == "" {
+		return nil
+	}
+
+	var sources []string
+	for _, pattern := range r.config.Artifacts {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			r.warnings.Add(WarningArtifacts, "invalid artifact pattern %q: %v", pattern, err)
+			continue
+		}
+		if len(matches) == 0 {
+			r.warnings.Add(WarningArtifacts, "no files match artifact pattern %q", pattern)
+		}
+		sources = append(sources, matches...)
+	}
+
+	store := storage.Open(r.config.ArtifactDest)
+	prefix := fmt.Sprintf("%s/step-%d", r.runStartedAt.Format("20060102-150405"), stepNum)
+	var urls []string
+	for _, src := range sources {
+		url, err := store.Copy(ctx, path.Join(prefix, filepath.Base(src)), src)
+		if err != nil {
+			r.warnings.Add(WarningArtifacts, "failed to upload %s: %v", src, err)
+			continue
+		}
+		fmt.Printf("Uploaded artifact %s -> %s\n", src, url)
+		urls = append(urls, url)
+	}
+	return urls
+}
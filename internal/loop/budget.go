@@ -0,0 +1,59 @@
+package loop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// RunBudget caps a whole run. Limits are checked between attempts, so the
+// attempt that reaches one finishes first. Zero fields are no limit.
+type RunBudget struct {
+	MaxDuration   time.Duration // Wall-clock time since the run started
+	MaxCost       float64       // US dollars, summed over the costs the agents reported
+	MaxIterations int           // Agent attempts
+}
+
+// spent returns the cost reported by this run's attempts so far, and how
+// many attempts it made
+func (r *Runner) spent() (cost float64, attempts int) {
+	if r.record == nil {
+		return 0, 0
+	}
+	for _, a := range r.record.Attempts {
+		cost += a.CostUSD
+	}
+	return cost, len(r.record.Attempts)
+}
+
+// exhaustedBudget describes the run limit that has been reached, e.g. "max
+// cost $10.00 reached ($10.37 spent)", or returns "" while all have room
+func (r *Runner) exhaustedBudget() string {
+	budget := r.config.Budget
+	if elapsed := time.Since(r.runStartedAt); budget.MaxDuration > 0 && elapsed >= budget.MaxDuration {
+		return fmt.Sprintf("max duration %s reached (ran %s)", plan.FormatDuration(budget.MaxDuration), plan.FormatDuration(elapsed.Round(time.Second)))
+	}
+	cost, attempts := r.spent()
+	if budget.MaxCost > 0 && !r.costUnreported && attempts > 0 && r.record.Attempts[attempts-1].CostUSD == 0 {
+		r.warnings.Add(WarningBudget, "max cost $%.2f not fully enforced: the agent did not report what its attempts cost", budget.MaxCost)
+		r.costUnreported = true
+	}
+	if budget.MaxCost > 0 && cost >= budget.MaxCost {
+		return fmt.Sprintf("max cost $%.2f reached ($%.2f spent)", budget.MaxCost, cost)
+	}
+	if budget.MaxIterations > 0 && attempts >= budget.MaxIterations {
+		return fmt.Sprintf("max iterations %d reached", budget.MaxIterations)
+	}
+	return ""
+}
+
+// stopOnBudget ends the run gracefully, noting in the plan which limit was
+// reached
+func (r *Runner) stopOnBudget(reason string) {
+	note := fmt.Sprintf("%s: %s. Run ralph-loop again to continue.", time.Now().Format("2006-01-02 15:04"), reason)
+	if err := plan.MarkBudgetExhausted(r.planPath, note); err != nil {
+		r.warnings.Add(WarningBudget, "failed to record the exhausted budget in the plan: %v", err)
+	}
+	fmt.Printf("\nStopped: budget exhausted, %s. Run ralph-loop again to continue.\n", reason)
+}
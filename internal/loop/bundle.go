@@ -0,0 +1,124 @@
+package loop
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+const bundleTranscriptSize = 64 * 1024 // Keep the last 64 KB of output in a failure bundle
+
+// Environment variables whose presence (never value) is recorded in bundles
+var bundleEnvVars = []string{"ANTHROPIC_API_KEY", "OPENAI_API_KEY", "GEMINI_API_KEY", "GOOGLE_API_KEY", "CI", "HTTP_PROXY", "HTTPS_PROXY"}
+
+// writeFailureBundle saves everything needed to investigate a failed attempt
+// into a single directory and returns its path:
+//
+//	summary.txt     step, reason, attempt and warnings raised during the step
+//	transcript.log  the last 64 KB of agent output
+//	prompt.md       the prompt sent to the agent
+//	diff.patch      uncommitted changes in the working tree (if in a git repo)
+//	env.txt         platform, config and which API keys are set
+//	state.json      the runner's live state at the time of failure
+func (r *Runner) writeFailureBundle(step *plan.Step, promptText string, output string, reason string, startedAt time.Time) (string, error) {
+	baseDir := filepath.Dir(r.planPath)
+	dir := layout.ForPlan(r.planPath).Path(layout.Logs, "failures",
+		fmt.Sprintf("step-%d-%s", step.Number, time.Now().Format("20060102-150405")))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create failure bundle: %w", err)
+	}
+
+	files := map[string]string{
+		"summary.txt":    r.bundleSummary(step, reason, startedAt),
+		"transcript.log": tail(output, bundleTranscriptSize),
+		"prompt.md":      promptText,
+		"env.txt":        r.bundleEnv(),
+	}
+	if diff := gitOutput(baseDir, "status", "--short"); diff != "" {
+		files["diff.patch"] = "# git status --short\n" + diff + "\n" + gitOutput(baseDir, "diff", "HEAD")
+	}
+	if state, err := ReadState(r.planPath); err == nil && state != nil {
+		if content, err := json.MarshalIndent(state, "", "  "); err == nil {
+			files["state.json"] = string(content)
+		}
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return "", fmt.Errorf("failed to write failure bundle: %w", err)
+		}
+	}
+
+	return dir, nil
+}
+
+func (r *Runner) bundleSummary(step *plan.Step, reason string, startedAt time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Step %d: %s\n", step.Number, step.Description))
+	sb.WriteString(fmt.Sprintf("Attempt: %d of %d\n", step.RetryCount+1, r.maxRetries(step)))
+	sb.WriteString(fmt.Sprintf("Agent: %s\n", r.activeAgent().Name()))
+	sb.WriteString(fmt.Sprintf("Started: %s\n", startedAt.Format("2006-01-02 15:04:05")))
+	sb.WriteString(fmt.Sprintf("Duration: %v\n", time.Since(startedAt).Round(time.Second)))
+	sb.WriteString(fmt.Sprintf("Reason: %s\n", reason))
+
+	var stepWarnings []Warning
+	for _, w := range r.warnings.Warnings() {
+		if w.Step == step.Number {
+			stepWarnings = append(stepWarnings, w)
+		}
+	}
+	if len(stepWarnings) > 0 {
+		sb.WriteString("\nWarnings during this step:\n")
+		for _, w := range stepWarnings {
+			sb.WriteString(fmt.Sprintf("  [%s] %s\n", w.Kind, w.Message))
+		}
+	}
+	return sb.String()
+}
+
+func (r *Runner) bundleEnv() string {
+	var sb strings.Builder
+	wd, _ := os.Getwd()
+	sb.WriteString(fmt.Sprintf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH))
+	sb.WriteString(fmt.Sprintf("Working directory: %s\n", wd))
+	sb.WriteString(fmt.Sprintf("Plan file: %s\n", r.planPath))
+	sb.WriteString(fmt.Sprintf("Timeout: %v, Max retries: %d, Retry delay: %v\n",
+		r.config.Timeout, r.config.MaxRetries, r.config.RetryDelay))
+
+	sb.WriteString("\nEnvironment (presence only):\n")
+	for _, name := range bundleEnvVars {
+		status := "unset"
+		if os.Getenv(name) != "" {
+			status = "set"
+		}
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", name, status))
+	}
+	return sb.String()
+}
+
+// tail returns at most the last n bytes of s, noting when it was truncated
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return fmt.Sprintf("... (truncated, showing last %d KB)\n", n/1024) + s[len(s)-n:]
+}
+
+// gitOutput runs a git command in dir and returns its output, or "" on error
+func gitOutput(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(string(out), "\n")
+}
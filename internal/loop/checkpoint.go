@@ -0,0 +1,155 @@
+package loop
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkpointRefPrefix is where checkpoints are kept in the repository, under
+// the plan's path relative to the repository root
+const checkpointRefPrefix = "refs/ralph-loop/checkpoints/"
+
+// unsafeRefChars are characters that can't appear in a git ref name
+var unsafeRefChars = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+
+// Checkpoint is the state of the repository before a step first ran: the
+// commit checked out and the working tree, untracked files included
+type Checkpoint struct {
+	Step      int
+	Commit    string // Checkpoint commit; its tree is the working tree
+	Head      string // Commit that was checked out
+	CreatedAt time.Time
+}
+
+// checkpointRefs returns the ref namespace of a plan's checkpoints, or ""
+// when the plan isn't in a git repository
+func checkpointRefs(planPath string) string {
+	dir := filepath.Dir(planPath)
+	if gitOutput(dir, "rev-parse", "--is-inside-work-tree") != "true" {
+		return ""
+	}
+	rel := gitOutput(dir, "rev-parse", "--show-prefix") + filepath.Base(planPath)
+	rel = unsafeRefChars.ReplaceAllString(rel, "-")
+	rel = strings.ReplaceAll("/"+rel, "/.", "/_")[1:]
+	return checkpointRefPrefix + rel + "/"
+}
+
+// checkpointStep records the working tree before a step's first attempt,
+// unless this run already did. A step retried from an earlier run keeps
+// the checkpoint taken before it first ran.
+func (r *Runner) checkpointStep(stepNum int, retried bool, snapshot *worktreeSnapshot) {
+	refs := checkpointRefs(r.planPath)
+	if refs == "" || snapshot == nil || r.checkpointed[stepNum] {
+		return
+	}
+	ref := fmt.Sprintf("%sstep-%d", refs, stepNum)
+	dir := filepath.Dir(r.planPath)
+	if retried && gitOutput(dir, "rev-parse", "--verify", "--quiet", ref) != "" {
+		r.checkpointed[stepNum] = true
+		return
+	}
+
+	commit, err := commitCheckpoint(dir, stepNum, snapshot)
+	if err == nil {
+		_, err = gitRun(dir, "update-ref", ref, commit)
+	}
+	if err != nil {
+		r.warnings.Add(WarningCheckpoint, "failed to checkpoint the working tree: %v", err)
+		return
+	}
+	r.checkpointed[stepNum] = true
+}
+
+// commitCheckpoint writes a snapshot as a commit on top of the commit it
+// was taken at. Checkpoints are authored by ralph-loop, so they work in
+// repositories without a git identity configured.
+func commitCheckpoint(dir string, stepNum int, snapshot *worktreeSnapshot) (string, error) {
+	message := fmt.Sprintf("ralph-loop checkpoint before Step %d", stepNum)
+	cmd := exec.Command("git", "commit-tree", snapshot.tree, "-p", snapshot.head, "-m", message)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(),
+		"GIT_AUTHOR_NAME=ralph-loop", "GIT_AUTHOR_EMAIL=ralph-loop@localhost",
+		"GIT_COMMITTER_NAME=ralph-loop", "GIT_COMMITTER_EMAIL=ralph-loop@localhost")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git commit-tree: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// needsCheckpoint reports whether a step's attempt should snapshot the
+// working tree for a checkpoint
+func (r *Runner) needsCheckpoint(stepNum int) bool {
+	return r.config.Checkpoints && !r.checkpointed[stepNum]
+}
+
+// Checkpoints returns the checkpoints of a plan's steps, oldest first
+func Checkpoints(planPath string) ([]Checkpoint, error) {
+	refs := checkpointRefs(planPath)
+	if refs == "" {
+		return nil, fmt.Errorf("%s is not in a git repository", planPath)
+	}
+	out, err := gitRun(filepath.Dir(planPath), "for-each-ref", "--format=%(refname) %(objectname) %(parent) %(creatordate:unix)", refs)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []Checkpoint
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		stepNum, err := strconv.Atoi(strings.TrimPrefix(fields[0], refs+"step-"))
+		if err != nil {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[3], 10, 64)
+		checkpoints = append(checkpoints, Checkpoint{
+			Step:      stepNum,
+			Commit:    fields[1],
+			Head:      fields[2],
+			CreatedAt: time.Unix(unix, 0),
+		})
+	}
+	// Commit dates are in seconds; steps checkpointed in the same second
+	// most likely ran in plan order
+	sort.Slice(checkpoints, func(i, j int) bool {
+		a, b := checkpoints[i], checkpoints[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.Step < b.Step
+	})
+	return checkpoints, nil
+}
+
+// CheckpointDiff returns how restoring a checkpoint would change the working
+// tree, as git diff --stat. The plan file, which a restore keeps, is left
+// out.
+func CheckpointDiff(planPath string, cp Checkpoint) (string, error) {
+	dir := filepath.Dir(planPath)
+	current, err := snapshotDir(dir)
+	if err != nil {
+		return "", err
+	}
+	return gitOutput(dir, "diff", "--stat", current.tree, cp.Commit+"^{tree}", "--", ":/", ":(exclude)"+filepath.Base(planPath)), nil
+}
+
+// RestoreCheckpoint resets the repository to a checkpoint: the branch goes
+// back to the commit that was checked out, and the working tree to what it
+// held, untracked files included. Ignored files and the plan file are left
+// alone.
+func RestoreCheckpoint(planPath string, cp Checkpoint) error {
+	tree := gitOutput(filepath.Dir(planPath), "rev-parse", cp.Commit+"^{tree}")
+	if tree == "" {
+		return fmt.Errorf("checkpoint %s not found", shortRev(cp.Commit))
+	}
+	return restoreWorktree(planPath, &worktreeSnapshot{head: cp.Head, tree: tree})
+}
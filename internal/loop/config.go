@@ -1,21 +1,65 @@
 package loop
 
-import "time"
+import (
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
 
 // Config holds configuration for the loop runner
 type Config struct {
-	Timeout       time.Duration // Per-step timeout (default: 30m)
-	MaxRetries    int           // Max retry attempts per step (default: 3)
-	RetryDelay    time.Duration // Initial delay between retries (default: 5s)
-	BackoffFactor float64       // Multiplier for exponential backoff (default: 2.0)
+	Timeout          time.Duration   // Per-step timeout (default: 30m)
+	MaxRetries       int             // Max retry attempts per step (default: 3)
+	TransientRetries int             // Immediate reruns of an attempt whose agent infrastructure failed, not counted as retries (default: 2)
+	RetryDelay       time.Duration   // Initial delay between retries (default: 5s)
+	BackoffFactor    float64         // Multiplier for exponential backoff (default: 2.0)
+	Order            string          // Step ordering strategy (default: sequential)
+	OnFailure        string          // What to do after a failed attempt: retry or continue (default: retry)
+	Verify           string          // Shell command that must pass before a step counts as complete (default: none)
+	Artifacts        []string        // Glob patterns of files to upload after each successful step
+	ArtifactDest     string          // Upload destination: s3://..., gs://... or a local directory
+	Upstream         string          // Branch to watch for changes between steps, e.g. origin/main (default: none)
+	Rebase           bool            // Rebase onto Upstream when it moves
+	Rollback         bool            // Reset the working tree to its state before a failed attempt
+	Checkpoints      bool            // Record the working tree before each step, for `ralph-loop restore` (default: true)
+	Review           bool            // Have a reviewer agent check each step the agent finishes before marking it complete
+	ReviewAgent      string          // Agent that reviews steps (default: the run's agent)
+	ReviewModel      string          // Model of the reviewer agent (default: the agent's default)
+	ApproveEach      bool            // Ask the operator to approve each step the agent finishes before marking it complete
+	Model            string          // Run's default model, recorded in run records
+	ResumeSessions   bool            // On retry, continue the failed attempt's agent session
+	LogDest          string          // Where transcripts and failure bundles are kept: s3://..., gs://... or a local directory (default: .ralph-loop next to the plan)
+	Stall            StallPolicy     // How to respond to an agent that stops producing output
+	MaxSteps         int             // Stop after this many steps complete (default: 0, no limit)
+	MaxPlanAdds      int             // Steps agents may append to the plan with PLAN_ADD lines in one run (default: 5; 0 disables)
+	Watch            bool            // Once no step can run, wait for new steps instead of ending the run
+	Budget           RunBudget       // Limits on the whole run's time, cost and attempts (default: none)
+	Phase            int             // Only run the steps of this phase (default: 0, all steps)
+	OnlyTags         []string        // Only run the steps with one of these tags, without the # (default: all steps)
+	From             int             // Only run the steps numbered from this one (default: 0, from the first)
+	Until            int             // Only run the steps numbered up to this one (default: 0, to the last)
+	Glossary         string          // File of project terms added to the plan's Glossary section (default: none)
+	ContextProviders []ContextSource // Extra context added to each prompt, in order (default: none)
+	Denylist         []string        // Patterns of destructive commands that stop an attempt when they show in its output (default: DefaultDenylist)
+	Hooks            Hooks           // Shell commands run around each step's attempts (default: none)
+	GitHubStatus     bool            // Publish progress as commit statuses on the GitHub commit being built
+	Notify           Notifications   // How the operator is told about progress (default: not at all)
+	ForceLock        bool            // Take the plan's lock even from a loop that looks alive
 }
 
 // DefaultConfig returns a Config with sensible defaults
 func DefaultConfig() Config {
 	return Config{
-		Timeout:       30 * time.Minute,
-		MaxRetries:    3,
-		RetryDelay:    5 * time.Second,
-		BackoffFactor: 2.0,
+		Timeout:          30 * time.Minute,
+		MaxRetries:       3,
+		TransientRetries: 2,
+		RetryDelay:       5 * time.Second,
+		BackoffFactor:    2.0,
+		Order:            plan.DefaultOrder,
+		OnFailure:        DefaultOnFailure,
+		MaxPlanAdds:      DefaultMaxPlanAdds,
+		Checkpoints:      true,
+		Stall:            DefaultStallPolicy(),
+		Denylist:         DefaultDenylist,
 	}
 }
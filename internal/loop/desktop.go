@@ -0,0 +1,86 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// desktopNotifyTimeout bounds the command that shows a desktop notification
+const desktopNotifyTimeout = 15 * time.Second
+
+// Notifications chooses how the operator hears about a run's progress
+// without watching the terminal
+type Notifications struct {
+	Desktop bool             // Native desktop notifications when a step finishes or fails, or the agent asks for input
+	Email   *EmailSettings   // Email a summary when the run ends; nil for none
+	Webhook *WebhookSettings // Post run and step events as JSON; nil for none
+}
+
+// DesktopNotifyTool returns the program desktop notifications are shown
+// with on this platform
+func DesktopNotifyTool() string {
+	return desktopNotifyTool
+}
+
+// sendDesktopNotification shows a notification with the platform's own
+// tool: osascript on macOS, notify-send on Linux, PowerShell on Windows
+func sendDesktopNotification(title string, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), desktopNotifyTimeout)
+	defer cancel()
+
+	cmd := desktopNotifyCommand(ctx, title, message)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if detail := strings.TrimSpace(string(out)); detail != "" {
+		return fmt.Errorf("%s: %v: %s", desktopNotifyTool, err, detail)
+	}
+	return fmt.Errorf("%s: %v", desktopNotifyTool, err)
+}
+
+// notifyDesktop shows a desktop notification in the background, when they
+// are on. Run waits for notifications still being shown before it returns.
+func (r *Runner) notifyDesktop(title string, message string) {
+	if !r.config.Notify.Desktop {
+		return
+	}
+	r.notifying.Add(1)
+	go func() {
+		defer r.notifying.Done()
+		if err := sendDesktopNotification(title, message); err != nil {
+			r.warnings.Add(WarningNotify, "desktop notification failed: %v", err)
+		}
+	}()
+}
+
+// notifyStepResult announces the outcome of a step's attempt on the desktop.
+// Marking a step skipped once it's out of retries runs no attempt, and its
+// last attempt was already announced.
+func (r *Runner) notifyStepResult(step *plan.Step, result plan.StepResult) {
+	if result.Status == plan.StatusSkipped && result.Attempt == nil {
+		return
+	}
+	title := fmt.Sprintf("Step %d completed", step.Number)
+	switch {
+	case result.Success:
+	case result.Status == plan.StatusSkipped || result.RetryCount >= r.maxRetries(step):
+		title = fmt.Sprintf("Step %d failed, out of retries", step.Number)
+	default:
+		title = fmt.Sprintf("Step %d failed, will retry", step.Number)
+	}
+	message := step.Description
+	if !result.Success {
+		message = result.Reason
+	}
+	r.notifyDesktop(title, message)
+}
+
+// notifyPrompt announces that the agent appears to be waiting for input
+func (r *Runner) notifyPrompt(step int, question string) {
+	r.notifyDesktop(fmt.Sprintf("Step %d: the agent is asking for input", step), question)
+}
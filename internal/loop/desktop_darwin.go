@@ -0,0 +1,21 @@
+//go:build darwin
+
+package loop
+
+import (
+	"context"
+	"os/exec"
+)
+
+// desktopNotifyTool is the program that shows notifications
+const desktopNotifyTool = "osascript"
+
+// desktopNotifyCommand shows a notification through Notification Center.
+// The text is passed as arguments, so it needs no AppleScript quoting.
+func desktopNotifyCommand(ctx context.Context, title string, message string) *exec.Cmd {
+	return exec.CommandContext(ctx, desktopNotifyTool,
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title \"ralph-loop\" subtitle (item 1 of argv)",
+		"-e", "end run",
+		title, message)
+}
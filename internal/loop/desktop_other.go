@@ -0,0 +1,17 @@
+//go:build !darwin && !windows
+
+package loop
+
+import (
+	"context"
+	"os/exec"
+)
+
+// desktopNotifyTool is the program that shows notifications
+const desktopNotifyTool = "notify-send"
+
+// desktopNotifyCommand shows a notification through notify-send, which
+// Linux and BSD desktops provide with libnotify
+func desktopNotifyCommand(ctx context.Context, title string, message string) *exec.Cmd {
+	return exec.CommandContext(ctx, desktopNotifyTool, "--app-name=ralph-loop", "ralph-loop: "+title, message)
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package loop
+
+import (
+	"context"
+	"os/exec"
+)
+
+// desktopNotifyTool is the program that shows notifications
+const desktopNotifyTool = "powershell"
+
+// desktopNotifyScript shows a balloon notification from the tray. The text
+// comes from the environment, so it needs no PowerShell quoting.
+const desktopNotifyScript = `Add-Type -AssemblyName System.Windows.Forms
+$icon = New-Object System.Windows.Forms.NotifyIcon
+$icon.Icon = [System.Drawing.SystemIcons]::Information
+$icon.Visible = $true
+$icon.ShowBalloonTip(10000, $env:RALPH_NOTIFY_TITLE, $env:RALPH_NOTIFY_MESSAGE, 'Info')
+Start-Sleep -Seconds 5
+$icon.Dispose()`
+
+// desktopNotifyCommand shows a notification through PowerShell
+func desktopNotifyCommand(ctx context.Context, title string, message string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, desktopNotifyTool, "-NoProfile", "-NonInteractive", "-Command", desktopNotifyScript)
+	cmd.Env = append(cmd.Environ(), "RALPH_NOTIFY_TITLE=ralph-loop: "+title, "RALPH_NOTIFY_MESSAGE="+message)
+	return cmd
+}
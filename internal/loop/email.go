@@ -0,0 +1,216 @@
+package loop
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// emailTimeout bounds sending the run summary, from connecting to QUIT
+const emailTimeout = 30 * time.Second
+
+// DefaultSMTPPort is the submission port, upgraded with STARTTLS
+const DefaultSMTPPort = 587
+
+// smtpsPort is the port of SMTP over implicit TLS
+const smtpsPort = 465
+
+// EmailSettings configure the run summary sent by email when a run ends
+type EmailSettings struct {
+	To       []string
+	From     string
+	Host     string // SMTP server
+	Port     int    // DefaultSMTPPort when 0; smtpsPort connects with TLS from the start
+	Username string // SMTP login; none when empty
+	Password string
+}
+
+// emailRunSummary sends the summary of the run that just ended, when an
+// address is configured
+func (r *Runner) emailRunSummary(runErr error) {
+	settings := r.config.Notify.Email
+	if settings == nil {
+		return
+	}
+	p, err := r.parsePlan()
+	if err != nil {
+		r.warnings.Add(WarningNotify, "run summary not emailed: %v", err)
+		return
+	}
+	subject, body := r.runSummary(p, runErr)
+	if err := sendEmail(settings, subject, body); err != nil {
+		r.warnings.Add(WarningNotify, "run summary not emailed: %v", err)
+		return
+	}
+	fmt.Printf("\nEmailed the run summary to %s\n", strings.Join(settings.To, ", "))
+}
+
+// runSummary writes the subject and plain-text body of the run summary
+func (r *Runner) runSummary(p *plan.Plan, runErr error) (string, string) {
+	completed, failed, skipped, pending := 0, 0, 0, 0
+	for _, step := range p.Steps {
+		switch step.Status {
+		case plan.StatusCompleted:
+			completed++
+		case plan.StatusFailed:
+			failed++
+		case plan.StatusSkipped:
+			skipped++
+		default:
+			pending++
+		}
+	}
+
+	outcome := "all steps completed"
+	switch {
+	case runErr != nil && !errors.Is(runErr, context.Canceled):
+		outcome = "run failed"
+	case runErr != nil:
+		outcome = "interrupted"
+	case !p.IsComplete():
+		outcome = fmt.Sprintf("stopped with %d of %d steps completed", completed, len(p.Steps))
+	case skipped > 0:
+		outcome = fmt.Sprintf("finished with %d skipped", skipped)
+	}
+	project := p.ProjectName
+	if project == "" {
+		project = r.planPath
+	}
+	subject := fmt.Sprintf("ralph-loop: %s: %s", project, outcome)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Plan: %s (%s)\n", project, r.planPath))
+	sb.WriteString(fmt.Sprintf("Agent: %s\n", r.agent.Name()))
+	if r.runID != "" {
+		sb.WriteString(fmt.Sprintf("Run ID: %s\n", r.runID))
+	}
+	sb.WriteString(fmt.Sprintf("Started: %s\n", r.runStartedAt.Format("2006-01-02 15:04:05")))
+	sb.WriteString(fmt.Sprintf("Duration: %s\n", plan.FormatDuration(time.Since(r.runStartedAt).Round(time.Second))))
+	if runErr != nil {
+		sb.WriteString(fmt.Sprintf("Ended with: %v\n", runErr))
+	}
+
+	cost, attempts := r.spent()
+	completedNow := 0
+	var failures []string
+	if r.record != nil {
+		for _, a := range r.record.Attempts {
+			if a.Success {
+				completedNow++
+			} else {
+				failures = append(failures, fmt.Sprintf("- Step %d: %s", a.Step, a.Reason))
+			}
+		}
+	}
+	sb.WriteString(fmt.Sprintf("\nThis run: %d step(s) completed, %d failed attempt(s), %d attempt(s) in all\n", completedNow, len(failures), attempts))
+	if cost > 0 {
+		sb.WriteString(fmt.Sprintf("Cost: $%.2f (as reported by the agents)\n", cost))
+	}
+	sb.WriteString(fmt.Sprintf("Plan: %d of %d steps completed, %d failed, %d skipped, %d pending\n", completed, len(p.Steps), failed, skipped, pending))
+	if len(failures) > 0 {
+		sb.WriteString("\nFailed attempts:\n")
+		sb.WriteString(strings.Join(failures, "\n"))
+		sb.WriteString("\n")
+	}
+
+	var attention []string
+	for _, step := range p.Steps {
+		if step.Status == plan.StatusFailed || step.Status == plan.StatusSkipped {
+			attention = append(attention, fmt.Sprintf("- Step %d (%s): %s", step.Number, step.Status, step.Description))
+		}
+	}
+	if len(attention) > 0 {
+		sb.WriteString("\nSteps that need attention:\n")
+		sb.WriteString(strings.Join(attention, "\n"))
+		sb.WriteString("\n")
+	}
+	if warnings := r.warnings.Warnings(); len(warnings) > 0 {
+		sb.WriteString(fmt.Sprintf("\n%d warning(s); see the run's output for them.\n", len(warnings)))
+	}
+	return subject, sb.String()
+}
+
+// sendEmail sends a plain-text message over SMTP. Port 465 uses TLS from
+// the start; other ports upgrade with STARTTLS when the server offers it.
+// Logging in is only done over TLS or to localhost.
+func sendEmail(settings *EmailSettings, subject string, body string) error {
+	port := settings.Port
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	addr := net.JoinHostPort(settings.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: settings.Host}
+	dialer := &net.Dialer{Timeout: emailTimeout}
+
+	var conn net.Conn
+	var err error
+	if port == smtpsPort {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(emailTimeout))
+
+	client, err := smtp.NewClient(conn, settings.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && port != smtpsPort {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if settings.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(settings.From); err != nil {
+		return err
+	}
+	for _, to := range settings.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(emailMessage(settings, subject, body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// emailMessage formats the headers and body of a plain-text message
+func emailMessage(settings *EmailSettings, subject string, body string) []byte {
+	var sb strings.Builder
+	sb.WriteString("From: " + settings.From + "\r\n")
+	sb.WriteString("To: " + strings.Join(settings.To, ", ") + "\r\n")
+	sb.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(sb.String())
+}
@@ -0,0 +1,256 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+const (
+	githubAPI            = "https://api.github.com"
+	githubStatusContext  = "ralph-loop"     // Context of the run's overall status; steps append " / step N"
+	githubMaxDescription = 140              // GitHub rejects longer status descriptions
+	githubStatusTimeout  = 15 * time.Second // Bounds each status update
+)
+
+// Commit status states
+const (
+	githubPending = "pending"
+	githubSuccess = "success"
+	githubFailure = "failure"
+	githubError   = "error"
+)
+
+// githubStatus publishes run progress as commit statuses on the commit
+// under review: one status per step and one for the run as a whole. It is
+// configured from the environment GitHub Actions provides.
+type githubStatus struct {
+	api       string
+	repo      string // owner/name
+	sha       string
+	token     string
+	targetURL string // Link from each status, e.g. the Actions run
+	client    *http.Client
+}
+
+// newGitHubStatus configures commit statuses from GITHUB_TOKEN (or
+// GH_TOKEN) and GITHUB_REPOSITORY. Statuses go to the head commit of the
+// pull request being built, else GITHUB_SHA, else the checked-out commit.
+func newGitHubStatus(dir string) (*githubStatus, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("--github-status requires GITHUB_TOKEN")
+	}
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if repo == "" {
+		return nil, fmt.Errorf("--github-status requires GITHUB_REPOSITORY (owner/name)")
+	}
+
+	sha := pullRequestHead(os.Getenv("GITHUB_EVENT_PATH"))
+	if sha == "" {
+		sha = os.Getenv("GITHUB_SHA")
+	}
+	if sha == "" {
+		out, err := gitRun(dir, "rev-parse", "HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("--github-status: no commit to publish to: %w", err)
+		}
+		sha = strings.TrimSpace(out)
+	}
+
+	api := strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/")
+	if api == "" {
+		api = githubAPI
+	}
+	var targetURL string
+	if server, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_RUN_ID"); server != "" && runID != "" {
+		targetURL = fmt.Sprintf("%s/%s/actions/runs/%s", strings.TrimSuffix(server, "/"), repo, runID)
+	}
+
+	return &githubStatus{
+		api:       api,
+		repo:      repo,
+		sha:       sha,
+		token:     token,
+		targetURL: targetURL,
+		client:    &http.Client{Timeout: githubStatusTimeout},
+	}, nil
+}
+
+// pullRequestHead returns the head commit of the pull request in a GitHub
+// event payload, or "" for other events. A pull request build checks out a
+// merge commit, but reviewers look at the branch's head.
+func pullRequestHead(eventPath string) string {
+	if eventPath == "" {
+		return ""
+	}
+	data, err := os.ReadFile(eventPath)
+	if err != nil {
+		return ""
+	}
+	var event struct {
+		PullRequest struct {
+			Head struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+	}
+	if json.Unmarshal(data, &event) != nil {
+		return ""
+	}
+	return event.PullRequest.Head.SHA
+}
+
+// publish sets the commit status for context
+func (g *githubStatus) publish(statusContext string, state string, description string) error {
+	if len(description) > githubMaxDescription {
+		description = description[:githubMaxDescription-3] + "..."
+	}
+	status := map[string]string{
+		"state":       state,
+		"context":     statusContext,
+		"description": description,
+	}
+	if g.targetURL != "" {
+		status["target_url"] = g.targetURL
+	}
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), githubStatusTimeout)
+	defer cancel()
+	path := fmt.Sprintf("/repos/%s/statuses/%s", g.repo, g.sha)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.api+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("POST %s: %s: %s", path, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// stepContext is the status context of a step
+func stepContext(step int) string {
+	return fmt.Sprintf("%s / step %d", githubStatusContext, step)
+}
+
+// publishStatus sets a commit status, recording a warning if it can't be
+// published. It does nothing unless --github-status is on.
+func (r *Runner) publishStatus(statusContext string, state string, description string) {
+	if r.github == nil {
+		return
+	}
+	if err := r.github.publish(statusContext, state, description); err != nil {
+		r.warnings.Add(WarningGitHub, "could not publish a commit status: %v", err)
+	}
+}
+
+// publishStepStart reports a step's attempt as running, and the run's
+// progress so far
+func (r *Runner) publishStepStart(p *plan.Plan, step *plan.Step) {
+	if r.github == nil {
+		return
+	}
+	r.publishStatus(stepContext(step.Number), githubPending,
+		fmt.Sprintf("Attempt %d of %d running: %s", step.RetryCount+1, r.maxRetries(step), step.Description))
+	r.publishStatus(githubStatusContext, githubPending,
+		fmt.Sprintf("%s; running step %d", progressSummary(p), step.Number))
+}
+
+// publishStepResult reports the outcome of a step's attempt. A failed
+// attempt with retries left stays pending.
+func (r *Runner) publishStepResult(step *plan.Step, result plan.StepResult) {
+	if r.github == nil {
+		return
+	}
+	state, description := githubSuccess, "Completed: "+step.Description
+	switch {
+	case result.Status == plan.StatusSkipped:
+		state, description = githubFailure, fmt.Sprintf("Skipped after %d failed attempts", result.RetryCount)
+	case result.Success:
+	case result.RetryCount < r.maxRetries(step):
+		state, description = githubPending, fmt.Sprintf("Attempt %d failed, will retry: %s", result.RetryCount, result.Reason)
+	default:
+		state, description = githubFailure, "Failed: "+result.Reason
+	}
+	r.publishStatus(stepContext(step.Number), state, description)
+}
+
+// publishRunEnd reports how the run ended: success once every step is
+// complete, failure when steps were skipped or can never run, error when
+// the run failed, and pending when it stopped with work left
+func (r *Runner) publishRunEnd(runErr error) {
+	if r.github == nil {
+		return
+	}
+	p, err := r.parsePlan()
+	if err != nil {
+		r.publishStatus(githubStatusContext, githubError, "Run ended: "+err.Error())
+		return
+	}
+	summary := progressSummary(p)
+	switch {
+	case p.IsComplete() && !hasSkipped(p):
+		r.publishStatus(githubStatusContext, githubSuccess, summary)
+	case runErr != nil && !errors.Is(runErr, context.Canceled):
+		r.publishStatus(githubStatusContext, githubError, fmt.Sprintf("%s; run failed: %v", summary, runErr))
+	case p.NextStep() == nil:
+		r.publishStatus(githubStatusContext, githubFailure, summary)
+	default:
+		r.publishStatus(githubStatusContext, githubPending, summary+"; stopped with steps left")
+	}
+}
+
+// progressSummary describes a plan's progress, e.g. "3 of 7 steps
+// completed, 1 skipped"
+func progressSummary(p *plan.Plan) string {
+	completed, skipped := 0, 0
+	for _, step := range p.Steps {
+		switch step.Status {
+		case plan.StatusCompleted:
+			completed++
+		case plan.StatusSkipped:
+			skipped++
+		}
+	}
+	summary := fmt.Sprintf("%d of %d steps completed", completed, len(p.Steps))
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	return summary
+}
+
+// hasSkipped reports whether any step was skipped
+func hasSkipped(p *plan.Plan) bool {
+	for _, step := range p.Steps {
+		if step.Status == plan.StatusSkipped {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package loop
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// parsePlan reads the plan and adds the configured glossary file to its
+// Glossary section. Both are read fresh for every step, so edits made
+// mid-run apply to the next prompt.
+func (r *Runner) parsePlan() (*plan.Plan, error) {
+	p, err := plan.ParseFile(r.planPath)
+	if err != nil {
+		return nil, err
+	}
+	if r.config.Glossary == "" {
+		return p, nil
+	}
+	content, err := os.ReadFile(r.config.Glossary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary: %w", err)
+	}
+	if terms := strings.TrimSpace(string(content)); terms != "" {
+		p.Glossary = strings.TrimSpace(p.Glossary + "\n\n" + terms)
+	}
+	return p, nil
+}
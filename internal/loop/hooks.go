@@ -0,0 +1,109 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Hook names, as used in the config file and passed as RALPH_HOOK
+const (
+	HookPreStep    = "pre_step"
+	HookPostStep   = "post_step"
+	HookOnFailure  = "on_failure"
+	HookOnComplete = "on_complete"
+)
+
+// DefaultHookTimeout bounds each hook command unless configured otherwise
+const DefaultHookTimeout = 5 * time.Minute
+
+// hookFailed is the failure class of an attempt whose pre_step hook failed
+const hookFailed = "Hook pre_step failed"
+
+// Hooks are shell commands run around steps. Empty commands are not run.
+type Hooks struct {
+	PreStep    string        // Before each attempt; a failure fails the attempt
+	PostStep   string        // After each attempt, whatever its outcome
+	OnFailure  string        // After an attempt fails
+	OnComplete string        // After a step completes
+	Timeout    time.Duration // Limit for each command (default: DefaultHookTimeout)
+}
+
+// runHook runs a hook command through the platform shell, streaming its
+// output. The step's details are passed in RALPH_* variables; result is nil
+// before the attempt.
+func (r *Runner) runHook(ctx context.Context, name string, command string, step *plan.Step, a agent.Agent, result *plan.StepResult, elapsed time.Duration) error {
+	if command == "" {
+		return nil
+	}
+	timeout := r.config.Hooks.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	fmt.Printf("\n=== Running %s hook: %s ===\n", name, command)
+	cmd := shellCommand(hookCtx, command)
+	cmd.Stdin = nil
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stdout
+	cmd.Env = append(os.Environ(), r.hookEnv(name, step, a, result, elapsed)...)
+	err := cmd.Run()
+	if hookCtx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+	return err
+}
+
+// hookEnv describes the step and, after an attempt, its outcome
+func (r *Runner) hookEnv(name string, step *plan.Step, a agent.Agent, result *plan.StepResult, elapsed time.Duration) []string {
+	model := step.Model
+	if model == "" {
+		model = r.config.Model
+	}
+	env := []string{
+		"RALPH_HOOK=" + name,
+		"RALPH_PLAN=" + r.planPath,
+		"RALPH_RUN_ID=" + r.runID,
+		fmt.Sprintf("RALPH_STEP=%d", step.Number),
+		"RALPH_STEP_DESCRIPTION=" + step.Description,
+		fmt.Sprintf("RALPH_ATTEMPT=%d", step.RetryCount+1),
+		"RALPH_AGENT=" + a.Name(),
+		"RALPH_MODEL=" + model,
+		"RALPH_TICKET=" + step.Ticket,
+		"RALPH_TAGS=" + strings.Join(step.Tags, ","),
+	}
+	if result == nil {
+		return env
+	}
+	status := plan.StatusCompleted
+	if !result.Success {
+		status = plan.StatusFailed
+	}
+	return append(env,
+		"RALPH_STATUS="+string(status),
+		"RALPH_REASON="+result.Reason,
+		fmt.Sprintf("RALPH_DURATION_SECONDS=%d", int(elapsed.Seconds())),
+		"RALPH_TRANSCRIPT="+result.Transcript,
+	)
+}
+
+// afterAttempt runs the post_step hook, then on_complete or on_failure.
+// Their failures are only warnings: the attempt's outcome is recorded.
+func (r *Runner) afterAttempt(ctx context.Context, step *plan.Step, a agent.Agent, result plan.StepResult, elapsed time.Duration) {
+	name, command := HookOnFailure, r.config.Hooks.OnFailure
+	if result.Success {
+		name, command = HookOnComplete, r.config.Hooks.OnComplete
+	}
+	for _, hook := range [][2]string{{HookPostStep, r.config.Hooks.PostStep}, {name, command}} {
+		if err := r.runHook(ctx, hook[0], hook[1], step, a, &result, elapsed); err != nil && ctx.Err() == nil {
+			r.warnings.Add(WarningHook, "%s hook failed: %v", hook[0], err)
+		}
+	}
+}
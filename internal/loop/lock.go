@@ -0,0 +1,127 @@
+package loop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+)
+
+// LockInfo identifies the loop holding a plan's lock
+type LockInfo struct {
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// IsAlive reports whether the loop holding the lock is still running. A
+// lock taken on another machine is assumed to be.
+func (l *LockInfo) IsAlive() bool {
+	if hostname, _ := os.Hostname(); l.Hostname != "" && l.Hostname != hostname {
+		return true
+	}
+	return processAlive(l.PID)
+}
+
+// planLock is a lock held by this process
+type planLock struct {
+	path string
+}
+
+// LockPath returns the lock file location for a plan:
+// .ralph-loop/locks/<plan name>.lock next to the plan file
+func LockPath(planPath string) string {
+	name := strings.TrimSuffix(filepath.Base(planPath), filepath.Ext(planPath))
+	return layout.ForPlan(planPath).Path(layout.Locks, name+".lock")
+}
+
+// ReadLock reads the lock of a plan. It returns nil with no error when the
+// plan isn't locked.
+func ReadLock(planPath string) (*LockInfo, error) {
+	content, err := os.ReadFile(LockPath(planPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	var info LockInfo
+	if err := json.Unmarshal(content, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", LockPath(planPath), err)
+	}
+	return &info, nil
+}
+
+// acquireLock takes the plan's lock so no other loop runs it at the same
+// time. A lock left by a loop that is no longer running is taken over; one
+// held by a live loop only with force.
+func acquireLock(planPath string, force bool) (*planLock, error) {
+	path := LockPath(planPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	content, err := json.MarshalIndent(LockInfo{PID: os.Getpid(), Hostname: hostname, StartedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lock: %w", err)
+	}
+
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = file.Write(content)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock file: %w", err)
+			}
+			return &planLock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		holder, err := ReadLock(planPath)
+		switch {
+		case err != nil && !force:
+			return nil, fmt.Errorf("%w; remove it or use --force if no loop is running this plan", err)
+		case err != nil:
+			fmt.Println("Removing an unreadable lock file (--force)")
+		case holder == nil:
+			// Released in the meantime
+			continue
+		case holder.IsAlive() && !force:
+			return nil, fmt.Errorf("another loop is running this plan (PID %d on %s, started %s); wait for it to finish, or use --force if it is no longer running",
+				holder.PID, holder.Hostname, holder.StartedAt.Local().Format("2006-01-02 15:04:05"))
+		case holder.IsAlive():
+			fmt.Printf("Taking over the lock of PID %d on %s (--force)\n", holder.PID, holder.Hostname)
+		default:
+			fmt.Printf("Removing a stale lock left by PID %d, which is no longer running\n", holder.PID)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove lock file: %w", err)
+		}
+	}
+}
+
+// release removes the lock, unless another loop has taken it over since
+func (l *planLock) release() {
+	content, err := os.ReadFile(l.path)
+	if err != nil {
+		return
+	}
+	var info LockInfo
+	if json.Unmarshal(content, &info) == nil && info.PID != os.Getpid() {
+		return
+	}
+	os.Remove(l.path)
+}
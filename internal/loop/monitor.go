@@ -0,0 +1,234 @@
+package loop
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutputHandler inspects agent output as it streams. Each handler sees
+// every line in the order handlers were added, in one pass shared by all
+// of them, with the monitor's lock held.
+type OutputHandler interface {
+	// HandleLine is called for each non-empty line, trimmed. Returning
+	// false consumes the line: later handlers don't see it, and it isn't
+	// shown as context.
+	HandleLine(m *OutputMonitor, line string) bool
+}
+
+// runHandler is implemented by handlers with state per watched agent run
+type runHandler interface {
+	StartRun(m *OutputMonitor)
+}
+
+// tickHandler is implemented by handlers that act on time passing, e.g. on
+// silence
+type tickHandler interface {
+	Tick(m *OutputMonitor, now time.Time)
+}
+
+// OutputMonitor wraps an io.Writer and passes everything written to it
+// through a pipeline of handlers, such as prompt and stall detection
+type OutputMonitor struct {
+	writer      io.Writer
+	mu          sync.Mutex
+	handlers    []OutputHandler
+	recentLines []string // Buffer of recent lines for context
+	lastLineAt  time.Time
+	alerted     bool        // A warning box was shown during this run
+	watching    bool        // An agent run is in progress
+	step        int         // Step of the watched run; 0 for other agent runs
+	stop        func(error) // Ends the watched run early, with the reason
+	ticker      *time.Ticker
+	done        chan struct{}
+	warnings    *WarningCollector // Records warnings for the end-of-run summary
+}
+
+const (
+	maxRecentLines      = 10              // Keep last 10 lines for context
+	monitorTickInterval = 5 * time.Second // How often tick handlers run
+	boxWidth            = 74              // Inner width of warning boxes
+)
+
+// NewOutputMonitor creates an OutputMonitor wrapping the given writer.
+// Handlers record what they detect in warnings.
+func NewOutputMonitor(w io.Writer, warnings *WarningCollector, handlers ...OutputHandler) *OutputMonitor {
+	m := &OutputMonitor{
+		writer:   w,
+		handlers: handlers,
+		done:     make(chan struct{}),
+		warnings: warnings,
+	}
+
+	// Start a background goroutine for time-based handlers
+	m.ticker = time.NewTicker(monitorTickInterval)
+	go m.tick()
+
+	return m
+}
+
+// Write implements io.Writer
+func (m *OutputMonitor) Write(p []byte) (n int, err error) {
+	// Write to underlying writer first
+	n, err = m.writer.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, line := range strings.Split(string(p), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m.lastLineAt = time.Now()
+		m.handleLine(line)
+	}
+
+	return n, nil
+}
+
+// handleLine keeps a line as context and runs it through the handlers.
+// Lines a handler consumes are dropped from the context again.
+func (m *OutputMonitor) handleLine(line string) {
+	m.recentLines = append(m.recentLines, line)
+	trimmed := strings.TrimSpace(line)
+	for _, h := range m.handlers {
+		if !h.HandleLine(m, trimmed) {
+			m.recentLines = m.recentLines[:len(m.recentLines)-1]
+			break
+		}
+	}
+	if len(m.recentLines) > maxRecentLines {
+		m.recentLines = m.recentLines[1:]
+	}
+}
+
+// tick runs the tick handlers until the monitor is closed
+func (m *OutputMonitor) tick() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-m.ticker.C:
+			m.mu.Lock()
+			for _, h := range m.handlers {
+				if t, ok := h.(tickHandler); ok {
+					t.Tick(m, now)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// Close stops the background monitoring
+func (m *OutputMonitor) Close() {
+	m.ticker.Stop()
+	close(m.done)
+}
+
+// Watch clears the per-run state and starts monitoring an agent run.
+// step labels what handlers report (0 for runs outside a step); stop
+// ends the run early with the reason, e.g. when it stalls, and may be nil.
+func (m *OutputMonitor) Watch(step int, stop func(cause error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alerted = false
+	m.recentLines = nil
+	m.lastLineAt = time.Now()
+	m.watching = true
+	m.step = step
+	m.stop = stop
+	for _, h := range m.handlers {
+		if r, ok := h.(runHandler); ok {
+			r.StartRun(m)
+		}
+	}
+}
+
+// Unwatch ends monitoring once the agent run has finished, so time spent
+// verifying or waiting between steps doesn't count as silence
+func (m *OutputMonitor) Unwatch() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watching = false
+	m.stop = nil
+}
+
+// Silence returns how long the agent has gone without output
+func (m *OutputMonitor) Silence(now time.Time) time.Duration {
+	return now.Sub(m.lastLineAt)
+}
+
+// lastLine returns the most recent output line, or ""
+func (m *OutputMonitor) lastLine() string {
+	if len(m.recentLines) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(m.recentLines[len(m.recentLines)-1])
+}
+
+// showBox displays a warning box: a heading, explanation lines, and the
+// agent's recent output under contextLabel
+func (m *OutputMonitor) showBox(heading string, lines []string, contextLabel string) {
+	border := strings.Repeat("═", boxWidth)
+	fmt.Fprintln(m.writer, "")
+	fmt.Fprintf(m.writer, "╔%s╗\n", border)
+	for _, line := range append([]string{heading}, lines...) {
+		fmt.Fprintf(m.writer, "║  %s║\n", formatBoxLine(line, boxWidth-2))
+	}
+	fmt.Fprintf(m.writer, "╠%s╣\n", border)
+	fmt.Fprintf(m.writer, "║  %s║\n", formatBoxLine(contextLabel, boxWidth-2))
+	fmt.Fprintf(m.writer, "╟%s╢\n", strings.Repeat("─", boxWidth))
+
+	// Show recent lines as context
+	for _, line := range m.recentContext() {
+		fmt.Fprintf(m.writer, "║  %s║\n", formatBoxLine(line, boxWidth-2))
+	}
+
+	fmt.Fprintf(m.writer, "╚%s╝\n", border)
+	fmt.Fprintln(m.writer, "")
+}
+
+// recentContext returns the last few non-empty output lines
+func (m *OutputMonitor) recentContext() []string {
+	// Get the last few lines, filtering out empty ones and limiting context
+	var context []string
+	startIdx := max(len(m.recentLines)-5, 0) // Last 5 lines max
+	for _, line := range m.recentLines[startIdx:] {
+		if line = strings.TrimSpace(line); line != "" {
+			context = append(context, line)
+		}
+	}
+
+	if len(context) == 0 {
+		return []string{"(no context available)"}
+	}
+	return context
+}
+
+// formatBoxLine pads or truncates a line to fit in a box of given width
+func formatBoxLine(line string, width int) string {
+	// Remove any ANSI escape codes for length calculation
+	cleanLine := stripAnsi(line)
+
+	if len(cleanLine) > width {
+		return cleanLine[:width-3] + "..."
+	}
+
+	// Pad with spaces
+	padding := width - len(cleanLine)
+	return line + strings.Repeat(" ", padding)
+}
+
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// stripAnsi removes ANSI escape codes from a string
+func stripAnsi(s string) string {
+	return ansiRegex.ReplaceAllString(s, "")
+}
@@ -0,0 +1,46 @@
+package loop
+
+import (
+	"fmt"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// What the loop does after a step's attempt fails
+const (
+	OnFailureRetry    = "retry"    // Retry the step, as --order picks it, before moving on
+	OnFailureContinue = "continue" // Park the step and move on, retrying it once nothing else can run
+)
+
+// DefaultOnFailure is the on-failure mode used when none is configured
+const DefaultOnFailure = OnFailureRetry
+
+// OnFailureModes returns the valid on-failure modes
+func OnFailureModes() []string {
+	return []string{OnFailureRetry, OnFailureContinue}
+}
+
+// parkFailures wraps a strategy so that steps that failed in this run wait
+// while other steps can run. When nothing else can, the parked steps are
+// released and retried, each once, before any of them gets another turn.
+func (r *Runner) parkFailures(next plan.OrderStrategy) plan.OrderStrategy {
+	unparked := plan.Only(next, func(s *plan.Step) bool { return !r.parked[s.Number] })
+	return func(p *plan.Plan) *plan.Step {
+		if step := unparked(p); step != nil || len(r.parked) == 0 {
+			return step
+		}
+		fmt.Printf("\n=== Revisiting %d parked step(s) ===\n", len(r.parked))
+		r.parked = make(map[int]bool)
+		return unparked(p)
+	}
+}
+
+// parkStep parks a step whose attempt failed, with --on-failure continue,
+// unless it has run out of retries
+func (r *Runner) parkStep(step *plan.Step, result plan.StepResult) {
+	if r.config.OnFailure != OnFailureContinue || result.Success || result.RetryCount >= r.maxRetries(step) {
+		return
+	}
+	r.parked[step.Number] = true
+	fmt.Println("Parking the step until the other runnable steps have had their turn.")
+}
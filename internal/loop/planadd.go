@@ -0,0 +1,102 @@
+package loop
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+	"github.com/eraldohasanaj/ralph-loop/internal/prompt"
+)
+
+// DefaultMaxPlanAdds is how many steps agents may append to the plan in one
+// run unless configured otherwise
+const DefaultMaxPlanAdds = 5
+
+// planAddsLeft returns how many more steps agents may append to the plan in
+// this run. A frozen plan takes none.
+func (r *Runner) planAddsLeft() int {
+	left := r.config.MaxPlanAdds - r.planAdds
+	if left <= 0 {
+		return 0
+	}
+	if content, err := os.ReadFile(r.planPath); err == nil && plan.IsFrozen(string(content)) {
+		return 0
+	}
+	return left
+}
+
+// addDiscoveredSteps appends the steps the agent asked for with PLAN_ADD
+// lines to the plan, up to the run's cap. Each new step notes which step
+// and run added it. Requests over the cap, or to a frozen plan, are
+// reported and dropped.
+func (r *Runner) addDiscoveredSteps(step *plan.Step, output string, attemptID string) {
+	descriptions := prompt.ParsePlanAdds(output)
+	if len(descriptions) == 0 {
+		return
+	}
+	if echoed := prompt.EchoedRunID(output); echoed != "" && attemptID != "" && echoed != attemptID {
+		r.warnings.Add(WarningPlanAdd, "ignored %d PLAN_ADD line(s) from output tagged with run ID %s", len(descriptions), echoed)
+		return
+	}
+
+	content, err := os.ReadFile(r.planPath)
+	if err != nil {
+		r.warnings.Add(WarningPlanAdd, "failed to add the steps the agent asked for: %v", err)
+		return
+	}
+	if plan.IsFrozen(string(content)) {
+		r.warnings.Add(WarningPlanAdd, "the agent asked to add %d step(s), but the plan is frozen", len(descriptions))
+		return
+	}
+
+	// Steps the plan already has don't count against the cap
+	if p, err := plan.Parse(string(content)); err == nil {
+		var fresh []string
+		for _, desc := range descriptions {
+			if !p.HasStep(desc) {
+				fresh = append(fresh, desc)
+			}
+		}
+		descriptions = fresh
+	}
+	if len(descriptions) == 0 {
+		return
+	}
+
+	if r.config.MaxPlanAdds == 0 {
+		r.warnings.Add(WarningPlanAdd, "ignored %d step(s) the agent asked to add: --max-plan-adds is 0", len(descriptions))
+		return
+	}
+	left := max(r.config.MaxPlanAdds-r.planAdds, 0)
+	if len(descriptions) > left {
+		dropped := descriptions[left:]
+		descriptions = descriptions[:left]
+		r.warnings.Add(WarningPlanAdd, "dropped %d step(s) the agent asked to add, over the cap of %d per run: %s",
+			len(dropped), r.config.MaxPlanAdds, strings.Join(dropped, "; "))
+	}
+	if len(descriptions) == 0 {
+		return
+	}
+
+	addedBy := fmt.Sprintf("agent, while running Step %d", step.Number)
+	if r.runID != "" {
+		addedBy += fmt.Sprintf(" (run %s)", r.runID)
+	}
+	addedBy += ", " + time.Now().Format("2006-01-02 15:04")
+	added, err := plan.AppendDiscoveredSteps(r.planPath, descriptions, addedBy)
+	if err != nil {
+		r.warnings.Add(WarningPlanAdd, "failed to add the steps the agent asked for: %v", err)
+		return
+	}
+	if len(added) == 0 {
+		return
+	}
+	r.planAdds += len(added)
+
+	fmt.Printf("\n=== Step %d added %d step(s) to the plan ===\n", step.Number, len(added))
+	for _, s := range added {
+		fmt.Printf("  Step %d: %s\n", s.Number, s.Description)
+	}
+}
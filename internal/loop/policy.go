@@ -0,0 +1,86 @@
+package loop
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultDenylist matches commands destructive enough that an attempt
+// running one is stopped: deleting the root or home directory, force
+// pushes, dropping databases, formatting or overwriting disks, and fork
+// bombs
+var DefaultDenylist = []string{
+	`\brm\s+(-[a-zA-Z]*\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\s+(-[a-zA-Z]+\s+)*["']?(/|/\*|~|~/|~/\*|\$HOME|\$HOME/|\$HOME/\*)(\s|[;&|'"]|$)`,
+	`\bgit\s+push\s+(.*\s)?(--force|-f)(\s|$)`,
+	`(?i)\bdrop\s+(database|schema)\b`,
+	`\bmkfs(\.\w+)?\s`,
+	`\bdd\s+(.*\s)?of=/dev/`,
+	`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`,
+}
+
+// policyViolation is the failure class of attempts stopped for running a
+// denied command
+const policyViolation = "Policy violation"
+
+// PolicyViolation is the cancellation cause of an attempt whose output
+// matched the denylist
+type PolicyViolation struct {
+	Pattern string // The denylist pattern that matched
+	Line    string // The offending output line
+}
+
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("agent ran a denied command: %s", v.Line)
+}
+
+// CompileDenylist compiles denylist patterns, reporting the first invalid one
+func CompileDenylist(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid denylist pattern %q: %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// policyHandler stops the watched run as soon as its output shows a
+// command on the denylist. It is a last line of defense for unattended
+// runs, not a sandbox: the command has usually started by the time it is
+// printed.
+type policyHandler struct {
+	denylist []*regexp.Regexp
+	tripped  bool // The watched run has already been stopped
+}
+
+func newPolicyHandler(denylist []*regexp.Regexp) *policyHandler {
+	return &policyHandler{denylist: denylist}
+}
+
+func (h *policyHandler) StartRun(m *OutputMonitor) {
+	h.tripped = false
+}
+
+func (h *policyHandler) HandleLine(m *OutputMonitor, line string) bool {
+	if !m.watching || h.tripped {
+		return true
+	}
+	for _, re := range h.denylist {
+		if !re.MatchString(line) {
+			continue
+		}
+		h.tripped = true
+		m.warnings.Add(WarningPolicy, "stopped the attempt: output matched the denylist: %s", line)
+		m.showBox("STOPPING: The agent ran a command on the denylist.", []string{
+			"The attempt is being stopped and will count as a failure.",
+			"Check the working tree: the command may already have run.",
+		}, "OUTPUT:")
+		if m.stop != nil {
+			m.stop(&PolicyViolation{Pattern: re.String(), Line: line})
+		}
+		break
+	}
+	return true
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package loop
+
+import (
+	"os"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given PID exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Signal 0 checks for existence without affecting the process
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
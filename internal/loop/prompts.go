@@ -0,0 +1,71 @@
+package loop
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Agents prefix non-fatal problems with this so they are collected as warnings
+const agentWarningPrefix = "[ralph-loop] warning:"
+
+// Common patterns that indicate the agent is waiting for user input
+var promptPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\[y/n\]`),
+	regexp.MustCompile(`(?i)\[yes/no\]`),
+	regexp.MustCompile(`(?i)press enter`),
+	regexp.MustCompile(`(?i)press any key`),
+	regexp.MustCompile(`(?i)do you want to (continue|proceed|confirm)`),
+	regexp.MustCompile(`(?i)would you like to`),
+	regexp.MustCompile(`(?i)are you sure`),
+	regexp.MustCompile(`(?i)confirm\?`),
+	regexp.MustCompile(`(?i)proceed\?`),
+	regexp.MustCompile(`(?i)continue\?`),
+	regexp.MustCompile(`^\s*\?\s+`), // inquirer-style "? " prompts
+	regexp.MustCompile(`(?i)waiting for (input|response|confirmation)`),
+	regexp.MustCompile(`(?i)enter .* to continue`),
+	regexp.MustCompile(`(?i)type .* to confirm`),
+}
+
+// agentWarningHandler collects the warnings agents print with
+// agentWarningPrefix, and hides those lines from later handlers
+type agentWarningHandler struct{}
+
+func (agentWarningHandler) HandleLine(m *OutputMonitor, line string) bool {
+	if !strings.HasPrefix(line, agentWarningPrefix) {
+		return true
+	}
+	m.warnings.Add(WarningAgent, "%s", strings.TrimSpace(strings.TrimPrefix(line, agentWarningPrefix)))
+	return false
+}
+
+// promptHandler warns when the agent appears to ask for user input
+type promptHandler struct {
+	onPrompt func(step int, question string) // Also told about the question, if set; must not block
+}
+
+func (h promptHandler) HandleLine(m *OutputMonitor, line string) bool {
+	if !matchesPromptPattern(line) || m.alerted {
+		return true // Only warn once per run
+	}
+	m.alerted = true
+	m.warnings.Add(WarningPrompt, "agent appeared to ask for input: %q", line)
+	if h.onPrompt != nil && m.step > 0 {
+		h.onPrompt(m.step, strings.TrimSpace(line))
+	}
+
+	m.showBox("WARNING: Agent is asking for user input!", []string{
+		"The agent should be running autonomously without prompts.",
+		"Press Ctrl+C to cancel - the step will be retried automatically.",
+	}, "AGENT IS ASKING:")
+	return true
+}
+
+// matchesPromptPattern checks if a line matches any known prompt pattern
+func matchesPromptPattern(line string) bool {
+	for _, pattern := range promptPatterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
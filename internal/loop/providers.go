@@ -0,0 +1,163 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+	"github.com/eraldohasanaj/ralph-loop/internal/prompt"
+)
+
+// ContextSource enables a built-in context provider, with an optional
+// budget for its share of the prompt
+type ContextSource struct {
+	Name      string // One of ContextProviderNames
+	MaxTokens int    // Approximate limit on the provider's output (0: no limit)
+}
+
+// Files agents commonly take instructions from, in the order they're
+// included
+var instructionFiles = []string{"AGENTS.md", "CLAUDE.md", "GEMINI.md", ".github/copilot-instructions.md", "CONTRIBUTING.md"}
+
+// hintFileLimit caps each file the file-hints provider includes
+const hintFileLimit = 32 * 1024
+
+// pathHintRegex matches words in a step description that look like file
+// paths: they contain a slash or end in an extension
+var pathHintRegex = regexp.MustCompile("[A-Za-z0-9_.@-]*(?:/[A-Za-z0-9_.@-]+)+|[A-Za-z0-9_@-][A-Za-z0-9_.@-]*\\.[A-Za-z0-9]+")
+
+// builtinProviders creates the built-in providers for a run, by name
+var builtinProviders = map[string]func(r *Runner) prompt.ContextProvider{
+	"git-diff":     func(r *Runner) prompt.ContextProvider { return gitDiffProvider{dir: filepath.Dir(r.planPath)} },
+	"file-hints":   func(r *Runner) prompt.ContextProvider { return fileHintsProvider{dir: filepath.Dir(r.planPath)} },
+	"instructions": func(r *Runner) prompt.ContextProvider { return instructionsProvider{dir: filepath.Dir(r.planPath)} },
+	"test-output":  func(r *Runner) prompt.ContextProvider { return testOutputProvider{outputs: r.verifyOutputs} },
+}
+
+// ContextProviderNames lists the built-in context providers, sorted
+func ContextProviderNames() []string {
+	var names []string
+	for name := range builtinProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckContextSource reports whether a source names a built-in provider
+// and has a usable budget
+func CheckContextSource(source ContextSource) error {
+	if _, ok := builtinProviders[source.Name]; !ok {
+		return fmt.Errorf("unknown context provider %q (valid: %s)", source.Name, strings.Join(ContextProviderNames(), ", "))
+	}
+	if source.MaxTokens < 0 {
+		return fmt.Errorf("context provider %s: max_tokens must not be negative", source.Name)
+	}
+	return nil
+}
+
+// contextProviders creates the configured providers in order
+func (r *Runner) contextProviders() ([]prompt.ContextProvider, error) {
+	var providers []prompt.ContextProvider
+	for _, source := range r.config.ContextProviders {
+		if err := CheckContextSource(source); err != nil {
+			return nil, err
+		}
+		providers = append(providers, prompt.WithBudget(builtinProviders[source.Name](r), source.MaxTokens))
+	}
+	return providers, nil
+}
+
+// gitDiffProvider shows the uncommitted changes in the working tree, which
+// include the work of earlier steps in this run
+type gitDiffProvider struct {
+	dir string
+}
+
+func (gitDiffProvider) Name() string { return "git-diff" }
+
+func (g gitDiffProvider) Collect(ctx context.Context, step *plan.Step) (string, error) {
+	status := gitOutput(g.dir, "status", "--short")
+	if status == "" {
+		return "", nil
+	}
+	return "# git status --short\n" + status + "\n\n" + gitOutput(g.dir, "diff", "HEAD"), nil
+}
+
+// fileHintsProvider includes the files a step's description mentions
+type fileHintsProvider struct {
+	dir string
+}
+
+func (fileHintsProvider) Name() string { return "file-hints" }
+
+func (f fileHintsProvider) Collect(ctx context.Context, step *plan.Step) (string, error) {
+	var sb strings.Builder
+	seen := make(map[string]bool)
+	for _, hint := range pathHintRegex.FindAllString(step.Description, -1) {
+		hint = strings.TrimRight(hint, ".")
+		if seen[hint] || filepath.IsAbs(hint) || strings.HasPrefix(filepath.Clean(hint), "..") {
+			continue
+		}
+		seen[hint] = true
+		path := filepath.Join(f.dir, hint)
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if len(content) > hintFileLimit {
+			content = append(content[:hintFileLimit:hintFileLimit], "\n[... file truncated]"...)
+		}
+		sb.WriteString(fmt.Sprintf("==> %s <==\n%s\n\n", hint, strings.TrimRight(string(content), "\n")))
+	}
+	return sb.String(), nil
+}
+
+// instructionsProvider includes the project's agent instruction files, for
+// agents that don't read them on their own
+type instructionsProvider struct {
+	dir string
+}
+
+func (instructionsProvider) Name() string { return "instructions" }
+
+func (i instructionsProvider) Collect(ctx context.Context, step *plan.Step) (string, error) {
+	var sb strings.Builder
+	for _, name := range instructionFiles {
+		content, err := os.ReadFile(filepath.Join(i.dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if text := strings.TrimSpace(string(content)); text != "" {
+			sb.WriteString(fmt.Sprintf("==> %s <==\n%s\n\n", name, text))
+		}
+	}
+	return sb.String(), nil
+}
+
+// testOutputProvider includes the output of the step's last failed
+// verification in this run, so a retry sees exactly what broke
+type testOutputProvider struct {
+	outputs map[int]string
+}
+
+func (testOutputProvider) Name() string { return "test-output" }
+
+func (t testOutputProvider) Collect(ctx context.Context, step *plan.Step) (string, error) {
+	if step.Status != plan.StatusFailed {
+		return "", nil
+	}
+	return t.outputs[step.Number], nil
+}
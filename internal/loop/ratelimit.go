@@ -0,0 +1,77 @@
+package loop
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	rateLimitTail      = 4 * 1024         // Only the end of the output is searched, where CLIs report errors
+	rateLimitMaxWaits  = 5                // Consecutive rate-limited attempts before one counts as a failure
+	rateLimitBaseDelay = 30 * time.Second // Wait when the error gives no advice; doubles per consecutive hit
+	rateLimitMaxDelay  = 10 * time.Minute
+)
+
+// rateLimitPatterns match API errors that mean "try again later" rather
+// than a problem with the step
+var rateLimitPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(?:status|error|code|http)\W{0,3}(?:code\W{0,3})?429\b`),
+	regexp.MustCompile(`(?i)\btoo many requests\b`),
+	regexp.MustCompile(`(?i)\brate[ _-]?limit(?:ed|_error| exceeded| reached)\b`),
+	regexp.MustCompile(`(?i)\b(?:overloaded_error|resource_exhausted)\b`),
+	regexp.MustCompile(`(?i)\b(?:api|server|model) (?:is )?(?:currently )?overloaded\b`),
+}
+
+// retryAfterPattern captures advice like "retry after 30 seconds",
+// "retry-after: 12" or "try again in 2 minutes"
+var retryAfterPattern = regexp.MustCompile(`(?i)(?:retry[ -]after|try again in)\W{0,3}(\d+(?:\.\d+)?)\s*(ms|milliseconds?|s|secs?|seconds?|m|mins?|minutes?|h|hours?)?\b`)
+
+// detectRateLimit reports whether an attempt's output ends with a rate-limit
+// or overload error, and the wait the error advised (0 if none)
+func detectRateLimit(output string) (bool, time.Duration) {
+	if len(output) > rateLimitTail {
+		output = output[len(output)-rateLimitTail:]
+	}
+	limited := false
+	for _, pattern := range rateLimitPatterns {
+		if pattern.MatchString(output) {
+			limited = true
+			break
+		}
+	}
+	if !limited {
+		return false, 0
+	}
+
+	match := retryAfterPattern.FindStringSubmatch(output)
+	if match == nil {
+		return true, 0
+	}
+	n, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return true, 0
+	}
+	unit := time.Second
+	switch strings.ToLower(match[2]) {
+	case "ms", "millisecond", "milliseconds":
+		unit = time.Millisecond
+	case "m", "min", "mins", "minute", "minutes":
+		unit = time.Minute
+	case "h", "hour", "hours":
+		unit = time.Hour
+	}
+	return true, time.Duration(n * float64(unit))
+}
+
+// rateLimitDelay returns how long to wait after the nth consecutive
+// rate-limited attempt: the advised period if there is one, otherwise an
+// exponential backoff. Either is capped at rateLimitMaxDelay.
+func rateLimitDelay(advised time.Duration, n int) time.Duration {
+	delay := advised
+	if delay <= 0 {
+		delay = rateLimitBaseDelay << (n - 1)
+	}
+	return min(delay, rateLimitMaxDelay)
+}
@@ -0,0 +1,239 @@
+package loop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// RunRecordFile is the name of a run's record in its transcripts directory
+const RunRecordFile = "run.json"
+
+// RunRecord summarizes a finished run so it can be compared with others
+// (see `ralph-loop report compare`). It is stored with the run's
+// transcripts as transcripts/<run>/run.json.
+type RunRecord struct {
+	RunID        string          `json:"run_id"`
+	Plan         string          `json:"plan"`
+	Agent        string          `json:"agent"`
+	Model        string          `json:"model,omitempty"`
+	Branch       string          `json:"branch,omitempty"`
+	StartCommit  string          `json:"start_commit,omitempty"`
+	EndCommit    string          `json:"end_commit,omitempty"`
+	StartedAt    time.Time       `json:"started_at"`
+	FinishedAt   time.Time       `json:"finished_at"`
+	Attempts     []AttemptRecord `json:"attempts"`
+	DiffStat     string          `json:"diff_stat,omitempty"`     // git diff --shortstat from StartCommit
+	FilesChanged []string        `json:"files_changed,omitempty"` // Tracked files changed since StartCommit
+	Warnings     []Warning       `json:"warnings,omitempty"`      // Warnings collected so far, as summarized at exit
+}
+
+// AttemptRecord is one agent attempt at a step
+type AttemptRecord struct {
+	Step         int     `json:"step"`
+	Description  string  `json:"description"`
+	Ticket       string  `json:"ticket,omitempty"`
+	Attempt      int     `json:"attempt"`
+	Agent        string  `json:"agent"`
+	Model        string  `json:"model,omitempty"`
+	Success      bool    `json:"success"`
+	Reason       string  `json:"reason,omitempty"`
+	DurationSec  float64 `json:"duration_sec"`
+	InputTokens  int     `json:"input_tokens,omitempty"`
+	OutputTokens int     `json:"output_tokens,omitempty"`
+	CostUSD      float64 `json:"cost_usd,omitempty"`
+}
+
+// Duration returns the run's wall-clock time
+func (rec *RunRecord) Duration() time.Duration {
+	return rec.FinishedAt.Sub(rec.StartedAt)
+}
+
+// StepAttempts groups the attempts by step number, in step order
+func (rec *RunRecord) StepAttempts() (steps []int, attempts map[int][]AttemptRecord) {
+	attempts = make(map[int][]AttemptRecord)
+	for _, a := range rec.Attempts {
+		if _, ok := attempts[a.Step]; !ok {
+			steps = append(steps, a.Step)
+		}
+		attempts[a.Step] = append(attempts[a.Step], a)
+	}
+	sort.Ints(steps)
+	return steps, attempts
+}
+
+// ReadRunRecord reads a run record from a run's transcripts directory or
+// from the run.json file itself
+func ReadRunRecord(path string) (*RunRecord, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, RunRecordFile)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run record: %w", err)
+	}
+	var rec RunRecord
+	if err := json.Unmarshal(content, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse run record %s: %w", path, err)
+	}
+	return &rec, nil
+}
+
+// PlanRuns reads the records of the plan's runs kept in its local
+// transcripts directory, oldest first. Records that can't be read are
+// passed over.
+func PlanRuns(planPath string) ([]*RunRecord, error) {
+	dirs := layout.ForPlan(planPath)
+	if err := dirs.Upgrade(); err != nil {
+		return nil, err
+	}
+	dir := dirs.Path(layout.Logs, "transcripts")
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	var records []*RunRecord
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		rec, err := ReadRunRecord(filepath.Join(dir, entry.Name()))
+		if err != nil || filepath.Base(rec.Plan) != filepath.Base(planPath) {
+			continue
+		}
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].StartedAt.Before(records[j].StartedAt) })
+	return records, nil
+}
+
+// FindRun resolves a run given as a directory, a run.json path, or a run
+// ID (or a unique prefix of the directory name) under the plan's local
+// transcripts directory
+func FindRun(planPath string, run string) (string, error) {
+	if _, err := os.Stat(run); err == nil {
+		return run, nil
+	}
+	dirs := layout.ForPlan(planPath)
+	if err := dirs.Upgrade(); err != nil {
+		return "", err
+	}
+	dir := dirs.Path(layout.Logs, "transcripts")
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to list runs: %w", err)
+	}
+	var matches []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() && (strings.HasPrefix(name, run) || strings.HasSuffix(name, "-"+run)) {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("run %q not found in %s", run, dir)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("run %q is ambiguous: %s", run, strings.Join(matches, ", "))
+}
+
+// startRecord begins the run record
+func (r *Runner) startRecord() {
+	baseDir := filepath.Dir(r.planPath)
+	r.record = &RunRecord{
+		RunID:       r.runID,
+		Plan:        r.planPath,
+		Agent:       r.agent.Name(),
+		Model:       r.config.Model,
+		Branch:      gitOutput(baseDir, "rev-parse", "--abbrev-ref", "HEAD"),
+		StartCommit: gitOutput(baseDir, "rev-parse", "HEAD"),
+		StartedAt:   r.runStartedAt,
+	}
+}
+
+// recordAttempt adds an attempt to the run record and stores the record,
+// so it survives a run that is killed
+func (r *Runner) recordAttempt(step *plan.Step, a agent.Agent, result plan.StepResult, elapsed time.Duration, usage *agent.Usage) {
+	if r.record == nil {
+		return
+	}
+	attempt := AttemptRecord{
+		Step:        step.Number,
+		Description: step.Description,
+		Ticket:      step.Ticket,
+		Attempt:     step.RetryCount + 1,
+		Agent:       a.Name(),
+		Model:       step.Model,
+		Success:     result.Success,
+		Reason:      result.Reason,
+		DurationSec: elapsed.Seconds(),
+	}
+	if attempt.Model == "" {
+		attempt.Model = r.config.Model
+	}
+	if usage != nil {
+		attempt.InputTokens = usage.InputTokens
+		attempt.OutputTokens = usage.OutputTokens
+		attempt.CostUSD = usage.CostUSD
+	}
+	r.record.Attempts = append(r.record.Attempts, attempt)
+	r.record.FinishedAt = time.Now()
+	r.saveRecord()
+}
+
+// attempt describes an attempt for the step's history in the plan
+func (r *Runner) attempt(step *plan.Step, a agent.Agent, startedAt time.Time, elapsed time.Duration) *plan.Attempt {
+	model := step.Model
+	if model == "" {
+		model = r.config.Model
+	}
+	return &plan.Attempt{StartedAt: startedAt, Duration: elapsed, Agent: a.Name(), Model: model}
+}
+
+// finishRecord notes the run's end and the changes it made, then stores
+// the record
+func (r *Runner) finishRecord() {
+	if r.record == nil || len(r.record.Attempts) == 0 {
+		return
+	}
+	baseDir := filepath.Dir(r.planPath)
+	r.record.FinishedAt = time.Now()
+	r.record.EndCommit = gitOutput(baseDir, "rev-parse", "HEAD")
+	if r.record.StartCommit != "" {
+		r.record.DiffStat = strings.TrimSpace(gitOutput(baseDir, "diff", "--shortstat", r.record.StartCommit))
+		if files := gitOutput(baseDir, "diff", "--name-only", r.record.StartCommit); files != "" {
+			r.record.FilesChanged = strings.Split(files, "\n")
+		}
+	}
+	r.saveRecord()
+}
+
+// saveRecord writes the run record, with the warnings collected so far, to
+// the log store
+func (r *Runner) saveRecord() {
+	r.record.Warnings = r.warnings.Warnings()
+	content, err := json.MarshalIndent(r.record, "", "  ")
+	if err != nil {
+		r.warnings.Add(WarningLogs, "failed to encode run record: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), logUploadTimeout)
+	defer cancel()
+
+	store := r.logStore()
+	key := fmt.Sprintf("transcripts/%s/%s", r.runKey(), RunRecordFile)
+	if _, err := store.Write(ctx, key, content); err != nil {
+		r.warnings.Add(WarningLogs, "failed to store run record in %s: %v", store.Name(), err)
+	}
+}
@@ -0,0 +1,73 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+	"github.com/eraldohasanaj/ralph-loop/internal/prompt"
+)
+
+// reviewWithAgent has the reviewer agent check an attempt that reported
+// success, against the attempt's diff and the step's acceptance criteria. A
+// failed review fails the attempt, with the reviewer's feedback as its
+// reason. A reviewer that gives no verdict leaves the result as it is, with
+// a warning.
+func (r *Runner) reviewWithAgent(ctx context.Context, p *plan.Plan, step *plan.Step, result plan.StepResult, before *worktreeSnapshot, monitor *OutputMonitor) plan.StepResult {
+	reviewer, err := r.reviewer()
+	if err != nil {
+		r.warnings.Add(WarningReview, "step was not reviewed: %v", err)
+		return result
+	}
+	diff, err := r.attemptDiff(before)
+	if err != nil {
+		diff = ""
+	}
+
+	fmt.Printf("\n=== Reviewing Step %d with %s ===\n\n", step.Number, reviewer.Name())
+	defer r.setActiveAgent(r.activeAgent())
+	r.setActiveAgent(reviewer)
+	killCtx, stopReview := context.WithCancelCause(ctx)
+	reviewCtx, cancel := context.WithTimeout(killCtx, r.timeout(step))
+	monitor.Watch(step.Number, stopReview)
+	output, err := reviewer.Run(reviewCtx, prompt.BuildReview(p, step, diff), monitor)
+	monitor.Unwatch()
+	cancel()
+	stopReview(nil)
+	if ctx.Err() != nil {
+		return result
+	}
+
+	passed, feedback, ok := prompt.ParseReview(output)
+	switch {
+	case !ok && err != nil:
+		r.warnings.Add(WarningReview, "step was not reviewed: the reviewer failed: %v", err)
+		return result
+	case !ok:
+		r.warnings.Add(WarningReview, "step was not reviewed: the reviewer gave no REVIEW_PASS or REVIEW_FAIL verdict")
+		return result
+	case passed:
+		fmt.Printf("\n=== Step %d passed review ===\n", step.Number)
+		return result
+	}
+
+	if feedback == "" {
+		feedback = "no feedback given"
+	}
+	return plan.StepResult{
+		Success:   false,
+		Output:    result.Output,
+		Reason:    prompt.ReviewFailedPrefix + feedback,
+		SessionID: result.SessionID,
+	}
+}
+
+// reviewer returns the agent that reviews steps: the run's default unless
+// a review agent or model is configured
+func (r *Runner) reviewer() (agent.Agent, error) {
+	if (r.config.ReviewAgent == "" && r.config.ReviewModel == "") || r.agentFactory == nil {
+		return r.agent, nil
+	}
+	return r.agentFactory(r.config.ReviewAgent, r.config.ReviewModel)
+}
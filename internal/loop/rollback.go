@@ -0,0 +1,127 @@
+package loop
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+	"github.com/eraldohasanaj/ralph-loop/internal/prompt"
+)
+
+// worktreeSnapshot is the state of the git working tree before an attempt
+type worktreeSnapshot struct {
+	head string // Commit checked out
+	tree string // Tracked and untracked files, except ignored ones
+}
+
+// snapshotWorktree records the working tree before an attempt, so a failed
+// attempt's edits can be rolled back. The tree is written through a copy of
+// the index, leaving the real index, and what is staged, alone.
+func (r *Runner) snapshotWorktree() (*worktreeSnapshot, error) {
+	return snapshotDir(filepath.Dir(r.planPath))
+}
+
+// snapshotDir records the working tree of the repository holding dir
+func snapshotDir(dir string) (*worktreeSnapshot, error) {
+	head := gitOutput(dir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if head == "" {
+		return nil, fmt.Errorf("not a git repository with at least one commit")
+	}
+
+	index, err := os.CreateTemp("", "ralph-loop-index-*")
+	if err != nil {
+		return nil, err
+	}
+	index.Close()
+	defer os.Remove(index.Name())
+	if current := gitOutput(dir, "rev-parse", "--path-format=absolute", "--git-path", "index"); current != "" {
+		// Starting from the real index spares rehashing unchanged files
+		if content, err := os.ReadFile(current); err == nil {
+			os.WriteFile(index.Name(), content, 0600)
+		}
+	}
+
+	env := "GIT_INDEX_FILE=" + index.Name()
+	if _, err := gitWithEnv(dir, env, "add", "--all", "--", ":/"); err != nil {
+		return nil, err
+	}
+	tree, err := gitWithEnv(dir, env, "write-tree")
+	if err != nil {
+		return nil, err
+	}
+	return &worktreeSnapshot{head: head, tree: strings.TrimSpace(tree)}, nil
+}
+
+// attemptDiff returns how the working tree changed since a snapshot, new
+// untracked files included, with extra git diff options such as --stat
+func (r *Runner) attemptDiff(before *worktreeSnapshot, options ...string) (string, error) {
+	if before == nil {
+		return "", fmt.Errorf("the working tree isn't a git repository with a commit")
+	}
+	after, err := r.snapshotWorktree()
+	if err != nil {
+		return "", err
+	}
+	args := append(append([]string{"diff"}, options...), before.tree, after.tree)
+	return gitOutput(filepath.Dir(r.planPath), args...), nil
+}
+
+// rollback restores the working tree to a snapshot: commits made since are
+// dropped from the branch, changed and deleted files are restored, and new
+// files are removed. Ignored files, including ralph-loop's own run data,
+// are left alone, and so is the plan file. Changes that were staged before
+// the attempt come back unstaged.
+func (r *Runner) rollback(snapshot *worktreeSnapshot) error {
+	return restoreWorktree(r.planPath, snapshot)
+}
+
+// restoreWorktree resets the repository holding the plan to a snapshot,
+// keeping the plan file as it is
+func restoreWorktree(planPath string, snapshot *worktreeSnapshot) error {
+	dir := filepath.Dir(planPath)
+	planContent, err := os.ReadFile(planPath)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	for _, args := range [][]string{
+		{"reset", "--quiet", "--hard", snapshot.head},
+		{"read-tree", "--reset", "-u", snapshot.tree},
+		{"clean", "-d", "--force", "--quiet", "--", ":/"},
+		{"reset", "--quiet"},
+	} {
+		if _, err := gitRun(dir, args...); err != nil {
+			return err
+		}
+	}
+	return plan.SaveContent(planPath, string(planContent))
+}
+
+// rollbackAttempt rolls back a failed attempt's edits, unless it reported
+// partial progress that the next attempt builds on
+func (r *Runner) rollbackAttempt(snapshot *worktreeSnapshot, result plan.StepResult) {
+	if !r.config.Rollback || snapshot == nil || result.Success || strings.HasPrefix(result.Reason, prompt.PartialPrefix) {
+		return
+	}
+	if err := r.rollback(snapshot); err != nil {
+		r.warnings.Add(WarningRollback, "failed to roll back the attempt: %v", err)
+		return
+	}
+	fmt.Printf("Rolled back the working tree to its state before the attempt (%s)\n", shortRev(snapshot.head))
+}
+
+// gitWithEnv runs a git command with an extra environment variable,
+// returning its output
+func gitWithEnv(dir string, env string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(), env)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
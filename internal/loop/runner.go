@@ -2,30 +2,79 @@ package loop
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
 	"github.com/eraldohasanaj/ralph-loop/internal/plan"
 	"github.com/eraldohasanaj/ralph-loop/internal/prompt"
 )
 
 // Runner orchestrates the main execution loop
 type Runner struct {
-	agent    agent.Agent
-	planPath string
-	config   Config
+	agent         agent.Agent
+	planPath      string
+	config        Config
+	stopRequested atomic.Bool // Set when the agent was asked to stop gracefully
+	warnings      *WarningCollector
+	runStartedAt  time.Time
+
+	agentFactory AgentFactory // Creates agents for steps with overrides; nil disables them
+	activeMu     sync.Mutex
+	active       agent.Agent   // Agent running the current step
+	upstreamRev  string        // Last seen commit of the upstream branch
+	runID        string        // Random ID tagging this run's prompts
+	record       *RunRecord    // Attempts so far, stored with the transcripts
+	sandbox      agent.Sandbox // Started before the first step and stopped when the run ends
+	github       *githubStatus // Publishes progress as commit statuses; nil unless GitHubStatus is set
+
+	verifyOutputs map[int]string // Output of each step's last failed verification, for the test-output provider
+	parked        map[int]bool   // Steps that failed in this run and wait for the others, with --on-failure continue
+	planAdds      int            // Steps agents appended to the plan in this run
+	checkpointed  map[int]bool   // Steps whose checkpoint is in place, taken in this run or before it
+
+	costUnreported bool           // An attempt's cost was unknown, so the run's max cost can't be fully enforced
+	answers        chan string    // Lines the operator typed, read from stdin once a review first asks
+	notifying      sync.WaitGroup // Desktop notifications still being shown
+	webhook        *webhookSender // Delivers run events; nil unless a webhook is configured
 }
 
+// AgentFactory creates the agent for a step that overrides the agent or
+// model in the plan. An empty argument means the run's default.
+type AgentFactory func(agentName string, model string) (agent.Agent, error)
+
+const (
+	largePromptSize  = 64 * 1024 // Prompts larger than this are reported as warnings
+	slowStepFraction = 0.75      // Steps using more than this share of the timeout are reported
+
+	reconcilePollInterval = 2 * time.Second // How often a paused run rechecks an edited frozen plan
+
+	budgetExceeded = "Budget exceeded" // Failure class of attempts over a step's max_cost or max_duration
+
+	sessionNotFound = "No conversation found with session ID" // claude's error for a session it can't resume
+)
+
 // NewRunner creates a new loop runner with default config
 func NewRunner(a agent.Agent, planPath string) *Runner {
 	return &Runner{
 		agent:    a,
 		planPath: planPath,
 		config:   DefaultConfig(),
+		warnings: NewWarningCollector(),
+		runID:    prompt.NewRunID(),
+
+		verifyOutputs: make(map[int]string),
+		parked:        make(map[int]bool),
+		checkpointed:  make(map[int]bool),
 	}
 }
 
@@ -35,31 +84,157 @@ func NewRunnerWithConfig(a agent.Agent, planPath string, config Config) *Runner
 		agent:    a,
 		planPath: planPath,
 		config:   config,
+		warnings: NewWarningCollector(),
+		runID:    prompt.NewRunID(),
+
+		verifyOutputs: make(map[int]string),
+		parked:        make(map[int]bool),
+		checkpointed:  make(map[int]bool),
 	}
 }
 
+// SetAgentFactory enables per-step (agent: ..., model: ...) overrides
+func (r *Runner) SetAgentFactory(factory AgentFactory) {
+	r.agentFactory = factory
+}
+
+// SetSandbox has the runner start the sandbox agents run in before the
+// first step and stop it when the run ends
+func (r *Runner) SetSandbox(sandbox agent.Sandbox) {
+	r.sandbox = sandbox
+}
+
 // Run executes the main loop
 func (r *Runner) Run() error {
 	// Set up signal handling for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	// A Ctrl+C after the run was cancelled stops waiting on deliveries at exit
+	exitCtx, abandonExit := context.WithCancel(context.Background())
+	defer abandonExit()
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		<-sigChan
-		fmt.Println("\n\nReceived interrupt signal. Shutting down gracefully...")
-		cancel()
+		// First Ctrl+C asks the agent to stop after its current action if it
+		// supports that; otherwise (or on a second Ctrl+C) cancel immediately
+		for range sigChan {
+			if !r.stopRequested.Load() && r.requestStop() {
+				fmt.Println("\n\nReceived interrupt signal. Asking the agent to stop after its current action...")
+				fmt.Println("Press Ctrl+C again to stop immediately.")
+				continue
+			}
+			if ctx.Err() != nil {
+				abandonExit()
+				continue
+			}
+			fmt.Println("\n\nReceived interrupt signal. Shutting down gracefully...")
+			cancel()
+		}
 	}()
 
-	return r.runLoop(ctx)
+	// Summarize non-fatal warnings however the loop ends
+	defer r.warnings.Print(os.Stdout)
+	defer r.notifying.Wait()
+
+	if err := layout.ForPlan(r.planPath).Ensure(); err != nil {
+		return err
+	}
+
+	// Two loops on one plan would interleave their writes to it
+	lock, err := acquireLock(r.planPath, r.config.ForceLock)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	if r.config.GitHubStatus {
+		github, err := newGitHubStatus(filepath.Dir(r.planPath))
+		if err != nil {
+			return err
+		}
+		r.github = github
+		fmt.Printf("Publishing commit statuses to %s@%s\n", github.repo, shortRev(github.sha))
+	}
+
+	if r.sandbox != nil {
+		if err := r.sandbox.Start(ctx); err != nil {
+			return fmt.Errorf("%s sandbox: %w", r.sandbox.Name(), err)
+		}
+		defer r.sandbox.Stop()
+	}
+
+	// Publish live progress for `status` while the run is active
+	r.runStartedAt = time.Now()
+	if r.runID != "" {
+		fmt.Printf("Run ID: %s\n", r.runID)
+	}
+	defer removeState(r.planPath)
+	r.startRecord()
+	defer r.finishRecord()
+	r.startWebhook(ctx, exitCtx)
+	r.emitRunStarted()
+
+	// A new run starts with a fresh budget
+	if err := plan.ClearBudgetExhausted(r.planPath); err != nil && !errors.Is(err, plan.ErrFrozenPlanEdited) {
+		r.warnings.Add(WarningBudget, "failed to clear the exhausted budget note: %v", err)
+	}
+
+	err = r.runLoop(ctx)
+	r.publishRunEnd(err)
+	r.emailRunSummary(err)
+	r.emitRunFinished(err)
+	return err
 }
 
 func (r *Runner) runLoop(ctx context.Context) error {
-	// Create prompt detector to monitor for feedback prompts
-	promptDetector := NewPromptDetector(os.Stdout)
-	defer promptDetector.Close()
+	nextStep, err := plan.ParseOrderStrategy(r.config.Order)
+	if err != nil {
+		return err
+	}
+	if r.hasSelection() {
+		p, err := r.parsePlan()
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		if err := r.checkSelection(p); err != nil {
+			return err
+		}
+		nextStep = plan.Only(nextStep, r.selected)
+	}
+	if r.config.OnFailure == OnFailureContinue {
+		nextStep = r.parkFailures(nextStep)
+	}
+	providers, err := r.contextProviders()
+	if err != nil {
+		return err
+	}
+	denylist, err := CompileDenylist(r.config.Denylist)
+	if err != nil {
+		return err
+	}
+
+	// Monitor agent output for prompts, agent warnings, denied commands and
+	// stalls; the stall answer tier types into agents run in a PTY
+	answers := agent.NewInput()
+	monitor := NewOutputMonitor(os.Stdout, r.warnings,
+		agentWarningHandler{},
+		promptHandler{onPrompt: r.notifyPrompt},
+		newPolicyHandler(denylist),
+		newStallHandler(r.config.Stall, answers),
+	)
+	defer monitor.Close()
+
+	// Consecutive reruns of the same step's attempt after rate limits and
+	// transient agent failures
+	rerunStep, rateLimitWaits, transientRetries := 0, 0, 0
+
+	// Steps whose stored session could not be resumed; they start cold
+	staleSessions := make(map[int]bool)
+
+	// Steps completed in this run, for MaxSteps
+	completedSteps := 0
 
 	for {
 		// Check for cancellation
@@ -69,31 +244,55 @@ func (r *Runner) runLoop(ctx context.Context) error {
 		default:
 		}
 
+		// Don't act on a frozen plan that was edited by hand
+		if err := r.awaitReconciliation(ctx, nil); err != nil {
+			return err
+		}
+
+		// Pick up upstream changes between steps
+		if err := r.checkUpstream(ctx, monitor); err != nil {
+			return err
+		}
+
 		// Parse the plan
-		p, err := plan.ParseFile(r.planPath)
+		p, err := r.parsePlan()
 		if err != nil {
 			return fmt.Errorf("failed to parse plan: %w", err)
 		}
 
 		// Find next step
-		step := p.NextStep()
+		step := nextStep(p)
 		if step == nil {
-			fmt.Println("\n=== All steps completed! ===")
+			if err := r.finishSteps(p); err != nil || !r.config.Watch {
+				return err
+			}
+			if resumed, err := r.awaitNewSteps(ctx, nextStep); !resumed {
+				return err
+			}
+			continue
+		}
+		if r.config.MaxSteps > 0 && completedSteps >= r.config.MaxSteps {
+			fmt.Printf("\nStopped after %d completed step(s) (--max-steps). Run ralph-loop again to continue.\n", completedSteps)
+			return nil
+		}
+		if reason := r.exhaustedBudget(); reason != "" {
+			r.stopOnBudget(reason)
 			return nil
 		}
 
 		// Check max retries - skip and continue to next step
-		if step.RetryCount >= r.config.MaxRetries {
+		maxRetries := r.maxRetries(step)
+		if step.RetryCount >= maxRetries {
 			fmt.Printf("\n=== Step %d exceeded max retries (%d). Marking as skipped. ===\n",
-				step.Number, r.config.MaxRetries)
+				step.Number, maxRetries)
 			result := plan.StepResult{
 				Success:    false,
-				Reason:     fmt.Sprintf("Skipped after %d failed attempts", r.config.MaxRetries),
+				Reason:     fmt.Sprintf("Skipped after %d failed attempts", maxRetries),
 				Status:     plan.StatusSkipped,
 				RetryCount: step.RetryCount,
 			}
-			if err := plan.UpdateStep(r.planPath, step.Number, result); err != nil {
-				return fmt.Errorf("failed to update plan: %w", err)
+			if err := r.updatePlan(ctx, step, result); err != nil {
+				return err
 			}
 			continue // Move to next step
 		}
@@ -101,8 +300,9 @@ func (r *Runner) runLoop(ctx context.Context) error {
 		// Apply backoff delay if retrying
 		if step.Status == plan.StatusFailed && step.RetryCount > 0 {
 			delay := r.calculateBackoff(step.RetryCount)
+			r.updateState(step, PhaseWaiting, time.Now())
 			fmt.Printf("\n=== Waiting %v before retry (attempt %d of %d)... ===\n",
-				delay, step.RetryCount+1, r.config.MaxRetries)
+				delay, step.RetryCount+1, maxRetries)
 			select {
 			case <-time.After(delay):
 			case <-ctx.Done():
@@ -113,72 +313,536 @@ func (r *Runner) runLoop(ctx context.Context) error {
 		// Print status
 		fmt.Printf("\n=== Running Step %d: %s ===\n", step.Number, step.Description)
 		if step.Status == plan.StatusFailed {
-			fmt.Printf("(Retry attempt %d of %d)\n", step.RetryCount+1, r.config.MaxRetries)
+			fmt.Printf("(Retry attempt %d of %d)\n", step.RetryCount+1, maxRetries)
 		}
 		fmt.Println()
+		r.publishStepStart(p, step)
+
+		// Pick the agent, honoring the step's overrides
+		r.warnings.SetStep(step.Number)
+		a, err := r.agentFor(step)
+		if err != nil {
+			return fmt.Errorf("step %d: %w", step.Number, err)
+		}
+		r.setActiveAgent(a)
+		r.emitStepStarted(p, step)
+		if metadata := step.Metadata(); metadata != "" {
+			fmt.Printf("Using %s agent %s\n\n", a.Name(), metadata)
+		}
+
+		// Build prompt, tagged with an ID for this attempt
+		attemptID := prompt.CorrelationID(r.runID, step.Number, step.RetryCount+1)
+		scratch := r.scratchDir(step.Number, step.RetryCount+1)
+		sections, errs := prompt.CollectContext(ctx, providers, step)
+		for _, err := range errs {
+			r.warnings.Add(WarningContext, "%v", err)
+		}
+		promptText := prompt.Build(p, step, attemptID, scratch, sections, r.planAddsLeft())
+		if len(promptText) > largePromptSize {
+			r.warnings.Add(WarningLargePrompt, "prompt is %d KB; consider trimming the context", len(promptText)/1024)
+		}
+		for _, finding := range prompt.InjectionFindings(p, step, sections) {
+			fmt.Printf("Warning: possible prompt injection: %s\n", finding)
+			r.warnings.Add(WarningInjection, "possible prompt injection: %s", finding)
+		}
+
+		// The pre_step hook must pass before the agent runs
+		if err := r.runHook(ctx, HookPreStep, r.config.Hooks.PreStep, step, a, nil, 0); err != nil {
+			if ctx.Err() != nil {
+				return r.saveInterruptedState(step)
+			}
+			result := plan.StepResult{
+				Success:    false,
+				Reason:     fmt.Sprintf("%s: %v", hookFailed, err),
+				RetryCount: step.RetryCount + 1,
+			}
+			fmt.Printf("\n=== Step %d failed: %s ===\n", step.Number, result.Reason)
+			if err := r.updatePlan(ctx, step, result); err != nil {
+				return err
+			}
+			r.parkStep(step, result)
+			r.afterAttempt(ctx, step, a, result, 0)
+			continue
+		}
+
+		// Record the working tree, so a failed attempt can be rolled back and
+		// a reviewed one diffed
+		var snapshot *worktreeSnapshot
+		checkpoint := r.needsCheckpoint(step.Number)
+		if r.config.Rollback || r.config.Review || r.config.ApproveEach || checkpoint {
+			if snapshot, err = r.snapshotWorktree(); err != nil && r.config.Rollback {
+				r.warnings.Add(WarningRollback, "failed to snapshot the working tree; a failed attempt won't be rolled back: %v", err)
+			}
+		}
+		if checkpoint {
+			r.checkpointStep(step.Number, step.RetryCount > 0, snapshot)
+		}
 
-		// Build prompt
-		promptText := prompt.Build(p, step)
+		// Create timeout context; a step's max_duration budget can shorten it
+		timeout, budgeted := r.timeout(step), false
+		if step.MaxDuration > 0 && step.MaxDuration < timeout {
+			timeout, budgeted = step.MaxDuration, true
+		}
+		// The stall kill tier and the denylist stop the attempt through killCtx
+		killCtx, stopAttempt := context.WithCancelCause(ctx)
+		stepCtx, cancel := context.WithTimeout(killCtx, timeout)
+		if scratch != "" {
+			stepCtx = agent.WithEnv(stepCtx, prompt.ScratchDirEnv+"="+scratch)
+		}
 
-		// Create timeout context
-		stepCtx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+		// Continue the failed attempt's session instead of starting cold
+		resumed := false
+		if r.config.ResumeSessions && step.Status == plan.StatusFailed && step.SessionID != "" && !staleSessions[step.Number] {
+			if resumer, ok := a.(agent.SessionResumer); ok && resumer.ResumesSessions() {
+				fmt.Printf("Resuming session %s from the previous attempt\n\n", step.SessionID)
+				stepCtx = agent.WithResume(stepCtx, step.SessionID)
+				resumed = true
+			}
+		}
 
-		// Reset prompt detector for new step
-		promptDetector.Reset()
+		// Watch the new step's output for prompts, denied commands and stalls
+		monitor.Watch(step.Number, stopAttempt)
 
-		// Run agent with prompt detection
-		output, err := r.agent.Run(stepCtx, promptText, promptDetector)
+		// Run agent with output monitoring
+		startedAt := time.Now()
+		r.updateState(step, PhaseRunning, startedAt)
+		runCtx := stepCtx
+		if r.config.Stall.Answer > 0 {
+			runCtx = agent.WithInput(stepCtx, answers)
+		}
+		output, err := a.Run(runCtx, promptText, monitor)
+		elapsed := time.Since(startedAt)
+		monitor.Unwatch()
+		stopCause := context.Cause(killCtx)
 		cancel()
+		stopAttempt(nil)
+		transcript := r.saveTranscript(step.Number, step.RetryCount+1, promptText, output)
+
+		var usage *agent.Usage
+		if reporter, ok := a.(agent.UsageReporter); ok {
+			if usage = reporter.LastUsage(); usage != nil {
+				fmt.Printf("\nUsage: %s\n", usage)
+			}
+		}
 
 		// Check for timeout
 		if stepCtx.Err() == context.DeadlineExceeded {
-			fmt.Printf("\n=== Step %d timed out after %v ===\n", step.Number, r.config.Timeout)
+			reason := fmt.Sprintf("Step timed out after %v", timeout)
+			if budgeted {
+				reason = fmt.Sprintf("%s: ran longer than max_duration %s", budgetExceeded, plan.FormatDuration(step.MaxDuration))
+				fmt.Printf("\n=== Step %d stopped: %s ===\n", step.Number, reason)
+			} else {
+				fmt.Printf("\n=== Step %d timed out after %v ===\n", step.Number, timeout)
+			}
 			result := plan.StepResult{
 				Success:    false,
-				Reason:     fmt.Sprintf("Step timed out after %v", r.config.Timeout),
+				Reason:     reason,
 				RetryCount: step.RetryCount + 1,
+				Transcript: transcript,
+				Attempt:    r.attempt(step, a, startedAt, elapsed),
 			}
-			if err := plan.UpdateStep(r.planPath, step.Number, result); err != nil {
-				return fmt.Errorf("failed to update plan: %w", err)
+			if err := r.updatePlan(ctx, step, result); err != nil {
+				return err
 			}
+			r.recordAttempt(step, a, result, elapsed, usage)
+			r.saveFailureBundle(step, promptText, output, result.Reason, startedAt)
+			r.rollbackAttempt(snapshot, result)
+			r.parkStep(step, result)
+			r.afterAttempt(ctx, step, a, result, elapsed)
+			continue
+		}
+
+		// Check for an attempt stopped by the stall kill tier or the denylist
+		var violation *PolicyViolation
+		if stopCause != nil && ctx.Err() == nil && (errors.Is(stopCause, errStalled) || errors.As(stopCause, &violation)) {
+			reason := fmt.Sprintf("%s: no output for %s", agentStalled, plan.FormatDuration(r.config.Stall.Kill))
+			if errors.Is(stopCause, errAwaitingInput) {
+				reason = fmt.Sprintf("%s: no output for %s after it asked a question", agentAwaitingInput, plan.FormatDuration(r.config.Stall.Kill))
+			}
+			if violation != nil {
+				reason = fmt.Sprintf("%s: %v", policyViolation, violation)
+			}
+			result := plan.StepResult{
+				Success:    false,
+				Reason:     reason,
+				RetryCount: step.RetryCount + 1,
+				Transcript: transcript,
+				Attempt:    r.attempt(step, a, startedAt, elapsed),
+			}
+			fmt.Printf("\n=== Step %d stopped: %s ===\n", step.Number, result.Reason)
+			if err := r.updatePlan(ctx, step, result); err != nil {
+				return err
+			}
+			r.recordAttempt(step, a, result, elapsed, usage)
+			r.saveFailureBundle(step, promptText, output, result.Reason, startedAt)
+			r.rollbackAttempt(snapshot, result)
+			r.parkStep(step, result)
+			r.afterAttempt(ctx, step, a, result, elapsed)
+			continue
+		}
+
+		if err != nil && ctx.Err() != nil {
+			// Parent cancelled - save current state and exit
+			return r.saveInterruptedState(step)
+		}
+
+		if step.Number != rerunStep {
+			rerunStep, rateLimitWaits, transientRetries = step.Number, 0, 0
+		}
+		// A session that can't be resumed (expired, or from another machine)
+		// is the tool's problem, not the step's: rerun the attempt cold
+		if resumed && strings.Contains(output, sessionNotFound) {
+			staleSessions[step.Number] = true
+			r.warnings.Add(WarningAgent, "session %s could not be resumed; reran the attempt without it", step.SessionID)
+			fmt.Printf("\n=== Step %d: session %s could not be resumed. Rerunning without it... ===\n", step.Number, step.SessionID)
 			continue
 		}
 
+		// An infrastructure failure (a crash, a CLI that couldn't start, a
+		// dropped connection) says nothing about the step either: retry the
+		// attempt right away, without spending one of the step's retries
+		transient := detectTransient(a, output, err)
+		if transient != nil && transientRetries < r.config.TransientRetries && !r.stopRequested.Load() {
+			transientRetries++
+			r.warnings.Add(WarningAgent, "%s; retried the attempt", transient)
+			fmt.Printf("\n=== Step %d: %s. Retrying (%d of %d)... ===\n",
+				step.Number, transient, transientRetries, r.config.TransientRetries)
+			continue
+		}
 		if err != nil {
+			return fmt.Errorf("agent execution failed: %w", err)
+		}
+		if transient == nil {
+			transientRetries = 0
+		}
+
+		if elapsed > time.Duration(float64(timeout)*slowStepFraction) {
+			r.warnings.Add(WarningSlowStep, "took %v (%.0f%% of the %v timeout)",
+				elapsed.Round(time.Second), 100*elapsed.Seconds()/timeout.Seconds(), timeout)
+		}
+
+		// Parse result
+		result := prompt.ParseResult(output, attemptID)
+		if usage != nil {
+			result.SessionID = usage.SessionID
+		}
+		if transient != nil && !result.Success {
+			result.Reason = transient.String()
+		}
+		if step.MaxCost > 0 {
+			result = r.checkCostBudget(step, usage, result)
+		}
+		if result.Success && attemptID != "" && prompt.EchoedRunID(output) == "" {
+			r.warnings.Add(WarningAgent, "completion marker was not tagged with run ID %s; the result could not be attributed", attemptID)
+		}
+
+		// The agent was asked to stop; keep finished work, otherwise record the interruption
+		if r.stopRequested.Load() && !result.Success {
+			return r.saveInterruptedState(step)
+		}
+
+		// A rate limit or overload says nothing about the step: wait and
+		// rerun the same attempt without spending one of its retries
+		if !result.Success {
+			if limited, advised := detectRateLimit(output); limited && rateLimitWaits < rateLimitMaxWaits {
+				rateLimitWaits++
+				delay := rateLimitDelay(advised, rateLimitWaits)
+				r.warnings.Add(WarningRateLimit, "agent was rate limited; waited %v and reran the attempt", delay)
+				r.updateState(step, PhaseWaiting, time.Now())
+				fmt.Printf("\n=== Step %d hit an API rate limit. Waiting %v before rerunning (%d of %d)... ===\n",
+					step.Number, delay, rateLimitWaits, rateLimitMaxWaits)
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return r.saveInterruptedState(step)
+				}
+				continue
+			}
+		}
+		rateLimitWaits = 0
+
+		// Echo the step's acceptance criteria so they can be checked
+		if result.Success && step.Acceptance != "" {
+			fmt.Printf("\n=== Acceptance criteria for Step %d ===\n%s\n", step.Number, step.Acceptance)
+		}
+
+		// A step only counts as complete once the verification command passes
+		if result.Success && r.config.Verify != "" {
+			fmt.Printf("\n=== Verifying Step %d: %s ===\n", step.Number, r.config.Verify)
+			verifyCtx, cancel := context.WithTimeout(ctx, r.timeout(step))
+			verifyOutput, err := runVerify(verifyCtx, r.config.Verify, step, os.Stdout)
+			cancel()
 			if ctx.Err() != nil {
-				// Parent cancelled - save current state and exit
 				return r.saveInterruptedState(step)
 			}
-			return fmt.Errorf("agent execution failed: %w", err)
+			if err != nil {
+				result = plan.StepResult{
+					Success:   false,
+					Output:    verifyOutput,
+					Reason:    verifyFailureReason(r.config.Verify, verifyOutput, err),
+					SessionID: result.SessionID,
+				}
+				output += "\n" + verifyOutput
+				r.verifyOutputs[step.Number] = verifyOutput
+			}
 		}
 
-		// Parse result
-		result := prompt.ParseResult(output)
+		// A second agent checks the work against the step
+		if result.Success && r.config.Review {
+			result = r.reviewWithAgent(ctx, p, step, result, snapshot, monitor)
+			if ctx.Err() != nil {
+				return r.saveInterruptedState(step)
+			}
+		}
+
+		// With --approve-each, the operator has the last word
+		if result.Success && r.config.ApproveEach {
+			reviewed, err := r.review(ctx, step, result, snapshot)
+			if ctx.Err() != nil {
+				return r.saveInterruptedState(step)
+			}
+			if err != nil {
+				return err
+			}
+			result = reviewed
+		}
+
+		result.Transcript = transcript
+		result.Attempt = r.attempt(step, a, startedAt, elapsed)
 
 		// Update retry count on failure
 		if !result.Success {
 			result.RetryCount = step.RetryCount + 1
 		} else {
 			result.RetryCount = step.RetryCount
+			// Record the context this step was executed against
+			result.ContextHash = plan.ContextFingerprint(p, filepath.Dir(r.planPath))
+			result.Artifacts = r.uploadArtifacts(ctx, step.Number)
 		}
 
 		// Update plan
-		if err := plan.UpdateStep(r.planPath, step.Number, result); err != nil {
-			return fmt.Errorf("failed to update plan: %w", err)
+		if err := r.updatePlan(ctx, step, result); err != nil {
+			return err
 		}
+		r.recordAttempt(step, a, result, elapsed, usage)
+		r.addDiscoveredSteps(step, output, attemptID)
 
 		// Print result
 		if result.Success {
 			fmt.Printf("\n=== Step %d completed successfully ===\n", step.Number)
 		} else {
 			fmt.Printf("\n=== Step %d failed: %s ===\n", step.Number, result.Reason)
-			if result.RetryCount < r.config.MaxRetries {
-				fmt.Printf("Will retry (attempt %d of %d)...\n", result.RetryCount+1, r.config.MaxRetries)
+			r.saveFailureBundle(step, promptText, output, result.Reason, startedAt)
+			r.rollbackAttempt(snapshot, result)
+			if result.RetryCount < maxRetries {
+				fmt.Printf("Will retry (attempt %d of %d)...\n", result.RetryCount+1, maxRetries)
 			} else {
-				fmt.Printf("Max retries reached (%d). Step will be skipped on next iteration.\n", r.config.MaxRetries)
+				fmt.Printf("Max retries reached (%d). Step will be skipped on next iteration.\n", maxRetries)
 			}
+			r.parkStep(step, result)
 		}
+		r.afterAttempt(ctx, step, a, result, elapsed)
+
+		if r.stopRequested.Load() {
+			fmt.Println("\nStopped after the current step. Run ralph-loop again to continue.")
+			return nil
+		}
+		if result.Success {
+			completedSteps++
+		}
+	}
+}
+
+// finishSteps reports the end of the plan: every step is done, or the
+// rest are blocked by skipped steps or wait on dependencies that can never
+// complete
+func (r *Runner) finishSteps(p *plan.Plan) error {
+	if r.hasSelection() {
+		return r.finishSelection(p)
+	}
+	blocked, waiting := p.Blocked(), p.Waiting()
+	if len(blocked) == 0 && len(waiting) == 0 {
+		fmt.Println("\n=== All steps completed! ===")
+		return nil
+	}
+
+	fmt.Println("\n=== No runnable steps left ===")
+	for _, step := range blocked {
+		fmt.Printf("Step %d is blocked: %s\n", step.Number, p.BlockedReason(&step))
+	}
+	if len(waiting) > 0 {
+		var numbers []string
+		for _, step := range waiting {
+			numbers = append(numbers, fmt.Sprintf("%d", step.Number))
+		}
+		return fmt.Errorf("step(s) %s depend on steps that can never complete; run 'ralph-loop validate'", strings.Join(numbers, ", "))
+	}
+	fmt.Println("Change a skipped step's [-] back to [ ] in the plan to retry it and unblock its dependents.")
+	return nil
+}
+
+// checkCostBudget fails an attempt whose reported cost exceeded the step's
+// max_cost. Agents report cost when they finish, so the budget is checked
+// after the attempt rather than while it runs.
+func (r *Runner) checkCostBudget(step *plan.Step, usage *agent.Usage, result plan.StepResult) plan.StepResult {
+	if usage == nil || usage.CostUSD == 0 {
+		r.warnings.Add(WarningBudget, "max_cost $%.2f not enforced: the agent did not report a cost", step.MaxCost)
+		return result
+	}
+	if usage.CostUSD <= step.MaxCost {
+		return result
+	}
+	return plan.StepResult{
+		Success:   false,
+		Output:    result.Output,
+		Reason:    fmt.Sprintf("%s: cost $%.2f, over max_cost $%.2f", budgetExceeded, usage.CostUSD, step.MaxCost),
+		SessionID: result.SessionID,
+	}
+}
+
+// updatePlan records a step result. If the plan is frozen and was edited by
+// hand, it waits for the edits to be reconciled instead of overwriting them.
+func (r *Runner) updatePlan(ctx context.Context, step *plan.Step, result plan.StepResult) error {
+	for {
+		err := plan.UpdateStep(r.planPath, step.Number, result)
+		if !errors.Is(err, plan.ErrFrozenPlanEdited) {
+			if err != nil {
+				return fmt.Errorf("failed to update plan: %w", err)
+			}
+			r.publishStepResult(step, result)
+			r.notifyStepResult(step, result)
+			r.emitStepResult(step, result)
+			return nil
+		}
+		if err := r.awaitReconciliation(ctx, step); err != nil {
+			return err
+		}
+	}
+}
+
+// awaitReconciliation pauses while a frozen plan has manual edits, until
+// they are reverted or accepted with `ralph-loop freeze` or `unfreeze`
+func (r *Runner) awaitReconciliation(ctx context.Context, step *plan.Step) error {
+	announced := false
+	for {
+		content, err := os.ReadFile(r.planPath)
+		if err != nil {
+			return fmt.Errorf("failed to read plan file: %w", err)
+		}
+		if plan.CheckFrozen(string(content)) == nil {
+			if announced {
+				fmt.Println("Plan reconciled. Resuming.")
+			}
+			return nil
+		}
+
+		if !announced {
+			fmt.Printf("\n=== %s was edited while frozen. Pausing so the edits aren't overwritten. ===\n", r.planPath)
+			fmt.Println("Revert the edits, or run 'ralph-loop freeze' to accept them (or 'ralph-loop unfreeze').")
+			if step != nil {
+				r.updateState(step, PhasePaused, time.Now())
+			}
+			announced = true
+		}
+
+		select {
+		case <-time.After(reconcilePollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// agentFor returns the agent for a step: the run's default unless the step
+// overrides the agent or model
+func (r *Runner) agentFor(step *plan.Step) (agent.Agent, error) {
+	if (step.Agent == "" && step.Model == "") || r.agentFactory == nil {
+		return r.agent, nil
+	}
+	return r.agentFactory(step.Agent, step.Model)
+}
+
+// setActiveAgent records the agent running the current step
+func (r *Runner) setActiveAgent(a agent.Agent) {
+	r.activeMu.Lock()
+	defer r.activeMu.Unlock()
+	r.active = a
+}
+
+// activeAgent returns the agent running the current step, or the default
+func (r *Runner) activeAgent() agent.Agent {
+	r.activeMu.Lock()
+	defer r.activeMu.Unlock()
+	if r.active != nil {
+		return r.active
+	}
+	return r.agent
+}
+
+// saveFailureBundle writes a failure bundle and prints where it is
+func (r *Runner) saveFailureBundle(step *plan.Step, promptText string, output string, reason string, startedAt time.Time) {
+	dir, err := r.writeFailureBundle(step, promptText, output, reason, startedAt)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+	fmt.Printf("Failure bundle saved to: %s\n", dir)
+	if url := r.uploadFailureBundle(dir); url != "" {
+		fmt.Printf("Failure bundle uploaded to: %s\n", url)
+	}
+}
+
+// requestStop asks the agent to stop gracefully, returning false if the
+// agent doesn't support it or nothing is running
+func (r *Runner) requestStop() bool {
+	interrupter, ok := r.activeAgent().(agent.Interrupter)
+	if !ok {
+		return false
+	}
+	// Set the flag first so the loop sees it as soon as the agent exits
+	r.stopRequested.Store(true)
+	if err := interrupter.Interrupt(); err != nil {
+		r.stopRequested.Store(false)
+		return false
+	}
+	return true
+}
+
+// updateState writes the live state file; failures are reported but don't stop the run
+func (r *Runner) updateState(step *plan.Step, phase string, stepStartedAt time.Time) {
+	state := &State{
+		PID:             os.Getpid(),
+		Agent:           r.activeAgent().Name(),
+		PlanPath:        r.planPath,
+		RunStartedAt:    r.runStartedAt,
+		Step:            step.Number,
+		StepDescription: step.Description,
+		StepStartedAt:   stepStartedAt,
+		Attempt:         step.RetryCount + 1,
+		MaxRetries:      r.maxRetries(step),
+		Phase:           phase,
+	}
+	if err := writeState(r.planPath, state); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// maxRetries returns how many attempts a step gets: its **Max Retries**
+// override, or the loop's setting
+func (r *Runner) maxRetries(step *plan.Step) int {
+	if step.MaxRetries > 0 {
+		return step.MaxRetries
+	}
+	return r.config.MaxRetries
+}
+
+// timeout returns how long an attempt at a step may run: its **Timeout**
+// override, or the loop's setting
+func (r *Runner) timeout(step *plan.Step) time.Duration {
+	if step.Timeout > 0 {
+		return step.Timeout
 	}
+	return r.config.Timeout
 }
 
 // calculateBackoff calculates the backoff delay for a given retry count
@@ -0,0 +1,31 @@
+package loop
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+)
+
+// ScratchRoot returns where steps' scratch directories are kept:
+// .ralph-loop/scratch next to the plan file. `ralph-loop clean` removes it.
+func ScratchRoot(planPath string) string {
+	return layout.ForPlan(planPath).Path(layout.Scratch)
+}
+
+// scratchDir creates the scratch directory of an attempt,
+// <root>/<run>/step-<n>-attempt-<m>, and returns its absolute path. A
+// failure is recorded as a warning and returns "" so the step runs without
+// one.
+func (r *Runner) scratchDir(step int, attempt int) string {
+	dir, err := filepath.Abs(filepath.Join(ScratchRoot(r.planPath), r.runKey(), fmt.Sprintf("step-%d-attempt-%d", step, attempt)))
+	if err == nil {
+		err = os.MkdirAll(dir, 0755)
+	}
+	if err != nil {
+		r.warnings.Add(WarningAgent, "failed to create scratch directory: %v", err)
+		return ""
+	}
+	return dir
+}
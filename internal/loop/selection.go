@@ -0,0 +1,118 @@
+package loop
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// hasSelection reports whether the run is limited to some of the steps,
+// by --phase, --only-tag, --from or --until
+func (r *Runner) hasSelection() bool {
+	return r.config.Phase > 0 || len(r.config.OnlyTags) > 0 || r.config.From > 0 || r.config.Until > 0
+}
+
+// selected reports whether a step is in the run's selection
+func (r *Runner) selected(step *plan.Step) bool {
+	if r.config.Phase > 0 && step.Phase != r.config.Phase {
+		return false
+	}
+	if step.Number < r.config.From || (r.config.Until > 0 && step.Number > r.config.Until) {
+		return false
+	}
+	return len(r.config.OnlyTags) == 0 || step.HasAnyTag(r.config.OnlyTags)
+}
+
+// checkSelection refuses a phase, tags or steps the plan doesn't have
+func (r *Runner) checkSelection(p *plan.Plan) error {
+	for _, bound := range []struct {
+		flag   string
+		number int
+	}{{"--from", r.config.From}, {"--until", r.config.Until}} {
+		if bound.number > len(p.Steps) {
+			return fmt.Errorf("%s %d: the plan has %d steps", bound.flag, bound.number, len(p.Steps))
+		}
+	}
+	if r.config.Phase > 0 && p.PhaseByNumber(r.config.Phase) == nil {
+		if len(p.Phases) == 0 {
+			return fmt.Errorf("phase %d does not exist: the plan has no '## Phase N' headers", r.config.Phase)
+		}
+		return fmt.Errorf("phase %d does not exist (the plan has phases %s)", r.config.Phase, p.PhaseNumbers())
+	}
+	tags := p.Tags()
+	for _, tag := range r.config.OnlyTags {
+		if slices.Contains(tags, tag) {
+			continue
+		}
+		if len(tags) == 0 {
+			return fmt.Errorf("no step is tagged #%s: the plan has no tags", tag)
+		}
+		return fmt.Errorf("no step is tagged #%s (the plan has #%s)", tag, strings.Join(tags, ", #"))
+	}
+	return nil
+}
+
+// selectionName describes the selection, e.g. "Phase 2: API, steps 4-8,
+// tagged #backend"
+func (r *Runner) selectionName(p *plan.Plan) string {
+	var parts []string
+	if r.config.Phase > 0 {
+		parts = append(parts, p.PhaseByNumber(r.config.Phase).Name())
+	}
+	switch {
+	case r.config.From > 0 && r.config.Until > 0:
+		parts = append(parts, fmt.Sprintf("steps %d-%d", r.config.From, r.config.Until))
+	case r.config.From > 0:
+		parts = append(parts, fmt.Sprintf("steps %d onward", r.config.From))
+	case r.config.Until > 0:
+		parts = append(parts, fmt.Sprintf("steps up to %d", r.config.Until))
+	}
+	if len(r.config.OnlyTags) > 0 {
+		tagged := "tagged #" + strings.Join(r.config.OnlyTags, " or #")
+		if len(parts) == 0 {
+			tagged = "steps " + tagged
+		}
+		parts = append(parts, tagged)
+	}
+	name := strings.Join(parts, ", ")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// finishSelection reports how a run limited by --phase or --only-tag
+// ended: every selected step completed or skipped, or the ones left
+// waiting on steps outside the selection
+func (r *Runner) finishSelection(p *plan.Plan) error {
+	var blocked []plan.Step
+	var left []string
+	completed, total := 0, 0
+	for _, step := range p.Steps {
+		if !r.selected(&step) {
+			continue
+		}
+		total++
+		switch step.Status {
+		case plan.StatusCompleted:
+			completed++
+		case plan.StatusBlocked:
+			blocked = append(blocked, step)
+		case plan.StatusPending, plan.StatusFailed:
+			left = append(left, fmt.Sprintf("%d", step.Number))
+		}
+	}
+	name := r.selectionName(p)
+	if len(blocked) == 0 && len(left) == 0 {
+		fmt.Printf("\n=== %s finished: %d of %d steps completed ===\n", name, completed, total)
+		return nil
+	}
+
+	fmt.Printf("\n=== No runnable steps left: %s ===\n", name)
+	for _, step := range blocked {
+		fmt.Printf("Step %d is blocked: %s\n", step.Number, p.BlockedReason(&step))
+	}
+	if len(left) > 0 {
+		fmt.Printf("Step(s) %s depend on steps that haven't completed; run those first.\n", strings.Join(left, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// StallPolicy escalates the response to an agent that stops producing
+// output, one tier at a time. A zero duration disables a tier.
+type StallPolicy struct {
+	Warn          time.Duration // Show a warning (default: 30s)
+	Notify        time.Duration // Run NotifyCommand (default: 2m, when a command is set)
+	NotifyCommand string        // Shell command run at the notify tier
+	Answer        time.Duration // Type AnswerText when the agent's last output was a question (default: 5m; agents in a PTY only)
+	AnswerText    string        // Reply typed at the answer tier (default: "y")
+	Kill          time.Duration // Stop the attempt, which fails and is retried (default: 5m, counted from any answer)
+}
+
+// DefaultStallAnswer is the reply typed at the answer tier
+const DefaultStallAnswer = "y"
+
+// DefaultStallPolicy warns after 30 seconds of silence and notifies after
+// two minutes. After five minutes, an agent that asked a question is
+// answered "y", and an attempt still silent five minutes later, or one that
+// can't be answered, is stopped.
+func DefaultStallPolicy() StallPolicy {
+	return StallPolicy{
+		Warn:       30 * time.Second,
+		Notify:     2 * time.Minute,
+		Answer:     5 * time.Minute,
+		AnswerText: DefaultStallAnswer,
+		Kill:       5 * time.Minute,
+	}
+}
+
+// Stall tiers, in escalation order
+const (
+	stallNone = iota
+	stallWarned
+	stallNotified
+	stallAnswered
+	stallKilled
+)
+
+// errStalled is the cancellation cause of an attempt stopped by the kill tier
+var errStalled = errors.New("agent stalled")
+
+// errAwaitingInput is errStalled for an agent whose last output looked like
+// a question
+var errAwaitingInput = fmt.Errorf("%w waiting for input", errStalled)
+
+// agentStalled is the failure class of attempts stopped by the kill tier
+const agentStalled = "Agent stalled"
+
+// agentAwaitingInput is agentStalled for an agent left waiting for input
+const agentAwaitingInput = "Agent stalled waiting for input"
+
+// notifyTimeout bounds the stall notification command
+const notifyTimeout = time.Minute
+
+// runNotifyCommand runs the notify tier's command. The stalled step, how
+// long the agent has been silent and its last output line are passed as
+// RALPH_STALL_STEP, RALPH_STALL_SECONDS and RALPH_STALL_LAST_OUTPUT.
+func runNotifyCommand(command string, step int, silent time.Duration, lastLine string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("RALPH_STALL_STEP=%d", step),
+		fmt.Sprintf("RALPH_STALL_SECONDS=%d", int(silent.Seconds())),
+		"RALPH_STALL_LAST_OUTPUT="+lastLine,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// stallHandler escalates through the stall policy's tiers while the
+// watched agent run is silent
+type stallHandler struct {
+	policy     StallPolicy
+	input      *agent.Input // Answers the agent at the answer tier
+	level      int          // Highest tier reached in the watched run
+	answeredAt time.Time    // When the answer tier answered the agent, zero if it didn't
+}
+
+func newStallHandler(policy StallPolicy, input *agent.Input) *stallHandler {
+	return &stallHandler{policy: policy, input: input}
+}
+
+func (h *stallHandler) HandleLine(m *OutputMonitor, line string) bool {
+	return true
+}
+
+func (h *stallHandler) StartRun(m *OutputMonitor) {
+	h.level = stallNone
+	h.answeredAt = time.Time{}
+}
+
+// Tick escalates to each tier the run's silence has reached
+func (h *stallHandler) Tick(m *OutputMonitor, now time.Time) {
+	if !m.watching {
+		return
+	}
+	silent := m.Silence(now)
+	policy := h.policy
+
+	if policy.Warn > 0 && silent >= policy.Warn && h.level < stallWarned {
+		h.level = stallWarned
+		m.warnings.Add(WarningStall, "no output for %s", plan.FormatDuration(policy.Warn))
+		if !m.alerted {
+			m.alerted = true
+			m.showBox(fmt.Sprintf("WARNING: No output for %s - agent may be stalled!", plan.FormatDuration(policy.Warn)), []string{
+				"The agent might be waiting for input or processing a large task.",
+				"Press Ctrl+C to cancel - the step will be retried automatically.",
+			}, "LAST OUTPUT:")
+		}
+	}
+
+	if policy.Notify > 0 && policy.NotifyCommand != "" && silent >= policy.Notify && h.level < stallNotified {
+		h.level = stallNotified
+		step, lastLine, warnings := m.step, m.lastLine(), m.warnings
+		fmt.Fprintf(m.writer, "\n[ralph-loop] No output for %s; running the stall notification command\n", plan.FormatDuration(policy.Notify))
+		go func() {
+			if err := runNotifyCommand(policy.NotifyCommand, step, silent, lastLine); err != nil {
+				warnings.Add(WarningStall, "stall notification command failed: %v", err)
+			} else {
+				warnings.Add(WarningStall, "no output for %s; sent a notification", plan.FormatDuration(policy.Notify))
+			}
+		}()
+	}
+
+	if policy.Answer > 0 && silent >= policy.Answer && h.level < stallAnswered {
+		h.level = stallAnswered
+		if h.answer(m, now) {
+			return
+		}
+	}
+
+	// After an answer, the kill tier gives the agent its full time again
+	if !h.answeredAt.IsZero() {
+		silent = min(silent, now.Sub(h.answeredAt))
+	}
+	if policy.Kill > 0 && silent >= policy.Kill && h.level < stallKilled {
+		h.level = stallKilled
+		m.warnings.Add(WarningStall, "no output for %s; stopped the attempt", plan.FormatDuration(policy.Kill))
+		m.showBox(fmt.Sprintf("STOPPING: No output for %s - agent is stalled.", plan.FormatDuration(policy.Kill)), []string{
+			"The attempt is being stopped and will count as a failure.",
+			"The step will be retried if it has retries left.",
+		}, "LAST OUTPUT:")
+		if m.stop != nil && matchesPromptPattern(m.lastLine()) {
+			m.stop(errAwaitingInput)
+		} else if m.stop != nil {
+			m.stop(errStalled)
+		}
+	}
+}
+
+// answer types the answer tier's reply when the agent's last output looked
+// like a question, reporting whether it was answered. Only agents run in a
+// PTY can be answered.
+func (h *stallHandler) answer(m *OutputMonitor, now time.Time) bool {
+	question := m.lastLine()
+	if !matchesPromptPattern(question) {
+		return false
+	}
+	if h.input == nil {
+		return false
+	}
+	if err := h.input.Answer(h.policy.AnswerText); err != nil {
+		m.warnings.Add(WarningStall, "agent asked %q and could not be answered: %v", question, err)
+		return false
+	}
+	h.answeredAt = now
+	fmt.Fprintf(m.writer, "\n[ralph-loop] No output for %s after a question; answered %q\n", plan.FormatDuration(h.policy.Answer), h.policy.AnswerText)
+	m.warnings.Add(WarningStall, "agent asked %q; answered %q after %s", question, h.policy.AnswerText, plan.FormatDuration(h.policy.Answer))
+	return true
+}
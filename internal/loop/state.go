@@ -0,0 +1,103 @@
+package loop
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+)
+
+// Run phases recorded in the state file
+const (
+	PhaseRunning   = "running"   // Agent is executing the step
+	PhaseWaiting   = "waiting"   // Backing off before a retry
+	PhasePaused    = "paused"    // Frozen plan was edited; waiting for reconciliation
+	PhaseWatching  = "watching"  // No step can run; a --watch run waits for new ones
+	PhaseReviewing = "reviewing" // Agent finished; waiting for the operator's approval
+)
+
+// State is the runner's live progress. It is written to the state file while
+// a run is active so `status` can show the current step before the plan is updated.
+type State struct {
+	PID             int       `json:"pid"`
+	Agent           string    `json:"agent"`
+	PlanPath        string    `json:"plan_path"`
+	RunStartedAt    time.Time `json:"run_started_at"`
+	Step            int       `json:"step"`
+	StepDescription string    `json:"step_description"`
+	StepStartedAt   time.Time `json:"step_started_at"`
+	Attempt         int       `json:"attempt"`
+	MaxRetries      int       `json:"max_retries"`
+	Phase           string    `json:"phase"`
+}
+
+// StatePath returns the state file location for a plan:
+// .ralph-loop/state/<plan name>.json next to the plan file
+func StatePath(planPath string) string {
+	name := strings.TrimSuffix(filepath.Base(planPath), filepath.Ext(planPath))
+	return layout.ForPlan(planPath).Path(layout.State, name+".json")
+}
+
+// ReadState reads the live state for a plan. It returns nil with no error
+// when no run has written state.
+func ReadState(planPath string) (*State, error) {
+	content, err := os.ReadFile(StatePath(planPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	return &state, nil
+}
+
+// IsAlive reports whether the process that wrote the state is still running
+func (s *State) IsAlive() bool {
+	return processAlive(s.PID)
+}
+
+// writeState atomically replaces the state file so readers never see a partial write
+func writeState(planPath string, state *State) error {
+	path := StatePath(planPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
+
+// removeState deletes the state file once the run ends
+func removeState(planPath string) {
+	os.Remove(StatePath(planPath))
+}
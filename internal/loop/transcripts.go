@@ -0,0 +1,90 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+	"github.com/eraldohasanaj/ralph-loop/internal/storage"
+)
+
+// logUploadTimeout bounds storing a transcript or failure bundle
+const logUploadTimeout = 5 * time.Minute
+
+// logStore returns where transcripts and failure bundles are kept
+func (r *Runner) logStore() storage.Store {
+	if r.config.LogDest != "" {
+		return storage.Open(r.config.LogDest)
+	}
+	return storage.Open(layout.ForPlan(r.planPath).Path(layout.Logs))
+}
+
+// runKey names this run's directory in the log store, e.g.
+// 20260117-103000-3f9a2c1e
+func (r *Runner) runKey() string {
+	key := r.runStartedAt.Format("20060102-150405")
+	if r.runID != "" {
+		key += "-" + r.runID
+	}
+	return key
+}
+
+// saveTranscript stores the full prompt and output of an attempt under
+// transcripts/<run>/ in the log store and returns where the output went,
+// or "" if it couldn't be stored. Failures are recorded as warnings.
+func (r *Runner) saveTranscript(step int, attempt int, promptText string, output string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), logUploadTimeout)
+	defer cancel()
+
+	store := r.logStore()
+	base := fmt.Sprintf("transcripts/%s/step-%d-attempt-%d", r.runKey(), step, attempt)
+	if _, err := store.Write(ctx, base+".prompt.md", []byte(promptText)); err != nil {
+		r.warnings.Add(WarningLogs, "failed to store prompt in %s: %v", store.Name(), err)
+		return ""
+	}
+	location, err := store.Write(ctx, base+".log", []byte(output))
+	if err != nil {
+		r.warnings.Add(WarningLogs, "failed to store transcript in %s: %v", store.Name(), err)
+		return ""
+	}
+	return r.relativeToPlan(location)
+}
+
+// relativeToPlan shortens a local path under the plan's directory to a
+// path relative to it, so the plan stays valid when the project moves.
+// URLs and other paths are returned as they are.
+func (r *Runner) relativeToPlan(location string) string {
+	if !filepath.IsAbs(location) {
+		return location
+	}
+	planDir, err := filepath.Abs(filepath.Dir(r.planPath))
+	if err != nil {
+		return location
+	}
+	rel, err := filepath.Rel(planDir, location)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return location
+	}
+	return filepath.ToSlash(rel)
+}
+
+// uploadFailureBundle copies a failure bundle to a remote log store and
+// returns its location, or "" when logs are kept locally
+func (r *Runner) uploadFailureBundle(dir string) string {
+	if r.config.LogDest == "" || storage.IsLocal(r.config.LogDest) {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), logUploadTimeout)
+	defer cancel()
+
+	store := r.logStore()
+	url, err := store.Copy(ctx, "failures/"+filepath.Base(dir), dir)
+	if err != nil {
+		r.warnings.Add(WarningLogs, "failed to upload failure bundle to %s: %v", store.Name(), err)
+		return ""
+	}
+	return url
+}
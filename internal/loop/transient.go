@@ -0,0 +1,92 @@
+package loop
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/agent"
+)
+
+const (
+	crashWindow        = 2 * time.Minute // Abnormal exits sooner than this may be crashes rather than model failures
+	crashOutputLimit   = 1024            // ... if they printed less than this
+	networkOutputLimit = 4096            // Network errors only count in output shorter than this
+
+	// Failure classes of attempts whose agent infrastructure failed
+	agentCrashed    = "Agent crashed"
+	agentNoOutput   = "Agent exited without output"
+	agentNetwork    = "Agent network error"
+	agentStartError = "Agent failed to start"
+)
+
+// networkErrorRegex matches connection failures as printed by agent CLIs
+// (mostly Node and Python HTTP clients)
+var networkErrorRegex = regexp.MustCompile(`(?i)\b(ECONNRESET|ECONNREFUSED|ETIMEDOUT|ENOTFOUND|EAI_AGAIN|socket hang up|fetch failed|network error|connection (?:reset|refused|timed out)|could not resolve host|temporary failure in name resolution)\b`)
+
+// transientFailure is an attempt that failed because of the agent's
+// infrastructure (its process, its connection) rather than its work on the
+// step. Such attempts are retried right away, without spending a retry.
+type transientFailure struct {
+	class  string // e.g. agentCrashed
+	detail string
+}
+
+func (f *transientFailure) String() string {
+	return fmt.Sprintf("%s: %s", f.class, f.detail)
+}
+
+// detectTransient classifies an attempt whose agent failed to start,
+// crashed, exited non-zero without printing anything, or lost its
+// connection early on. It returns nil when the attempt ended any other way.
+func detectTransient(a agent.Agent, output string, runErr error) *transientFailure {
+	if runErr != nil {
+		return &transientFailure{agentStartError, runErr.Error()}
+	}
+	if crash := detectCrash(a, output); crash != "" {
+		return &transientFailure{agentCrashed, crash}
+	}
+
+	reporter, ok := a.(agent.ExitReporter)
+	if !ok {
+		return nil
+	}
+	exit := reporter.LastExit()
+	if exit == nil || exit.Code == 0 {
+		return nil
+	}
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return &transientFailure{agentNoOutput, exit.State}
+	}
+	if len(output) >= networkOutputLimit {
+		return nil // Too much work done to blame the connection for all of it
+	}
+	if match := networkErrorRegex.FindString(output); match != "" {
+		return &transientFailure{agentNetwork, fmt.Sprintf("%s (%s)", match, exit.State)}
+	}
+	return nil
+}
+
+// detectCrash describes an agent process that was killed by a signal (a
+// segfault, an OOM kill) soon after starting and with next to no output,
+// or returns "" when the attempt ended any other way
+func detectCrash(a agent.Agent, output string) string {
+	reporter, ok := a.(agent.ExitReporter)
+	if !ok {
+		return ""
+	}
+	exit := reporter.LastExit()
+	if exit == nil || !exit.Signaled() || exit.Duration > crashWindow {
+		return ""
+	}
+	output = strings.TrimSpace(output)
+	if len(output) >= crashOutputLimit {
+		return ""
+	}
+	if output == "" {
+		return fmt.Sprintf("%s after %v, no output", exit.State, exit.Duration.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("%s after %v, %d bytes of output", exit.State, exit.Duration.Round(time.Millisecond), len(output))
+}
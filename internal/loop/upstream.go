@@ -0,0 +1,161 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/prompt"
+)
+
+// resolveUpstream fetches the configured upstream branch and returns the
+// commit it points at, or "" if it can't be resolved
+func (r *Runner) resolveUpstream() string {
+	dir := filepath.Dir(r.planPath)
+	if remote, branch, ok := strings.Cut(r.config.Upstream, "/"); ok && isGitRemote(dir, remote) {
+		if _, err := gitRun(dir, "fetch", "--quiet", remote, branch); err != nil {
+			r.warnings.Add(WarningUpstream, "failed to fetch %s: %v", r.config.Upstream, err)
+		}
+	}
+	return gitOutput(dir, "rev-parse", "--verify", "--quiet", r.config.Upstream+"^{commit}")
+}
+
+// checkUpstream runs between steps. If the upstream branch moved since the
+// last check, it reports it and, with Rebase enabled, rebases the working
+// branch onto it. Conflicts are handed to the agent as an interstitial
+// "resolve conflicts" step; if that fails the rebase is aborted and the run
+// continues on the old base.
+func (r *Runner) checkUpstream(ctx context.Context, monitor *OutputMonitor) error {
+	if r.config.Upstream == "" {
+		return nil
+	}
+	rev := r.resolveUpstream()
+	if rev == "" || rev == r.upstreamRev {
+		return nil
+	}
+
+	previous := r.upstreamRev
+	r.upstreamRev = rev
+	if previous == "" {
+		return nil // First successful resolution: nothing to compare against
+	}
+
+	r.warnings.SetStep(0) // Upstream events aren't tied to a step
+	fmt.Printf("\n=== Upstream %s moved (%s -> %s) ===\n", r.config.Upstream, shortRev(previous), shortRev(rev))
+	r.warnings.Add(WarningUpstream, "%s moved from %s to %s during the run", r.config.Upstream, shortRev(previous), shortRev(rev))
+	if !r.config.Rebase {
+		fmt.Println("Not rebasing (use --rebase to rebase between steps).")
+		return nil
+	}
+
+	dir := filepath.Dir(r.planPath)
+	if _, err := gitRun(dir, "merge-base", "--is-ancestor", rev, "HEAD"); err == nil {
+		return nil // Already based on the new upstream
+	}
+
+	fmt.Printf("Rebasing onto %s...\n", r.config.Upstream)
+	if _, err := gitRun(dir, "rebase", "--autostash", r.config.Upstream); err == nil {
+		fmt.Println("Rebased cleanly.")
+		return nil
+	}
+
+	conflicts := strings.Fields(gitOutput(dir, "diff", "--name-only", "--diff-filter=U"))
+	if len(conflicts) == 0 {
+		gitRun(dir, "rebase", "--abort")
+		r.warnings.Add(WarningUpstream, "rebase onto %s failed without conflicts; aborted", r.config.Upstream)
+		return nil
+	}
+
+	p, err := r.parsePlan()
+	if err != nil {
+		gitRun(dir, "rebase", "--abort")
+		return fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	fmt.Printf("\n=== Resolving conflicts in %d file(s) ===\n\n", len(conflicts))
+	r.setActiveAgent(r.agent)
+	killCtx, stopAttempt := context.WithCancelCause(ctx)
+	stepCtx, cancel := context.WithTimeout(killCtx, r.config.Timeout)
+	monitor.Watch(0, stopAttempt)
+	agentOutput, err := r.agent.Run(stepCtx, prompt.BuildConflictResolution(p, r.config.Upstream, conflicts), monitor)
+	monitor.Unwatch()
+	stopCause := context.Cause(killCtx)
+	cancel()
+	stopAttempt(nil)
+
+	if rebaseInProgress(dir) {
+		gitRun(dir, "rebase", "--abort")
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		reason := "the rebase was not completed"
+		var violation *PolicyViolation
+		if errors.Is(stopCause, errStalled) {
+			reason = "the agent stalled"
+		} else if errors.As(stopCause, &violation) {
+			reason = violation.Error()
+		} else if err != nil {
+			reason = err.Error()
+		} else if result := prompt.ParseResult(agentOutput, ""); !result.Success && result.Reason != "" {
+			reason = result.Reason
+		}
+		fmt.Printf("\n=== Conflict resolution failed (%s). Rebase aborted; continuing on the old base. ===\n", reason)
+		r.warnings.Add(WarningUpstream, "could not resolve conflicts rebasing onto %s (%s); rebase aborted", r.config.Upstream, reason)
+		return nil
+	}
+
+	fmt.Printf("\n=== Rebased onto %s after resolving conflicts ===\n", r.config.Upstream)
+	return nil
+}
+
+// rebaseInProgress reports whether the repository is stopped mid-rebase
+func rebaseInProgress(dir string) bool {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		path := gitOutput(dir, "rev-parse", "--git-path", name)
+		if path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isGitRemote reports whether name is a configured remote
+func isGitRemote(dir string, name string) bool {
+	for _, remote := range strings.Fields(gitOutput(dir, "remote")) {
+		if remote == name {
+			return true
+		}
+	}
+	return false
+}
+
+// gitRun runs a git command that may need an editor, returning its combined
+// output and an error that includes it
+func gitRun(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(), "GIT_EDITOR=true")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// shortRev abbreviates a commit hash for display
+func shortRev(rev string) string {
+	if len(rev) > 8 {
+		return rev[:8]
+	}
+	return rev
+}
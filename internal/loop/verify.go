@@ -0,0 +1,124 @@
+package loop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// verifyReasonLines is how many trailing output lines of a failed
+// verification are kept in the step's failure reason
+const verifyReasonLines = 5
+
+// projectVerifiers map a marker file to the idiomatic build/test command for
+// that ecosystem, in detection order
+var projectVerifiers = []struct {
+	marker  string
+	kind    string
+	command func(dir string) string
+}{
+	{"go.mod", "Go", func(string) string { return "go build ./... && go test ./..." }},
+	{"Cargo.toml", "Rust", func(string) string { return "cargo test" }},
+	{"package.json", "Node.js", npmVerifyCommand},
+	{"pyproject.toml", "Python", func(string) string { return "python -m pytest" }},
+}
+
+// DetectVerifyCommand guesses the verification command for the project in
+// dir from its manifest files. It returns "" if the project type is unknown.
+func DetectVerifyCommand(dir string) string {
+	_, command := DetectProject(dir)
+	return command
+}
+
+// DetectProject returns the project type of dir (e.g. "Go") and its
+// verification command, from the first manifest file found. The command
+// is "" when the project has no usable one (e.g. no npm test script), and
+// both are "" when the project type is unknown.
+func DetectProject(dir string) (kind string, command string) {
+	for _, v := range projectVerifiers {
+		if _, err := os.Stat(filepath.Join(dir, v.marker)); err != nil {
+			continue
+		}
+		if kind == "" {
+			kind = v.kind
+		}
+		if command := v.command(dir); command != "" {
+			return v.kind, command
+		}
+	}
+	return kind, ""
+}
+
+// npmVerifyCommand returns "npm test" only when package.json defines a real
+// test script (npm init's placeholder always fails)
+func npmVerifyCommand(dir string) string {
+	content, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return ""
+	}
+	test := pkg.Scripts["test"]
+	if test == "" || strings.Contains(test, "no test specified") {
+		return ""
+	}
+	return "npm test"
+}
+
+// runVerify runs the verification command for a step through the platform
+// shell, streaming its output. The step's number and acceptance criteria
+// are passed as RALPH_STEP and RALPH_ACCEPTANCE. It returns the combined
+// output and the command's error.
+func runVerify(ctx context.Context, command string, step *plan.Step, output io.Writer) (string, error) {
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = nil
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("RALPH_STEP=%d", step.Number),
+		"RALPH_ACCEPTANCE="+step.Acceptance,
+	)
+
+	var collected strings.Builder
+	cmd.Stdout = io.MultiWriter(output, &collected)
+	cmd.Stderr = cmd.Stdout
+	err := cmd.Run()
+	return collected.String(), err
+}
+
+// shellCommand runs command through the platform's shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// verifyFailureReason summarizes a failed verification on one line
+func verifyFailureReason(command string, output string, err error) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > verifyReasonLines {
+		lines = lines[len(lines)-verifyReasonLines:]
+	}
+
+	reason := fmt.Sprintf("verification `%s` failed (%v)", command, err)
+	if len(lines) > 0 {
+		reason += ": " + strings.Join(lines, " | ")
+	}
+	return reason
+}
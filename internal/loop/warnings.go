@@ -0,0 +1,106 @@
+package loop
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Warning kinds
+const (
+	WarningPrompt      = "prompt"       // Agent appeared to ask for user input
+	WarningStall       = "stall"        // Agent produced no output for a while
+	WarningLargePrompt = "large-prompt" // Prompt exceeded the size threshold
+	WarningSlowStep    = "slow-step"    // Step used most of its timeout
+	WarningAgent       = "agent"        // Agent reported a non-fatal problem (e.g. truncated output)
+	WarningInjection   = "injection"    // Prompt data contains text that looks like a prompt injection
+	WarningArtifacts   = "artifacts"    // An artifact could not be found or uploaded
+	WarningUpstream    = "upstream"     // The upstream branch moved or could not be rebased onto
+	WarningBudget      = "budget"       // A step or run budget could not be enforced
+	WarningLogs        = "logs"         // A transcript or failure bundle could not be stored
+	WarningRateLimit   = "rate-limit"   // The agent hit an API rate limit and the attempt was rerun
+	WarningContext     = "context"      // A context provider failed and its section was left out
+	WarningPolicy      = "policy"       // The agent ran a command on the denylist and the attempt was stopped
+	WarningGitHub      = "github"       // A GitHub commit status could not be published
+	WarningHook        = "hook"         // A post_step, on_failure or on_complete hook failed
+	WarningRollback    = "rollback"     // A failed attempt's edits could not be rolled back
+	WarningReview      = "review"       // The reviewer agent failed or gave no verdict, so a step went unreviewed
+	WarningPlanAdd     = "plan-add"     // Steps an agent asked to add to the plan were dropped: over the cap, or the plan is frozen
+	WarningCheckpoint  = "checkpoint"   // The working tree could not be checkpointed before a step
+	WarningNotify      = "notify"       // A notification could not be sent
+	WarningWebhook     = "webhook"      // A webhook event could not be delivered
+)
+
+// Warning is a non-fatal issue noticed during a run
+type Warning struct {
+	Step    int    `json:"step,omitempty"` // 0 when not tied to a step
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// String formats the warning as "Step 2 [stall]: message", or
+// "[kind]: message" when it isn't tied to a step
+func (w Warning) String() string {
+	if w.Step > 0 {
+		return fmt.Sprintf("Step %d [%s]: %s", w.Step, w.Kind, w.Message)
+	}
+	return fmt.Sprintf("[%s]: %s", w.Kind, w.Message)
+}
+
+// WarningCollector gathers warnings during a run so they can be summarized
+// at exit and in the run record instead of scrolling away mid-stream
+type WarningCollector struct {
+	mu       sync.Mutex
+	step     int
+	warnings []Warning
+}
+
+// NewWarningCollector creates an empty collector
+func NewWarningCollector() *WarningCollector {
+	return &WarningCollector{}
+}
+
+// SetStep sets the step that subsequent warnings are attributed to
+func (c *WarningCollector) SetStep(step int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.step = step
+}
+
+// Add records a warning for the current step. Repeats of an identical
+// warning (e.g. on every retry of the same step) are recorded once.
+func (c *WarningCollector) Add(kind string, format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	warning := Warning{
+		Step:    c.step,
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	}
+	for _, existing := range c.warnings {
+		if existing == warning {
+			return
+		}
+	}
+	c.warnings = append(c.warnings, warning)
+}
+
+// Warnings returns a copy of the collected warnings
+func (c *WarningCollector) Warnings() []Warning {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Warning(nil), c.warnings...)
+}
+
+// Print writes a consolidated warnings section, or nothing if there are none
+func (c *WarningCollector) Print(w io.Writer) {
+	warnings := c.Warnings()
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n=== Warnings (%d) ===\n", len(warnings))
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  %s\n", warning)
+	}
+}
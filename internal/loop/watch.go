@@ -0,0 +1,75 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// watchPollInterval is how often a watching run rereads the plan
+const watchPollInterval = 2 * time.Second
+
+// awaitNewSteps keeps a --watch run alive once no step can run, until the
+// plan changes so that one can: typically a pending step appended to it, or
+// a skipped one reset. It returns false when the run is stopped while
+// waiting.
+func (r *Runner) awaitNewSteps(ctx context.Context, next plan.OrderStrategy) (bool, error) {
+	last, err := os.ReadFile(r.planPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read plan file: %w", err)
+	}
+	fmt.Printf("\nWatching %s for new steps. Press Ctrl+C to stop.\n", r.planPath)
+	r.writeWatchingState()
+
+	for {
+		select {
+		case <-time.After(watchPollInterval):
+		case <-ctx.Done():
+			fmt.Println("\nStopped watching.")
+			return false, nil
+		}
+		if r.stopRequested.Load() {
+			fmt.Println("\nStopped watching.")
+			return false, nil
+		}
+
+		content, err := os.ReadFile(r.planPath)
+		if err != nil {
+			return false, fmt.Errorf("failed to read plan file: %w", err)
+		}
+		if bytes.Equal(content, last) {
+			continue
+		}
+		last = content
+
+		// The plan may be caught halfway through an edit; the next change
+		// is parsed again
+		p, err := r.parsePlan()
+		if err != nil {
+			fmt.Printf("Warning: failed to parse the changed plan: %v\n", err)
+			continue
+		}
+		if step := next(p); step != nil {
+			fmt.Printf("\n=== Plan changed: step %d is ready to run ===\n", step.Number)
+			return true, nil
+		}
+	}
+}
+
+// writeWatchingState publishes that the run is idle, watching the plan
+func (r *Runner) writeWatchingState() {
+	state := &State{
+		PID:          os.Getpid(),
+		Agent:        r.agent.Name(),
+		PlanPath:     r.planPath,
+		RunStartedAt: r.runStartedAt,
+		Phase:        PhaseWatching,
+	}
+	if err := writeState(r.planPath, state); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
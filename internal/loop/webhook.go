@@ -0,0 +1,377 @@
+package loop
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// Webhook events
+const (
+	EventRunStarted    = "run_started"
+	EventStepStarted   = "step_started"
+	EventStepCompleted = "step_completed"
+	EventStepFailed    = "step_failed"
+	EventStepSkipped   = "step_skipped"
+	EventRunFinished   = "run_finished"
+)
+
+// WebhookEvents are the events a webhook can receive, in the order a run
+// sends them
+var WebhookEvents = []string{EventRunStarted, EventStepStarted, EventStepCompleted, EventStepFailed, EventStepSkipped, EventRunFinished}
+
+const (
+	webhookTimeout        = 10 * time.Second // Bounds each delivery attempt
+	webhookRetryDelay     = time.Second      // Before the first retry; doubles after each
+	webhookQueueSize      = 64               // Events waiting to be delivered before new ones are dropped
+	webhookDrainTimeout   = 15 * time.Second // How long the run waits at exit for queued events
+	DefaultWebhookRetries = 3                // Retries of a failed delivery
+)
+
+// WebhookSecretEnv names the environment variable holding the key events
+// are signed with
+const WebhookSecretEnv = "RALPH_LOOP_WEBHOOK_SECRET"
+
+// WebhookSettings configure the HTTP endpoint run events are posted to
+type WebhookSettings struct {
+	URL     string
+	Headers map[string]string // Extra request headers; $VAR and ${VAR} are expanded from the environment
+	Events  []string          // Events to send; all when empty
+	Retries int               // Retries of a delivery that failed with a network error, 429 or 5xx
+	Secret  string            // Signs each body as X-Ralph-Loop-Signature when set
+}
+
+// WebhookEvent is the JSON body posted for each event
+type WebhookEvent struct {
+	Event    string         `json:"event"`
+	Time     time.Time      `json:"time"`
+	RunID    string         `json:"run_id,omitempty"`
+	Plan     string         `json:"plan"`
+	Project  string         `json:"project,omitempty"`
+	Step     *WebhookStep   `json:"step,omitempty"`
+	Progress *WebhookCounts `json:"progress,omitempty"`
+	Run      *WebhookRunEnd `json:"run,omitempty"`
+}
+
+// WebhookStep describes the step a step event is about
+type WebhookStep struct {
+	Number      int     `json:"number"`
+	Description string  `json:"description"`
+	Attempt     int     `json:"attempt"`
+	MaxAttempts int     `json:"max_attempts"`
+	Agent       string  `json:"agent,omitempty"`
+	Status      string  `json:"status,omitempty"`     // Plan status after the attempt
+	Reason      string  `json:"reason,omitempty"`     // Why the attempt failed
+	WillRetry   bool    `json:"will_retry,omitempty"` // The failed step has attempts left
+	DurationSec float64 `json:"duration_sec,omitempty"`
+}
+
+// WebhookCounts is the plan's progress when an event is sent
+type WebhookCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	Pending   int `json:"pending"`
+}
+
+// WebhookRunEnd describes how a run ended
+type WebhookRunEnd struct {
+	Outcome     string  `json:"outcome"` // completed, incomplete, failed or interrupted
+	Error       string  `json:"error,omitempty"`
+	DurationSec float64 `json:"duration_sec"`
+	Attempts    int     `json:"attempts"`
+	CostUSD     float64 `json:"cost_usd,omitempty"`
+}
+
+// webhookSender delivers events in the order they were sent, in the
+// background, so a slow endpoint doesn't hold up the loop
+type webhookSender struct {
+	settings *WebhookSettings
+	client   *http.Client
+	queue    chan WebhookEvent
+	done     chan struct{}
+	warnings *WarningCollector
+	run      context.Context    // Cancelled when the run is stopped; failed deliveries aren't retried after
+	abandon  context.Context    // Cancelled to give up on the events not yet delivered
+	giveUp   context.CancelFunc // Cancels abandon
+}
+
+// startWebhook starts delivering events, when a webhook is configured.
+// Once ctx is cancelled, failed deliveries are no longer retried; once
+// abandon is, undelivered events are dropped.
+func (r *Runner) startWebhook(ctx context.Context, abandon context.Context) {
+	settings := r.config.Notify.Webhook
+	if settings == nil {
+		return
+	}
+	w := &webhookSender{
+		settings: settings,
+		client:   &http.Client{Timeout: webhookTimeout},
+		queue:    make(chan WebhookEvent, webhookQueueSize),
+		done:     make(chan struct{}),
+		warnings: r.warnings,
+		run:      ctx,
+	}
+	w.abandon, w.giveUp = context.WithCancel(abandon)
+	go w.deliverAll()
+	r.webhook = w
+}
+
+// stopWebhook waits for the events already sent to be delivered, for up to
+// webhookDrainTimeout
+func (r *Runner) stopWebhook() {
+	w := r.webhook
+	if w == nil {
+		return
+	}
+	r.webhook = nil
+	close(w.queue)
+	timer := time.NewTimer(webhookDrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+		w.giveUp()
+		return
+	case <-timer.C:
+	case <-w.abandon.Done():
+	}
+	w.giveUp()
+	<-w.done
+}
+
+// deliverAll posts queued events until the queue is closed. Events left
+// once delivery is given up on are counted in a single warning.
+func (w *webhookSender) deliverAll() {
+	defer close(w.done)
+	dropped := 0
+	for event := range w.queue {
+		if w.abandon.Err() != nil {
+			dropped++
+			continue
+		}
+		if err := w.deliver(event); err != nil {
+			if w.abandon.Err() != nil {
+				dropped++
+				continue
+			}
+			w.warnings.Add(WarningWebhook, "%s event not delivered: %v", event.Event, err)
+		}
+	}
+	if dropped > 0 {
+		w.warnings.Add(WarningWebhook, "%d event(s) not delivered: stopped waiting for the webhook at exit", dropped)
+	}
+}
+
+// deliver posts an event, retrying with a doubling delay while the
+// failure might be temporary and the run hasn't been stopped
+func (w *webhookSender) deliver(event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	delay := webhookRetryDelay
+	for retry := 0; ; retry++ {
+		temporary, err := w.post(body)
+		if err == nil || !temporary || retry >= w.settings.Retries || w.run.Err() != nil {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-w.run.Done():
+			return err
+		case <-w.abandon.Done():
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// post sends one delivery attempt, reporting whether a failure is worth
+// retrying
+func (w *webhookSender) post(body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(w.abandon, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.settings.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ralph-loop")
+	for name, value := range w.settings.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	if w.settings.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.settings.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Ralph-Loop-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("POST: %s", resp.Status)
+		if detail = bytes.TrimSpace(detail); len(detail) > 0 {
+			err = fmt.Errorf("POST: %s: %s", resp.Status, detail)
+		}
+		return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+	}
+	return false, nil
+}
+
+// emit queues an event for delivery, when a webhook is configured and
+// takes the event. An event that doesn't fit in a full queue is dropped
+// with a warning rather than holding up the loop.
+func (r *Runner) emit(event WebhookEvent) {
+	if r.webhook == nil {
+		return
+	}
+	if events := r.webhook.settings.Events; len(events) > 0 && !slices.Contains(events, event.Event) {
+		return
+	}
+	event.Time = time.Now().UTC()
+	event.RunID = r.runID
+	event.Plan = r.planPath
+	select {
+	case r.webhook.queue <- event:
+	default:
+		r.warnings.Add(WarningWebhook, "%s event dropped: the webhook is not keeping up", event.Event)
+	}
+}
+
+// webhookProgress counts the plan's steps by status
+func webhookProgress(p *plan.Plan) *WebhookCounts {
+	counts := &WebhookCounts{Total: len(p.Steps)}
+	for _, step := range p.Steps {
+		switch step.Status {
+		case plan.StatusCompleted:
+			counts.Completed++
+		case plan.StatusFailed:
+			counts.Failed++
+		case plan.StatusSkipped:
+			counts.Skipped++
+		default:
+			counts.Pending++
+		}
+	}
+	return counts
+}
+
+// emitRunStarted announces the start of a run
+func (r *Runner) emitRunStarted() {
+	if r.webhook == nil {
+		return
+	}
+	event := WebhookEvent{Event: EventRunStarted}
+	if p, err := r.parsePlan(); err == nil {
+		event.Project = p.ProjectName
+		event.Progress = webhookProgress(p)
+	}
+	r.emit(event)
+}
+
+// emitStepStarted announces a step's attempt
+func (r *Runner) emitStepStarted(p *plan.Plan, step *plan.Step) {
+	if r.webhook == nil {
+		return
+	}
+	r.emit(WebhookEvent{
+		Event:   EventStepStarted,
+		Project: p.ProjectName,
+		Step: &WebhookStep{
+			Number:      step.Number,
+			Description: step.Description,
+			Attempt:     step.RetryCount + 1,
+			MaxAttempts: r.maxRetries(step),
+			Agent:       r.activeAgent().Name(),
+		},
+		Progress: webhookProgress(p),
+	})
+}
+
+// emitStepResult announces the outcome of a step's attempt. A step marked
+// skipped after running out of retries gets step_skipped instead: no attempt
+// ran, and its last one already sent step_failed.
+func (r *Runner) emitStepResult(step *plan.Step, result plan.StepResult) {
+	if r.webhook == nil {
+		return
+	}
+	info := &WebhookStep{
+		Number:      step.Number,
+		Description: step.Description,
+		Attempt:     result.RetryCount,
+		MaxAttempts: r.maxRetries(step),
+		Status:      string(plan.StatusCompleted),
+	}
+	event := WebhookEvent{Event: EventStepCompleted, Step: info}
+	switch {
+	case result.Success:
+		info.Attempt = step.RetryCount + 1
+	case result.Status == plan.StatusSkipped:
+		event.Event = EventStepSkipped
+		info.Status = string(plan.StatusSkipped)
+		info.Reason = result.Reason
+	default:
+		event.Event = EventStepFailed
+		info.Status = string(plan.StatusFailed)
+		info.Reason = result.Reason
+		info.WillRetry = result.RetryCount < info.MaxAttempts
+	}
+	if a := result.Attempt; a != nil {
+		info.Agent = a.Agent
+		info.DurationSec = a.Duration.Seconds()
+	}
+	if p, err := r.parsePlan(); err == nil {
+		event.Project = p.ProjectName
+		event.Progress = webhookProgress(p)
+	}
+	r.emit(event)
+}
+
+// emitRunFinished announces how the run ended, then waits for the queued
+// events to be delivered
+func (r *Runner) emitRunFinished(runErr error) {
+	if r.webhook == nil {
+		return
+	}
+	cost, attempts := r.spent()
+	end := &WebhookRunEnd{
+		Outcome:     "incomplete",
+		DurationSec: time.Since(r.runStartedAt).Round(time.Second).Seconds(),
+		Attempts:    attempts,
+		CostUSD:     cost,
+	}
+	event := WebhookEvent{Event: EventRunFinished, Run: end}
+	p, err := r.parsePlan()
+	if err == nil {
+		event.Project = p.ProjectName
+		event.Progress = webhookProgress(p)
+	}
+	switch {
+	case runErr != nil && errors.Is(runErr, context.Canceled):
+		end.Outcome = "interrupted"
+	case runErr != nil:
+		end.Outcome = "failed"
+		end.Error = runErr.Error()
+	case err == nil && p.IsComplete() && !hasSkipped(p):
+		end.Outcome = "completed"
+	}
+	r.emit(event)
+	r.stopWebhook()
+}
@@ -0,0 +1,71 @@
+package loop
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+)
+
+// Worktree is a git worktree created for an isolated run, checked out on a
+// new branch from the current commit
+type Worktree struct {
+	Path   string // Root of the worktree
+	Branch string // Branch the run's commits go to
+	Base   string // Commit the branch starts from
+	Dirty  bool   // The original checkout had uncommitted changes, which the worktree lacks
+}
+
+// CreateWorktree adds a worktree for running planPath in isolation, at
+// .ralph-loop/worktrees/<plan>-<time> next to the plan, on a new
+// ralph-loop/<plan>-<time> branch
+func CreateWorktree(planPath string) (*Worktree, error) {
+	dir := filepath.Dir(planPath)
+	base := gitOutput(dir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if base == "" {
+		return nil, fmt.Errorf("--worktree needs a git repository with at least one commit")
+	}
+	root := layout.ForPlan(planPath)
+	if err := root.Ensure(); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(planPath), filepath.Ext(planPath)) + "-" + time.Now().Format("20060102-150405")
+	wt := &Worktree{
+		Path:   root.Path(layout.Worktrees, name),
+		Branch: "ralph-loop/" + name,
+		Base:   base,
+		Dirty:  gitOutput(dir, "status", "--porcelain", "--untracked-files=no") != "",
+	}
+	if _, err := gitRun(dir, "worktree", "add", "--quiet", "-b", wt.Branch, wt.Path, base); err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(wt.Path); err == nil {
+		wt.Path = abs
+	}
+	return wt, nil
+}
+
+// Dir returns the directory in the worktree that corresponds to dir in the
+// original checkout
+func (wt *Worktree) Dir(dir string) (string, error) {
+	top := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if top == "" {
+		return "", fmt.Errorf("%s is not in a git repository", dir)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	// Resolve symlinks on both sides, as git reports the real path
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	rel, err := filepath.Rel(top, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(wt.Path, rel), nil
+}
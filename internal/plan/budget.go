@@ -0,0 +1,85 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// budgetBannerPrefix starts the line left under the project title by a run
+// that stopped on one of its budget limits
+const budgetBannerPrefix = "> **Budget exhausted**: "
+
+// MarkBudgetExhausted notes under the plan's title why a run stopped on a
+// budget limit, e.g. "max cost $10.00 reached ($10.37 spent)", replacing
+// the note of an earlier run. A frozen plan stays sealed.
+func MarkBudgetExhausted(path string, note string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+	if err := CheckFrozen(string(content)); err != nil {
+		return err
+	}
+	return SaveContent(path, resealFrozen(setBudgetBanner(string(content), note)))
+}
+
+// ClearBudgetExhausted removes the note left by MarkBudgetExhausted, if any
+func ClearBudgetExhausted(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+	if BudgetNote(string(content)) == "" {
+		return nil
+	}
+	if err := CheckFrozen(string(content)); err != nil {
+		return err
+	}
+	return SaveContent(path, resealFrozen(removeBudgetBanner(string(content))))
+}
+
+// BudgetNote returns the note of a run that stopped on a budget limit, or
+// "" if the plan has none
+func BudgetNote(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if note, ok := strings.CutPrefix(line, budgetBannerPrefix); ok {
+			return note
+		}
+	}
+	return ""
+}
+
+// setBudgetBanner inserts the banner line below the project title, and
+// below the freeze banner of a frozen plan
+func setBudgetBanner(content string, note string) string {
+	lines := strings.Split(removeBudgetBanner(content), "\n")
+	insertAt := frontmatterEnd(lines)
+	for i, line := range lines {
+		if projectNameRegex.MatchString(line) {
+			insertAt = i + 1
+		}
+		if line == frozenBanner {
+			insertAt = i + 1
+			break
+		}
+	}
+	return strings.Join(insertLines(lines, insertAt, "", budgetBannerPrefix+note), "\n")
+}
+
+// removeBudgetBanner removes the banner line and the blank line added
+// before it
+func removeBudgetBanner(content string) string {
+	lines := strings.Split(content, "\n")
+	var kept []string
+	for i := 0; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], budgetBannerPrefix) {
+			if len(kept) > 0 && kept[len(kept)-1] == "" && i+1 < len(lines) && lines[i+1] == "" {
+				kept = kept[:len(kept)-1]
+			}
+			continue
+		}
+		kept = append(kept, lines[i])
+	}
+	return strings.Join(kept, "\n")
+}
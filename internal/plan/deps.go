@@ -0,0 +1,176 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ready reports whether all of a step's dependencies have completed
+func (p *Plan) Ready(step *Step) bool {
+	for _, dep := range step.After {
+		d := p.stepByNumber(dep)
+		if d == nil || d.Status != StatusCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// Blocked returns the steps that can't run because a dependency was
+// skipped, directly or through another blocked step
+func (p *Plan) Blocked() []Step {
+	var blocked []Step
+	for _, step := range p.Steps {
+		if step.Status == StatusBlocked {
+			blocked = append(blocked, step)
+		}
+	}
+	return blocked
+}
+
+// BlockedReason explains why a blocked step can't run, e.g. "step 2 was
+// skipped"
+func (p *Plan) BlockedReason(step *Step) string {
+	if dep := p.stepByNumber(step.BlockedBy); dep != nil && dep.Status == StatusBlocked {
+		return fmt.Sprintf("step %d is blocked", step.BlockedBy)
+	}
+	return fmt.Sprintf("step %d was skipped", step.BlockedBy)
+}
+
+// Waiting returns the pending and failed steps whose dependencies haven't
+// completed yet
+func (p *Plan) Waiting() []Step {
+	var waiting []Step
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if (step.Status == StatusPending || step.Status == StatusFailed) && !p.Ready(step) {
+			waiting = append(waiting, *step)
+		}
+	}
+	return waiting
+}
+
+// propagateBlocked marks pending and failed steps blocked when one of their
+// dependencies was skipped or is itself blocked. Blocking is derived from
+// the dependencies' statuses on every parse, so resetting a skipped step
+// unblocks its dependents.
+func (p *Plan) propagateBlocked() {
+	for changed := true; changed; {
+		changed = false
+		for i := range p.Steps {
+			step := &p.Steps[i]
+			if step.Status != StatusPending && step.Status != StatusFailed {
+				continue
+			}
+			for _, dep := range step.After {
+				if d := p.stepByNumber(dep); d != nil && (d.Status == StatusSkipped || d.Status == StatusBlocked) {
+					step.Status = StatusBlocked
+					step.BlockedBy = dep
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// stepByNumber returns the step with the given number, or nil
+func (p *Plan) stepByNumber(number int) *Step {
+	if number < 1 || number > len(p.Steps) {
+		return nil
+	}
+	return &p.Steps[number-1]
+}
+
+// checkDependencies reports dependencies on steps that don't exist, on the
+// step itself, and cycles, none of which can ever be satisfied
+func checkDependencies(p *Plan) []Issue {
+	var issues []Issue
+	for _, step := range p.Steps {
+		for _, dep := range step.After {
+			var message string
+			switch {
+			case dep == step.Number:
+				message = "depends on itself"
+			case p.stepByNumber(dep) == nil:
+				message = fmt.Sprintf("depends on step %d, which doesn't exist", dep)
+			}
+			if message != "" {
+				issues = append(issues, Issue{Severity: SeverityError, Rule: "step-dependency", Step: step.Number, Message: message})
+			}
+		}
+	}
+
+	// Report each cycle once, at its lowest-numbered step
+	reported := make(map[int]bool)
+	for _, step := range p.Steps {
+		cycle := p.dependencyCycle(step.Number)
+		if cycle == nil || reported[cycle[0]] {
+			continue
+		}
+		for _, n := range cycle {
+			reported[n] = true
+		}
+		parts := make([]string, len(cycle))
+		for i, n := range cycle {
+			parts[i] = fmt.Sprintf("%d", n)
+		}
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Rule:     "step-dependency",
+			Step:     cycle[0],
+			Message:  fmt.Sprintf("dependency cycle: steps %s -> %d", strings.Join(parts, " -> "), cycle[0]),
+		})
+	}
+	return issues
+}
+
+// dependencyCycle returns the steps of a dependency cycle through start,
+// beginning with its lowest-numbered step, or nil if there is none
+func (p *Plan) dependencyCycle(start int) []int {
+	var path []int
+	onPath := make(map[int]bool)
+	visited := make(map[int]bool)
+	var walk func(n int) []int
+	walk = func(n int) []int {
+		if onPath[n] {
+			if n != start {
+				return nil // A cycle, but not through start
+			}
+			return append([]int(nil), path...)
+		}
+		if visited[n] {
+			return nil
+		}
+		visited[n] = true
+		step := p.stepByNumber(n)
+		if step == nil {
+			return nil
+		}
+		onPath[n] = true
+		path = append(path, n)
+		for _, dep := range step.After {
+			if dep == n {
+				continue // Reported as a self-dependency
+			}
+			if cycle := walk(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[n] = false
+		return nil
+	}
+
+	cycle := walk(start)
+	if cycle == nil {
+		return nil
+	}
+	lowest := 0
+	for i, n := range cycle {
+		if n < cycle[lowest] {
+			lowest = i
+		}
+	}
+	return append(cycle[lowest:], cycle[:lowest]...)
+}
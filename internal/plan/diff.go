@@ -0,0 +1,100 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StepDiff is a step that differs between two versions of a plan
+type StepDiff struct {
+	Old *Step // nil when the step was added
+	New *Step // nil when the step was removed
+}
+
+// Changes describes what changed in a step present in both versions, one
+// line per field, e.g. "status: pending -> completed"
+func (d StepDiff) Changes() []string {
+	if d.Old == nil || d.New == nil {
+		return nil
+	}
+	var changes []string
+	if d.Old.Number != d.New.Number {
+		changes = append(changes, fmt.Sprintf("renumbered: %d -> %d", d.Old.Number, d.New.Number))
+	}
+	if d.Old.Status != d.New.Status {
+		changes = append(changes, fmt.Sprintf("status: %s -> %s", d.Old.Status, d.New.Status))
+	}
+	if d.Old.Notes != d.New.Notes {
+		if d.New.Notes == "" {
+			changes = append(changes, "notes: cleared")
+		} else {
+			changes = append(changes, "notes: "+d.New.Notes)
+		}
+	}
+	if d.Old.RetryCount != d.New.RetryCount {
+		changes = append(changes, fmt.Sprintf("retries: %d -> %d", d.Old.RetryCount, d.New.RetryCount))
+	}
+	if d.Old.Metadata() != d.New.Metadata() {
+		changes = append(changes, fmt.Sprintf("annotation: %s -> %s", orNone(d.Old.Metadata()), orNone(d.New.Metadata())))
+	}
+	if before, after := subStepsDone(d.Old), subStepsDone(d.New); before != after {
+		changes = append(changes, fmt.Sprintf("sub-steps checked: %s -> %s", before, after))
+	}
+	return changes
+}
+
+// subStepsDone counts a step's checked sub-steps, e.g. "2/3"
+func subStepsDone(s *Step) string {
+	done := 0
+	for _, sub := range s.SubSteps {
+		if sub.Done {
+			done++
+		}
+	}
+	return fmt.Sprintf("%d/%d", done, len(s.SubSteps))
+}
+
+// orNone stands in for an empty value in a change description
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// DiffSteps compares the steps of two versions of a plan and returns the
+// ones added, removed or changed, in the new plan's order with removed
+// steps last. Steps are matched by description, so a step moved by
+// `plan move` shows as renumbered, and a reworded one as removed and added.
+func DiffSteps(old *Plan, new *Plan) []StepDiff {
+	matched := make(map[int]*Step) // Index of a new step -> its old step
+	used := make(map[int]bool)     // Indexes of the old steps matched
+	for i := range new.Steps {
+		for j := range old.Steps {
+			if !used[j] && sameDescription(&old.Steps[j], &new.Steps[i]) {
+				matched[i], used[j] = &old.Steps[j], true
+				break
+			}
+		}
+	}
+
+	var diffs []StepDiff
+	for i := range new.Steps {
+		d := StepDiff{Old: matched[i], New: &new.Steps[i]}
+		if d.Old == nil || len(d.Changes()) > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+	for j := range old.Steps {
+		if !used[j] {
+			diffs = append(diffs, StepDiff{Old: &old.Steps[j]})
+		}
+	}
+	return diffs
+}
+
+// sameDescription reports whether two steps have the same description,
+// ignoring case and spacing
+func sameDescription(a *Step, b *Step) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a.Description), " "), strings.Join(strings.Fields(b.Description), " "))
+}
@@ -0,0 +1,75 @@
+package plan
+
+import "time"
+
+// TimeSpent sums the durations in a step's attempt history
+func (s *Step) TimeSpent() time.Duration {
+	var d time.Duration
+	for _, a := range s.Attempts {
+		d += a.Duration
+	}
+	return d
+}
+
+// Forecast projects how much agent time the plan's remaining steps need,
+// from their estimates and the pace of the steps completed so far
+type Forecast struct {
+	Remaining time.Duration // Projected agent time for the steps that can still run
+	Steps     int           // Steps that can still run
+	Unknown   int           // Of those, steps with no estimate and no history to go on
+
+	// Projected is each remaining step's projected time, by step number
+	Projected map[int]time.Duration
+
+	// Pace is the time completed steps took per unit of their estimates,
+	// e.g. 1.5 when they ran 50% over; 1 until an estimated step completes
+	Pace float64
+	// Basis is how many completed steps with recorded attempts the
+	// projection draws on
+	Basis int
+}
+
+// Forecast projects the time left on the plan. A step's estimate is scaled
+// by the pace of the completed estimated steps; a step without one is
+// taken to need the average time of the completed steps. Skipped and
+// blocked steps won't run, so they don't count.
+func (p *Plan) Forecast() Forecast {
+	var spent, estimated, average time.Duration
+	f := Forecast{Pace: 1, Projected: make(map[int]time.Duration)}
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if step.Status != StatusCompleted || len(step.Attempts) == 0 {
+			continue
+		}
+		f.Basis++
+		average += step.TimeSpent()
+		if step.Estimate > 0 {
+			spent += step.TimeSpent()
+			estimated += step.Estimate
+		}
+	}
+	if estimated > 0 {
+		f.Pace = float64(spent) / float64(estimated)
+	}
+	if f.Basis > 0 {
+		average /= time.Duration(f.Basis)
+	}
+
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if step.Status != StatusPending && step.Status != StatusFailed {
+			continue
+		}
+		f.Steps++
+		switch {
+		case step.Estimate > 0:
+			f.Projected[step.Number] = time.Duration(float64(step.Estimate) * f.Pace)
+		case f.Basis > 0:
+			f.Projected[step.Number] = average
+		default:
+			f.Unknown++
+		}
+		f.Remaining += f.Projected[step.Number]
+	}
+	return f
+}
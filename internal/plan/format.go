@@ -0,0 +1,122 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FormatVersion is the current plan format. Plans without a format marker
+// predate it and are version 1.
+const FormatVersion = 2
+
+// formatMarkerRegex matches the hidden line that records a plan's format
+var formatMarkerRegex = regexp.MustCompile(`^<!-- ralph-loop:format (\d+) -->$`)
+
+// formatMigrations upgrade a plan from the version before each one's
+// target, in order
+var formatMigrations = []struct {
+	to      int
+	apply   func(content string) (string, error)
+	summary string
+}{
+	{2, addMissingStructure, "added missing step labels, the Context section, notes sections and **Retries** fields"},
+}
+
+// formatMarker returns the marker line for a format version
+func formatMarker(version int) string {
+	return fmt.Sprintf("<!-- ralph-loop:format %d -->", version)
+}
+
+// formatVersion reads the format marker of plan content; 1 without one
+func formatVersion(lines []string) int {
+	for _, line := range lines {
+		if matches := formatMarkerRegex.FindStringSubmatch(line); matches != nil {
+			version, _ := strconv.Atoi(matches[1])
+			return version
+		}
+	}
+	return 1
+}
+
+// setFormatVersion replaces the format marker, or adds one under the
+// project title
+func setFormatVersion(content string, version int) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if formatMarkerRegex.MatchString(line) {
+			lines[i] = formatMarker(version)
+			return strings.Join(lines, "\n")
+		}
+	}
+	insertAt := frontmatterEnd(lines)
+	for i, line := range lines {
+		if projectNameRegex.MatchString(line) {
+			insertAt = i + 1
+			break
+		}
+	}
+	return strings.Join(insertLines(lines, insertAt, formatMarker(version)), "\n")
+}
+
+// Migrate upgrades a plan file written in an older format to the current
+// one, backing up the old version, and returns what each migration did.
+// A plan already in the current format is left alone.
+func Migrate(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+	if IsFrozen(string(content)) {
+		return nil, ErrPlanFrozen
+	}
+
+	updated := string(content)
+	version := formatVersion(strings.Split(updated, "\n"))
+	if version > FormatVersion {
+		return nil, fmt.Errorf("plan format %d is newer than this ralph-loop knows (%d); upgrade ralph-loop", version, FormatVersion)
+	}
+
+	var applied []string
+	for _, m := range formatMigrations {
+		if m.to <= version {
+			continue
+		}
+		if updated, err = m.apply(updated); err != nil {
+			return nil, fmt.Errorf("failed to migrate to plan format %d: %w", m.to, err)
+		}
+		updated = setFormatVersion(updated, m.to)
+		applied = append(applied, m.summary)
+	}
+	if len(applied) == 0 {
+		return nil, nil
+	}
+	return applied, SaveContent(path, updated)
+}
+
+// addMissingStructure brings a version 1 plan up to version 2: it gets a
+// Context section if it has none, and every step a notes section with a
+// **Retries** field
+func addMissingStructure(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	prose := blockLines(lines)
+	hasContext := false
+	planHeader := -1
+	for i, line := range lines {
+		switch {
+		case prose[i]:
+		case contextSectionRegex.MatchString(line):
+			hasContext = true
+		case planSectionRegex.MatchString(line) && planHeader < 0:
+			planHeader = i
+		}
+	}
+	if !hasContext && planHeader >= 0 {
+		lines = insertLines(lines, planHeader, "## Context", "")
+	}
+
+	fixed, _, err := Fix(strings.Join(lines, "\n"))
+	return fixed, err
+}
@@ -0,0 +1,122 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrFrozenPlanEdited is returned when a frozen plan no longer matches the
+// hash recorded when it was frozen, i.e. someone edited it by hand
+var ErrFrozenPlanEdited = errors.New("plan was edited while frozen")
+
+// ErrPlanFrozen is returned by manual editing commands on a frozen plan
+var ErrPlanFrozen = errors.New("plan is frozen; run 'ralph-loop unfreeze' to edit it")
+
+// frozenBanner is shown under the project title of a frozen plan
+const frozenBanner = "> **FROZEN**: ralph-loop is managing this plan. Manual edits pause the run; run `ralph-loop unfreeze` before editing."
+
+// frozenSealRegex matches the hidden seal line that records the plan's hash
+var frozenSealRegex = regexp.MustCompile(`^<!-- ralph-loop:frozen sha256=([0-9a-f]+) -->$`)
+
+// IsFrozen reports whether plan content carries a freeze seal
+func IsFrozen(content string) bool {
+	_, ok := frozenSeal(content)
+	return ok
+}
+
+// CheckFrozen returns ErrFrozenPlanEdited if the content is frozen and has
+// changed since it was sealed. Unfrozen content always passes.
+func CheckFrozen(content string) error {
+	hash, ok := frozenSeal(content)
+	if !ok {
+		return nil
+	}
+	if hash != sealHash(content) {
+		return ErrFrozenPlanEdited
+	}
+	return nil
+}
+
+// Freeze marks a plan read-only for manual edits. Freezing an already
+// frozen plan accepts its current content, which is how edits made while
+// frozen are reconciled.
+func Freeze(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+	return SaveContent(path, freezeContent(string(content)))
+}
+
+// Unfreeze removes the freeze banner and seal from a plan
+func Unfreeze(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+	return SaveContent(path, unfreezeContent(string(content)))
+}
+
+// freezeContent inserts (or refreshes) the banner and seal below the
+// project title, sealing the rest of the content
+func freezeContent(content string) string {
+	content = unfreezeContent(content)
+	seal := fmt.Sprintf("<!-- ralph-loop:frozen sha256=%s -->", sealHash(content))
+
+	lines := strings.Split(content, "\n")
+	insertAt := frontmatterEnd(lines)
+	for i, line := range lines {
+		if projectNameRegex.MatchString(line) {
+			insertAt = i + 1
+			break
+		}
+	}
+	return strings.Join(insertLines(lines, insertAt, "", seal, frozenBanner), "\n")
+}
+
+// unfreezeContent removes the banner and seal lines
+func unfreezeContent(content string) string {
+	lines := strings.Split(content, "\n")
+	var kept []string
+	for i := 0; i < len(lines); i++ {
+		if frozenSealRegex.MatchString(lines[i]) || lines[i] == frozenBanner {
+			// Drop the blank line freezeContent added before the banner
+			if len(kept) > 0 && kept[len(kept)-1] == "" && i+1 < len(lines) && lines[i+1] == "" {
+				kept = kept[:len(kept)-1]
+			}
+			continue
+		}
+		kept = append(kept, lines[i])
+	}
+	return strings.Join(kept, "\n")
+}
+
+// resealFrozen refreshes the seal of frozen content after ralph-loop itself
+// changed it; unfrozen content is returned unchanged
+func resealFrozen(content string) string {
+	if !IsFrozen(content) {
+		return content
+	}
+	return freezeContent(content)
+}
+
+// frozenSeal returns the hash recorded in the content's seal line
+func frozenSeal(content string) (string, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		if matches := frozenSealRegex.FindStringSubmatch(line); matches != nil {
+			return matches[1], true
+		}
+	}
+	return "", false
+}
+
+// sealHash hashes content without the banner and seal lines
+func sealHash(content string) string {
+	sum := sha256.Sum256([]byte(unfreezeContent(content)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,74 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxFingerprintFileSize = 1024 * 1024 // Referenced files larger than 1MB are not hashed
+
+// Matches path-like tokens in the context section (e.g. internal/db/schema.sql, go.mod)
+var pathTokenRegex = regexp.MustCompile(`[A-Za-z0-9_][A-Za-z0-9_./-]*[./][A-Za-z0-9_]+`)
+
+// ContextFingerprint hashes the plan's Context section together with the
+// contents of any files it references, relative to baseDir. Whitespace in the
+// context is normalized so reflowing text doesn't count as a change.
+func ContextFingerprint(p *Plan, baseDir string) string {
+	h := sha256.New()
+	h.Write([]byte(strings.Join(strings.Fields(p.Context), " ")))
+
+	for _, path := range ReferencedFiles(p.Context, baseDir) {
+		content, err := os.ReadFile(filepath.Join(baseDir, path))
+		if err != nil {
+			continue
+		}
+		h.Write([]byte("\x00" + path + "\x00"))
+		h.Write(content)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// ReferencedFiles returns the files mentioned in the context text that exist
+// under baseDir, sorted and de-duplicated
+func ReferencedFiles(context string, baseDir string) []string {
+	seen := make(map[string]bool)
+	var files []string
+
+	for _, token := range pathTokenRegex.FindAllString(context, -1) {
+		token = strings.TrimRight(token, ".")
+		if seen[token] || strings.Contains(token, "://") {
+			continue
+		}
+		seen[token] = true
+
+		info, err := os.Stat(filepath.Join(baseDir, token))
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxFingerprintFileSize {
+			continue
+		}
+		files = append(files, token)
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+// StaleSteps returns completed steps that were executed against a different
+// context fingerprint than the current one. Steps without a recorded
+// fingerprint are not reported.
+func StaleSteps(p *Plan, baseDir string) []Step {
+	current := ContextFingerprint(p, baseDir)
+
+	var stale []Step
+	for _, step := range p.Steps {
+		if step.Status == StatusCompleted && step.ContextHash != "" && step.ContextHash != current {
+			stale = append(stale, step)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,168 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// frontmatterDelimiter opens and closes the frontmatter block
+const frontmatterDelimiter = "---"
+
+// frontmatterKeys are the settings a plan's frontmatter may carry
+var frontmatterKeys = []string{"agent", "model", "timeout", "max_retries", "verify"}
+
+// Frontmatter holds the run defaults from a YAML frontmatter block at the
+// top of the plan. `run` uses them where no flag is given; empty fields
+// leave the setting to the config file and the defaults.
+type Frontmatter struct {
+	Agent      string
+	Model      string
+	Timeout    time.Duration
+	MaxRetries int
+	Verify     string
+}
+
+// frontmatterEnd returns the number of lines the frontmatter block takes,
+// delimiters included, or 0 when the plan has none
+func frontmatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != frontmatterDelimiter {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed == frontmatterDelimiter || trimmed == "..." {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// parseFrontmatter reads the frontmatter block, or returns nil when the
+// plan has none. Only flat "key: value" pairs are supported, with values
+// optionally quoted and comments starting with #.
+func parseFrontmatter(lines []string) (*Frontmatter, error) {
+	end := frontmatterEnd(lines)
+	if end == 0 {
+		return nil, nil
+	}
+
+	fm := &Frontmatter{}
+	seen := make(map[string]bool)
+	for i := 1; i < end-1; i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.HasPrefix(lines[i], " ") || strings.HasPrefix(lines[i], "\t") {
+			return nil, fmt.Errorf("frontmatter line %d: want 'key: value'", i+1)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("frontmatter line %d: %s is set more than once", i+1, key)
+		}
+		seen[key] = true
+
+		value, err := frontmatterValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("frontmatter line %d: %s: %w", i+1, key, err)
+		}
+		switch key {
+		case "agent":
+			fm.Agent = value
+		case "model":
+			fm.Model = value
+		case "timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("frontmatter line %d: invalid timeout %q (want a duration, e.g. 45m)", i+1, value)
+			}
+			fm.Timeout = d
+		case "max_retries":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("frontmatter line %d: invalid max_retries %q (want a whole number of at least 1)", i+1, value)
+			}
+			fm.MaxRetries = n
+		case "verify":
+			fm.Verify = value
+		default:
+			return nil, fmt.Errorf("frontmatter line %d: unknown key %q (valid: %s)", i+1, key, strings.Join(frontmatterKeys, ", "))
+		}
+	}
+	return fm, nil
+}
+
+// frontmatterValue unquotes a value, or drops a trailing comment from an
+// unquoted one
+func frontmatterValue(raw string) (string, error) {
+	value := strings.TrimSpace(raw)
+	switch {
+	case strings.HasPrefix(value, `"`):
+		end := closingQuote(value)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		unquoted, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value %s", value[:end+1])
+		}
+		return unquoted, checkTrailing(value[end+1:])
+	case strings.HasPrefix(value, "'"):
+		// A doubled single quote stands for one
+		for i := 1; i < len(value); i++ {
+			if value[i] != '\'' {
+				continue
+			}
+			if i+1 < len(value) && value[i+1] == '\'' {
+				i++
+				continue
+			}
+			return strings.ReplaceAll(value[1:i], "''", "'"), checkTrailing(value[i+1:])
+		}
+		return "", fmt.Errorf("unterminated quoted value")
+	case strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") || value == "|" || value == ">":
+		return "", fmt.Errorf("only single-line text values are supported")
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
+
+// closingQuote returns the index of the quote closing a double-quoted
+// value, or -1
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// checkTrailing allows only a comment after a quoted value
+func checkTrailing(rest string) error {
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after the quoted value", rest)
+	}
+	return nil
+}
+
+// ReadFrontmatter reads the frontmatter of the plan file at path. It
+// returns nil when the plan has none or doesn't exist yet.
+func ReadFrontmatter(path string) (*Frontmatter, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+	return parseFrontmatter(strings.Split(string(content), "\n"))
+}
@@ -0,0 +1,101 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxHistoryReason caps the failure reason kept on a history line
+const maxHistoryReason = 200
+
+var (
+	// Matches: **History**: (the attempts follow on their own lines)
+	historyRegex = regexp.MustCompile(`^\*\*History\*\*:\s*$`)
+
+	// Matches: - 2026-01-17 10:30:00 failed after 4m12s (agent: claude, model: sonnet): Tests failed
+	historyLineRegex = regexp.MustCompile(`^-\s+(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\s+(\w+)\s+after\s+([\dhms.]+)(?:\s+\(([^()]*)\))?(?::\s+(.*))?$`)
+)
+
+// historyLine formats an attempt as a line of a step's **History**
+func historyLine(a Attempt) string {
+	line := fmt.Sprintf("- %s %s after %s", a.StartedAt.Format("2006-01-02 15:04:05"), a.Status, FormatDuration(a.Duration.Round(time.Second)))
+
+	var ran []string
+	if a.Agent != "" {
+		ran = append(ran, "agent: "+a.Agent)
+	}
+	if a.Model != "" {
+		ran = append(ran, "model: "+a.Model)
+	}
+	if len(ran) > 0 {
+		line += " (" + strings.Join(ran, ", ") + ")"
+	}
+
+	// Keep the reason to one readable line; the transcript has the rest
+	if reason := strings.Join(strings.Fields(a.Reason), " "); reason != "" {
+		if len(reason) > maxHistoryReason {
+			reason = reason[:maxHistoryReason-3] + "..."
+		}
+		line += ": " + reason
+	}
+	return line
+}
+
+// parseHistoryLine reads an attempt from a line of a step's **History**
+func parseHistoryLine(line string) (Attempt, bool) {
+	matches := historyLineRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return Attempt{}, false
+	}
+	startedAt, err := time.Parse("2006-01-02 15:04:05", matches[1])
+	if err != nil {
+		return Attempt{}, false
+	}
+	duration, err := time.ParseDuration(matches[3])
+	if err != nil {
+		return Attempt{}, false
+	}
+
+	a := Attempt{
+		StartedAt: startedAt,
+		Duration:  duration,
+		Status:    StepStatus(matches[2]),
+		Reason:    strings.TrimSpace(matches[5]),
+	}
+	for _, pair := range strings.Split(matches[4], ",") {
+		key, value, _ := strings.Cut(pair, ":")
+		switch strings.TrimSpace(key) {
+		case "agent":
+			a.Agent = strings.TrimSpace(value)
+		case "model":
+			a.Model = strings.TrimSpace(value)
+		}
+	}
+	return a, true
+}
+
+// appendHistory adds an attempt to the end of a step's **History**,
+// starting the field after the section's other fields if it has none yet
+func appendHistory(content string, stepNum int, a Attempt) string {
+	lines := strings.Split(content, "\n")
+	section, ok := findNotesSections(lines)[stepNum]
+	if !ok {
+		return content
+	}
+
+	prose := proseLines(lines)
+	for i := section.start + 1; i < section.end; i++ {
+		if prose[i] || !historyRegex.MatchString(lines[i]) {
+			continue
+		}
+		end := i + 1
+		for end < section.end && historyLineRegex.MatchString(lines[end]) {
+			end++
+		}
+		return strings.Join(insertLines(lines, end, historyLine(a)), "\n")
+	}
+
+	return strings.Join(insertLines(lines, fieldsEnd(lines, prose, section), "**History**:", historyLine(a)), "\n")
+}
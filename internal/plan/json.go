@@ -0,0 +1,294 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jsonPlan is the JSON form of a plan, for tools such as project trackers
+// and dashboards that generate or consume plans programmatically
+type jsonPlan struct {
+	Project  string     `json:"project"`
+	Context  string     `json:"context,omitempty"`
+	Glossary string     `json:"glossary,omitempty"`
+	Steps    []jsonStep `json:"steps"`
+}
+
+type jsonStep struct {
+	Number      int           `json:"number"` // Informational; steps are numbered by position
+	Description string        `json:"description"`
+	SubSteps    []jsonSubStep `json:"sub_steps,omitempty"`
+	Acceptance  string        `json:"acceptance,omitempty"`
+	Status      StepStatus    `json:"status,omitempty"` // Default pending; blocked is exported but imported as pending
+	After       []int         `json:"after,omitempty"`
+	Ticket      string        `json:"ticket,omitempty"`
+	Agent       string        `json:"agent,omitempty"`
+	Model       string        `json:"model,omitempty"`
+	MaxCost     float64       `json:"max_cost,omitempty"`     // US dollars per attempt
+	MaxDuration string        `json:"max_duration,omitempty"` // e.g. "20m"
+	Estimate    string        `json:"estimate,omitempty"`     // e.g. "45m"
+	Priority    int           `json:"priority,omitempty"`     // 1 is the highest
+	Timeout     string        `json:"timeout,omitempty"`      // e.g. "90m"
+	MaxRetries  int           `json:"max_retries,omitempty"`
+	LastRun     *time.Time    `json:"last_run,omitempty"`
+	Notes       string        `json:"notes,omitempty"`
+	Retries     int           `json:"retries,omitempty"`
+	ContextHash string        `json:"context_hash,omitempty"`
+	Artifacts   []string      `json:"artifacts,omitempty"`
+	Session     string        `json:"session,omitempty"`
+	Attempts    []jsonAttempt `json:"attempts,omitempty"`
+}
+
+type jsonAttempt struct {
+	StartedAt time.Time  `json:"started_at"`
+	Duration  string     `json:"duration"` // e.g. "4m12s"
+	Status    StepStatus `json:"status"`   // completed or failed
+	Reason    string     `json:"reason,omitempty"`
+	Agent     string     `json:"agent,omitempty"`
+	Model     string     `json:"model,omitempty"`
+}
+
+type jsonSubStep struct {
+	Description string `json:"description"`
+	Done        bool   `json:"done,omitempty"`
+}
+
+// MarshalJSON encodes a plan as indented JSON: the project, context,
+// glossary and every step with its annotation and recorded progress
+func MarshalJSON(p *Plan) ([]byte, error) {
+	out := jsonPlan{
+		Project:  p.ProjectName,
+		Context:  p.Context,
+		Glossary: p.Glossary,
+		Steps:    make([]jsonStep, 0, len(p.Steps)),
+	}
+	for _, s := range p.Steps {
+		step := jsonStep{
+			Number:      s.Number,
+			Description: s.Description,
+			Acceptance:  s.Acceptance,
+			Status:      s.Status,
+			After:       s.After,
+			Ticket:      s.Ticket,
+			Agent:       s.Agent,
+			Model:       s.Model,
+			MaxCost:     s.MaxCost,
+			Priority:    s.Priority,
+			MaxRetries:  s.MaxRetries,
+			LastRun:     s.LastRun,
+			Notes:       s.Notes,
+			Retries:     s.RetryCount,
+			ContextHash: s.ContextHash,
+			Artifacts:   s.Artifacts,
+			Session:     s.SessionID,
+		}
+		if s.MaxDuration > 0 {
+			step.MaxDuration = FormatDuration(s.MaxDuration)
+		}
+		if s.Estimate > 0 {
+			step.Estimate = FormatDuration(s.Estimate)
+		}
+		if s.Timeout > 0 {
+			step.Timeout = FormatDuration(s.Timeout)
+		}
+		for _, sub := range s.SubSteps {
+			step.SubSteps = append(step.SubSteps, jsonSubStep{Description: sub.Description, Done: sub.Done})
+		}
+		for _, a := range s.Attempts {
+			step.Attempts = append(step.Attempts, jsonAttempt{
+				StartedAt: a.StartedAt,
+				Duration:  FormatDuration(a.Duration),
+				Status:    a.Status,
+				Reason:    a.Reason,
+				Agent:     a.Agent,
+				Model:     a.Model,
+			})
+		}
+		out.Steps = append(out.Steps, step)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a plan written by MarshalJSON or by another tool.
+// Only the project and step descriptions are required. Unknown fields and
+// values the markdown format can't represent are rejected.
+func UnmarshalJSON(data []byte) (*Plan, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var in jsonPlan
+	if err := dec.Decode(&in); err != nil {
+		return nil, fmt.Errorf("invalid plan JSON: %w", err)
+	}
+
+	if strings.TrimSpace(in.Project) == "" {
+		return nil, fmt.Errorf("invalid plan JSON: project is required")
+	}
+	if strings.ContainsAny(in.Project, "\r\n") {
+		return nil, fmt.Errorf("invalid plan JSON: project must be a single line")
+	}
+	if len(in.Steps) == 0 {
+		return nil, fmt.Errorf("invalid plan JSON: no steps")
+	}
+
+	p := &Plan{
+		ProjectName: strings.TrimSpace(in.Project),
+		Context:     strings.TrimSpace(in.Context),
+		Glossary:    strings.TrimSpace(in.Glossary),
+	}
+	for i, s := range in.Steps {
+		step, err := s.toStep(i + 1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plan JSON: step %d: %w", i+1, err)
+		}
+		p.Steps = append(p.Steps, step)
+	}
+	for _, step := range p.Steps {
+		for _, dep := range step.After {
+			if dep > len(p.Steps) {
+				return nil, fmt.Errorf("invalid plan JSON: step %d: depends on step %d, which doesn't exist", step.Number, dep)
+			}
+		}
+	}
+	return p, nil
+}
+
+// toStep converts a JSON step to the step numbered number
+func (s jsonStep) toStep(number int) (Step, error) {
+	step := Step{
+		Number:      number,
+		Description: strings.TrimSpace(s.Description),
+		Acceptance:  strings.TrimSpace(s.Acceptance),
+		Status:      s.Status,
+		After:       s.After,
+		Ticket:      strings.TrimSpace(s.Ticket),
+		Agent:       s.Agent,
+		Model:       s.Model,
+		MaxCost:     s.MaxCost,
+		Priority:    s.Priority,
+		MaxRetries:  s.MaxRetries,
+		LastRun:     s.LastRun,
+		Notes:       s.Notes,
+		RetryCount:  s.Retries,
+		ContextHash: s.ContextHash,
+		Artifacts:   s.Artifacts,
+		SessionID:   s.Session,
+	}
+
+	if step.Description == "" {
+		return step, fmt.Errorf("description is required")
+	}
+	for _, sub := range s.SubSteps {
+		description := strings.TrimSpace(sub.Description)
+		if description == "" || strings.ContainsAny(description, "\r\n") {
+			return step, fmt.Errorf("sub-step descriptions must be non-empty single lines")
+		}
+		step.SubSteps = append(step.SubSteps, SubStep{Description: description, Done: sub.Done})
+	}
+	if step.Status == StatusCompleted && !step.SubStepsDone() {
+		return step, fmt.Errorf("completed, but not all sub-steps are done")
+	}
+	if strings.ContainsAny(step.Description+step.Notes, "\r\n") {
+		return step, fmt.Errorf("description and notes must be single lines")
+	}
+	if step.Acceptance != "" {
+		// Such lines would end the acceptance block in the plan file
+		for _, line := range strings.Split(step.Acceptance, "\n") {
+			if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "**") || strings.HasPrefix(line, "#") {
+				return step, fmt.Errorf("acceptance lines must not be blank or start with ** or #")
+			}
+		}
+	}
+	switch step.Status {
+	case "", StatusBlocked:
+		step.Status = StatusPending
+	case StatusPending, StatusCompleted, StatusFailed, StatusSkipped:
+	default:
+		return step, fmt.Errorf("unknown status %q (valid: pending, completed, failed, skipped)", step.Status)
+	}
+	if strings.ContainsAny(step.Agent+step.Model+step.Ticket, ",()\r\n") {
+		return step, fmt.Errorf("agent, model and ticket must not contain commas, parentheses or line breaks")
+	}
+	if step.MaxCost < 0 {
+		return step, fmt.Errorf("invalid max_cost %v", step.MaxCost)
+	}
+	if s.MaxDuration != "" {
+		d, err := time.ParseDuration(s.MaxDuration)
+		if err != nil || d <= 0 {
+			return step, fmt.Errorf("invalid max_duration %q (want a duration, e.g. 20m)", s.MaxDuration)
+		}
+		step.MaxDuration = d
+	}
+	if s.Estimate != "" {
+		d, err := time.ParseDuration(s.Estimate)
+		if err != nil || d <= 0 {
+			return step, fmt.Errorf("invalid estimate %q (want a duration, e.g. 45m)", s.Estimate)
+		}
+		step.Estimate = d
+	}
+	if s.Timeout != "" {
+		d, err := time.ParseDuration(s.Timeout)
+		if err != nil || d <= 0 {
+			return step, fmt.Errorf("invalid timeout %q (want a duration, e.g. 90m)", s.Timeout)
+		}
+		step.Timeout = d
+	}
+	if step.Priority < 0 {
+		return step, fmt.Errorf("priority must not be negative")
+	}
+	if step.MaxRetries < 0 {
+		return step, fmt.Errorf("max_retries must not be negative")
+	}
+	if step.RetryCount < 0 {
+		return step, fmt.Errorf("retries must not be negative")
+	}
+	for _, dep := range step.After {
+		if dep < 1 || dep == number {
+			return step, fmt.Errorf("invalid dependency on step %d", dep)
+		}
+	}
+	for _, a := range s.Attempts {
+		attempt, err := a.toAttempt()
+		if err != nil {
+			return step, err
+		}
+		step.Attempts = append(step.Attempts, attempt)
+	}
+	return step, nil
+}
+
+// toAttempt converts a JSON attempt to an entry of a step's history
+func (a jsonAttempt) toAttempt() (Attempt, error) {
+	attempt := Attempt{
+		StartedAt: a.StartedAt,
+		Status:    a.Status,
+		Reason:    strings.TrimSpace(a.Reason),
+		Agent:     strings.TrimSpace(a.Agent),
+		Model:     strings.TrimSpace(a.Model),
+	}
+	if attempt.StartedAt.IsZero() {
+		return attempt, fmt.Errorf("attempt started_at is required")
+	}
+	d, err := time.ParseDuration(a.Duration)
+	if err != nil || d < 0 {
+		return attempt, fmt.Errorf("invalid attempt duration %q (want a duration, e.g. 4m12s)", a.Duration)
+	}
+	attempt.Duration = d
+	if attempt.Status != StatusCompleted && attempt.Status != StatusFailed {
+		return attempt, fmt.Errorf("unknown attempt status %q (valid: completed, failed)", attempt.Status)
+	}
+	if strings.ContainsAny(attempt.Agent+attempt.Model, ",()") {
+		return attempt, fmt.Errorf("attempt agent and model must not contain commas or parentheses")
+	}
+	return attempt, nil
+}
@@ -0,0 +1,435 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severity indicates how serious a validation issue is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue describes a single problem found while validating a plan
+type Issue struct {
+	Severity Severity
+	Rule     string
+	Step     int // 0 when the issue is not tied to a specific step
+	Message  string
+	Fixable  bool // Whether Fix can rewrite the plan to resolve it
+}
+
+// String formats the issue for display
+func (i Issue) String() string {
+	location := ""
+	if i.Step > 0 {
+		location = fmt.Sprintf("Step %d: ", i.Step)
+	}
+	fixable := ""
+	if i.Fixable {
+		fixable = " (fixable)"
+	}
+	return fmt.Sprintf("%s [%s] %s%s%s", i.Severity, i.Rule, location, i.Message, fixable)
+}
+
+const (
+	minDescriptionWords = 3 // Descriptions shorter than this are too vague to act on
+	maxActionVerbs      = 3 // Descriptions with this many action verbs likely bundle several steps
+)
+
+// Matches phrases that carry no actionable meaning on their own
+var vaguePhraseRegex = regexp.MustCompile(`(?i)\b(misc|miscellaneous|stuff|things|various|etc|tbd|todo|fix it|clean ?up|make it work)\b`)
+
+// Verbs that usually start a distinct unit of work
+var actionVerbRegex = regexp.MustCompile(`(?i)\b(add|implement|create|write|update|refactor|fix|remove|delete|build|test|migrate|integrate|configure|deploy|document|rename|replace|set up|install|design)\b`)
+
+// Verbs of steps that change code, which a verification command can check
+var codeVerbRegex = regexp.MustCompile(`(?i)\b(add|implement|create|write|update|refactor|fix|remove|delete|build|migrate|integrate|rename|replace)\b`)
+
+// Words of steps about documentation or research rather than code
+var nonCodeRegex = regexp.MustCompile(`(?i)\b(document|documentation|docs|readme|changelog|research|investigate|design|plan)\b`)
+
+// Validate checks plan content for structural problems and lint issues.
+// baseDir is the plan's directory, used to resolve files referenced by the context.
+func Validate(content string, baseDir string) ([]Issue, error) {
+	p, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+
+	if p.ProjectName == "" {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Rule:     "project-name",
+			Message:  "missing '# Project: Name' header",
+		})
+	}
+
+	switch {
+	case p.FormatVersion > FormatVersion:
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Rule:     "plan-format",
+			Message:  fmt.Sprintf("plan format %d is newer than this ralph-loop knows (%d); upgrade ralph-loop", p.FormatVersion, FormatVersion),
+		})
+	case p.FormatVersion < FormatVersion:
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Rule:     "plan-format",
+			Message:  fmt.Sprintf("plan format %d is older than the current %d; run 'ralph-loop plan migrate'", p.FormatVersion, FormatVersion),
+		})
+	}
+
+	if len(p.Steps) == 0 {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Rule:     "no-steps",
+			Message:  "plan has no steps; add '- [ ] Step 1: ...' lines under '## Plan'",
+		})
+	}
+
+	issues = append(issues, checkStepLabels(content)...)
+	issues = append(issues, checkNotesSections(content, len(p.Steps))...)
+	issues = append(issues, checkDependencies(p)...)
+	issues = append(issues, checkPhases(p)...)
+
+	for _, step := range p.Steps {
+		issues = append(issues, lintDescription(step)...)
+		if step.MetadataError != "" {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Rule:     "step-metadata",
+				Step:     step.Number,
+				Message:  step.MetadataError,
+			})
+		}
+	}
+
+	for _, step := range StaleSteps(p, baseDir) {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Rule:     "stale-context",
+			Step:     step.Number,
+			Message:  "completed against an older version of the context or its referenced files",
+		})
+	}
+
+	return issues, nil
+}
+
+// checkStepLabels reports step lines whose "Step N:" label doesn't match their position
+func checkStepLabels(content string) []Issue {
+	var issues []Issue
+	position := 0
+	lines := strings.Split(content, "\n")
+	prose := proseLines(lines)
+	for i, line := range lines {
+		matches := stepLineRegex.FindStringSubmatch(line)
+		if matches == nil || prose[i] {
+			continue
+		}
+		position++
+		if matches[2] == "" {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Rule:     "step-numbering",
+				Step:     position,
+				Message:  "step line has no 'Step N:' label",
+				Fixable:  true,
+			})
+		} else if parseStepNumber(matches[2]) != position {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Rule:     "step-numbering",
+				Step:     position,
+				Message:  fmt.Sprintf("labelled 'Step %s' but is step %d in order", matches[2], position),
+				Fixable:  true,
+			})
+		}
+	}
+	return issues
+}
+
+// checkNotesSections reports missing, incomplete, or orphaned notes sections
+func checkNotesSections(content string, stepCount int) []Issue {
+	var issues []Issue
+	sections := findNotesSections(strings.Split(content, "\n"))
+
+	for num := 1; num <= stepCount; num++ {
+		section, ok := sections[num]
+		if !ok {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Rule:     "notes-missing",
+				Step:     num,
+				Message:  "no '### Step N' notes section",
+				Fixable:  true,
+			})
+			continue
+		}
+		if !section.hasRetries {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Rule:     "retries-missing",
+				Step:     num,
+				Message:  "notes section has no **Retries** field, so retry counts are not persisted",
+				Fixable:  true,
+			})
+		}
+	}
+
+	var orphans []int
+	for num := range sections {
+		if num > stepCount {
+			orphans = append(orphans, num)
+		}
+	}
+	sort.Ints(orphans)
+	for _, num := range orphans {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Rule:     "orphan-notes",
+			Step:     num,
+			Message:  "notes section refers to a step that does not exist",
+		})
+	}
+
+	return issues
+}
+
+// lintDescription flags descriptions that are too vague or too large to act on
+func lintDescription(step Step) []Issue {
+	var issues []Issue
+	desc := strings.TrimSpace(step.Description)
+	words := strings.Fields(desc)
+
+	if len(words) < minDescriptionWords {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Rule:     "short-description",
+			Step:     step.Number,
+			Message:  fmt.Sprintf("description has %d word(s); say what to change and where", len(words)),
+		})
+	}
+
+	if phrase := vaguePhraseRegex.FindString(desc); phrase != "" {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Rule:     "vague-description",
+			Step:     step.Number,
+			Message:  fmt.Sprintf("description uses vague wording (%q)", phrase),
+		})
+	}
+
+	if verbs := actionVerbRegex.FindAllString(desc, -1); len(verbs) >= maxActionVerbs {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Rule:     "large-step",
+			Step:     step.Number,
+			Message:  fmt.Sprintf("description contains %d action verbs; consider splitting it into smaller steps", len(verbs)),
+		})
+	}
+
+	return issues
+}
+
+// CheckVerify reports the code steps left to run when nothing would verify
+// them: verify is the run's verification command, from the plan's
+// frontmatter, the config file or the project type. Validate can't see the
+// last two, so callers that resolve the command apply this rule.
+func CheckVerify(p *Plan, verify string) []Issue {
+	if strings.TrimSpace(verify) != "" {
+		return nil
+	}
+	var issues []Issue
+	for _, step := range p.Steps {
+		if step.Status == StatusCompleted || step.Status == StatusSkipped {
+			continue
+		}
+		if !codeVerbRegex.MatchString(step.Description) || nonCodeRegex.MatchString(step.Description) {
+			continue
+		}
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Rule:     "verify-missing",
+			Step:     step.Number,
+			Message:  "changes code, but there is no verification command to check it; set verify in the frontmatter or the config file",
+		})
+	}
+	return issues
+}
+
+// notesSection describes the location of a "### Step N" block
+type notesSection struct {
+	start      int // Index of the "### Step N" header line
+	end        int // Index one past the last line of the block
+	notesLine  int // Index of the **Notes** line, or -1
+	hasRetries bool
+}
+
+// findNotesSections locates every "### Step N" block in the given lines
+func findNotesSections(lines []string) map[int]*notesSection {
+	sections := make(map[int]*notesSection)
+	var current *notesSection
+	prose := proseLines(lines)
+
+	for i, line := range lines {
+		if prose[i] {
+			continue
+		}
+		if matches := notesSectionRegex.FindStringSubmatch(line); matches != nil {
+			if current != nil {
+				current.end = i
+			}
+			current = &notesSection{start: i, end: len(lines), notesLine: -1}
+			sections[parseStepNumber(matches[1])] = current
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			current.end = i
+			current = nil
+			continue
+		}
+		if notesRegex.MatchString(line) {
+			current.notesLine = i
+		}
+		if retriesRegex.MatchString(line) {
+			current.hasRetries = true
+		}
+	}
+
+	// Trim trailing blank lines so insertions land directly after the fields
+	for _, section := range sections {
+		for section.end > section.start+1 && strings.TrimSpace(lines[section.end-1]) == "" {
+			section.end--
+		}
+	}
+
+	return sections
+}
+
+// Fix applies safe rewrites to plan content: renumbering step labels,
+// adding missing **Retries** fields, and scaffolding missing notes sections.
+// It returns the rewritten content and the number of changes made.
+func Fix(content string) (string, int, error) {
+	p, err := Parse(content)
+	if err != nil {
+		return "", 0, err
+	}
+
+	lines := strings.Split(content, "\n")
+	changes := 0
+
+	// Renumber step labels to match their position
+	position := 0
+	prose := proseLines(lines)
+	for i, line := range lines {
+		matches := stepLineRegex.FindStringSubmatch(line)
+		if matches == nil || prose[i] {
+			continue
+		}
+		position++
+		if matches[2] != "" && parseStepNumber(matches[2]) == position {
+			continue
+		}
+		lines[i] = fmt.Sprintf("- [%s] Step %d: %s", matches[1], position, strings.TrimSpace(matches[3]))
+		changes++
+	}
+
+	// Add missing **Retries** fields, working bottom-up so indices stay valid
+	sections := findNotesSections(lines)
+	for num := len(p.Steps); num >= 1; num-- {
+		section, ok := sections[num]
+		if !ok || section.hasRetries {
+			continue
+		}
+		insertAt := section.end
+		if section.notesLine >= 0 {
+			insertAt = section.notesLine + 1
+		}
+		lines = insertLines(lines, insertAt, fmt.Sprintf("**Retries**: %d", p.Steps[num-1].RetryCount))
+		changes++
+	}
+
+	// Scaffold notes sections for steps that have none
+	lines, scaffolded := scaffoldNotesSections(lines, p.Steps)
+	changes += scaffolded
+
+	return strings.Join(lines, "\n"), changes, nil
+}
+
+// scaffoldNotesSections adds a default "### Step N" block for each of the
+// given steps that has no notes section, returning the count added
+func scaffoldNotesSections(lines []string, steps []Step) ([]string, int) {
+	sections := findNotesSections(lines)
+	var missing []string
+	added := 0
+	for _, step := range steps {
+		if _, ok := sections[step.Number]; ok {
+			continue
+		}
+		missing = append(missing, "", fmt.Sprintf("### Step %d", step.Number),
+			fmt.Sprintf("**Status**: %s", step.storedStatus()),
+			"**Last Run**: N/A",
+			"**Notes**: (none)",
+			"**Retries**: 0")
+		added++
+	}
+	if added > 0 {
+		lines = insertLines(lines, notesSectionEnd(&lines), missing...)
+	}
+	return lines, added
+}
+
+// notesSectionEnd returns the index where new notes blocks should be inserted,
+// appending a "## Notes" header to the plan if it doesn't have one
+func notesSectionEnd(lines *[]string) int {
+	header := -1
+	prose := proseLines(*lines)
+	for i, line := range *lines {
+		if !prose[i] && strings.HasPrefix(line, "## Notes") {
+			header = i
+			break
+		}
+	}
+
+	if header < 0 {
+		// Drop trailing blank lines before appending the new section
+		for len(*lines) > 0 && strings.TrimSpace((*lines)[len(*lines)-1]) == "" {
+			*lines = (*lines)[:len(*lines)-1]
+		}
+		*lines = append(*lines, "", "## Notes", "")
+		return len(*lines) - 1
+	}
+
+	end := len(*lines)
+	for i := header + 1; i < len(*lines); i++ {
+		if !prose[i] && strings.HasPrefix((*lines)[i], "## ") {
+			end = i
+			break
+		}
+	}
+	for end > header+1 && strings.TrimSpace((*lines)[end-1]) == "" {
+		end--
+	}
+	return end
+}
+
+// insertLines inserts values into lines at the given index
+func insertLines(lines []string, index int, values ...string) []string {
+	result := make([]string, 0, len(lines)+len(values))
+	result = append(result, lines[:index]...)
+	result = append(result, values...)
+	result = append(result, lines[index:]...)
+	return result
+}
@@ -0,0 +1,148 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OrderStrategy picks the next step to run from a plan, or nil when none remain
+type OrderStrategy func(p *Plan) *Step
+
+// Available step ordering strategies by name
+var orderStrategies = map[string]OrderStrategy{
+	"sequential":     (*Plan).NextStep,
+	"failed-first":   nextFailedFirst,
+	"pending-first":  nextPendingFirst,
+	"topological":    nextTopological,
+	"priority-first": nextPriorityFirst,
+	"shortest-first": nextShortestFirst,
+}
+
+// DefaultOrder is the strategy used when none is configured
+const DefaultOrder = "sequential"
+
+// ParseOrderStrategy returns the strategy with the given name
+func ParseOrderStrategy(name string) (OrderStrategy, error) {
+	strategy, ok := orderStrategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown step order: %s (valid: %s)", name, strings.Join(OrderStrategyNames(), ", "))
+	}
+	return strategy, nil
+}
+
+// OrderStrategyNames returns the names of all strategies, sorted
+func OrderStrategyNames() []string {
+	names := make([]string, 0, len(orderStrategies))
+	for name := range orderStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Only restricts a strategy to the steps keep accepts. The others are
+// passed over, but still count as dependencies.
+func Only(next OrderStrategy, keep func(s *Step) bool) OrderStrategy {
+	return func(p *Plan) *Step {
+		// The strategy sees the other unfinished steps as blocked, which no
+		// strategy picks and no dependency counts as done
+		view := *p
+		view.Steps = append([]Step(nil), p.Steps...)
+		for i := range view.Steps {
+			step := &view.Steps[i]
+			if !keep(step) && (step.Status == StatusPending || step.Status == StatusFailed) {
+				step.Status = StatusBlocked
+			}
+		}
+		step := next(&view)
+		if step == nil {
+			return nil
+		}
+		return &p.Steps[step.Number-1]
+	}
+}
+
+// nextFailedFirst retries failed steps before starting pending ones
+func nextFailedFirst(p *Plan) *Step {
+	if step := p.firstWithStatus(StatusFailed); step != nil {
+		return step
+	}
+	return p.firstWithStatus(StatusPending)
+}
+
+// nextPendingFirst starts pending steps before retrying failed ones
+func nextPendingFirst(p *Plan) *Step {
+	if step := p.firstWithStatus(StatusPending); step != nil {
+		return step
+	}
+	return p.firstWithStatus(StatusFailed)
+}
+
+// nextTopological runs the plan by dependency depth: steps that depend on
+// nothing first, then the steps that depend only on those, and so on
+func nextTopological(p *Plan) *Step {
+	depths := make(map[int]int, len(p.Steps))
+	var depth func(step *Step, seen map[int]bool) int
+	depth = func(step *Step, seen map[int]bool) int {
+		if d, ok := depths[step.Number]; ok {
+			return d
+		}
+		// A cycle never becomes ready, so its depth doesn't matter
+		seen[step.Number] = true
+		d := 0
+		for _, n := range step.After {
+			if dep := p.stepByNumber(n); dep != nil && !seen[n] {
+				d = max(d, depth(dep, seen)+1)
+			}
+		}
+		depths[step.Number] = d
+		return d
+	}
+	return p.bestReady(func(a, b *Step) bool {
+		return depth(a, map[int]bool{}) < depth(b, map[int]bool{})
+	})
+}
+
+// nextPriorityFirst runs steps by their (priority: N) annotation, 1 first.
+// Steps without a priority come after those with one.
+func nextPriorityFirst(p *Plan) *Step {
+	return p.bestReady(func(a, b *Step) bool {
+		return b.Priority == 0 && a.Priority > 0 || a.Priority > 0 && a.Priority < b.Priority
+	})
+}
+
+// nextShortestFirst runs steps by their (estimate: ...) annotation, shortest
+// first. Steps without an estimate come after those with one.
+func nextShortestFirst(p *Plan) *Step {
+	return p.bestReady(func(a, b *Step) bool {
+		return b.Estimate == 0 && a.Estimate > 0 || a.Estimate > 0 && a.Estimate < b.Estimate
+	})
+}
+
+// bestReady returns the pending or failed step with completed dependencies
+// that comes first by less, or nil. Ties go to the earliest step in the plan.
+func (p *Plan) bestReady(less func(a, b *Step) bool) *Step {
+	var best *Step
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if step.Status != StatusPending && step.Status != StatusFailed || !p.Ready(step) {
+			continue
+		}
+		if best == nil || less(step, best) {
+			best = step
+		}
+	}
+	return best
+}
+
+// firstWithStatus returns the first step with the given status whose
+// dependencies have completed, or nil
+func (p *Plan) firstWithStatus(status StepStatus) *Step {
+	for i := range p.Steps {
+		if p.Steps[i].Status == status && p.Ready(&p.Steps[i]) {
+			return &p.Steps[i]
+		}
+	}
+	return nil
+}
@@ -5,14 +5,23 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// metadataPair matches one key: value pair of a step annotation. The
+// after: list is comma-separated, like the pairs themselves, so it only
+// takes step numbers.
+const metadataPair = `after\s*:\s*\d+(?:\s*,\s*\d+)*|(?:agent|model|max_cost|max_duration|estimate|priority|ticket)\s*:\s*[^,()]+`
+
 var (
 	// Matches: - [ ] Step 1: Description or - [x] Step 2: Description or - [!] Step 3: Description or - [-] Step 4: Description
 	stepLineRegex = regexp.MustCompile(`^-\s+\[([ x!\-])\]\s+(?:Step\s+(\d+):\s+)?(.+)$`)
 
+	// Matches an indented sub-step under a step:   - [ ] Description
+	subStepRegex = regexp.MustCompile(`^(?:\s{2,}|\t)-\s+\[([ xX])\]\s+(.+)$`)
+
 	// Matches: # Project: Name
 	projectNameRegex = regexp.MustCompile(`^#\s+Project:\s+(.+)$`)
 
@@ -31,9 +40,49 @@ var (
 	// Matches: **Retries**: N
 	retriesRegex = regexp.MustCompile(`^\*\*Retries\*\*:\s+(\d+)$`)
 
+	// Matches: **Context Hash**: 1a2b3c4d5e6f
+	contextHashRegex = regexp.MustCompile(`^\*\*Context Hash\*\*:\s+(\S+)$`)
+
+	// Matches: **Artifacts**: url1, url2
+	artifactsRegex = regexp.MustCompile(`^\*\*Artifacts\*\*:\s+(.+)$`)
+
+	// Matches: **Session**: 4f1c2d3e-...
+	sessionRegex = regexp.MustCompile(`^\*\*Session\*\*:\s+(\S+)$`)
+
+	// Matches: **Transcript**: .ralph-loop/logs/transcripts/.../step-1-attempt-1.log
+	transcriptRegex = regexp.MustCompile(`^\*\*Transcript\*\*:\s+(.+)$`)
+
+	// Matches: **Timeout**: 90m
+	timeoutRegex = regexp.MustCompile(`^\*\*Timeout\*\*:\s+(.+)$`)
+
+	// Matches: **Max Retries**: 5
+	maxRetriesRegex = regexp.MustCompile(`^\*\*Max Retries\*\*:\s+(.+)$`)
+
+	// Matches: **Field**: value, any notes field
+	fieldLineRegex = regexp.MustCompile(`^\*\*[^*]+\*\*:`)
+
+	// Matches: **Acceptance**: criteria, or **Acceptance**: alone with the
+	// criteria on the lines below
+	acceptanceRegex = regexp.MustCompile(`^\*\*Acceptance\*\*:\s*(.*)$`)
+
+	// Matches: a trailing (agent: opencode, model: openai/gpt-4.1, after: 2,3, ticket: PROJ-12) on a step line
+	stepMetadataRegex = regexp.MustCompile(`\s*\(((?:` + metadataPair + `)(?:,\s*(?:` + metadataPair + `))*)\)\s*$`)
+
+	// Matches: one key: value pair of a step annotation
+	metadataPairRegex = regexp.MustCompile(metadataPair)
+
 	// Matches: ## Context
 	contextSectionRegex = regexp.MustCompile(`^##\s+Context\s*$`)
 
+	// Matches: ## Glossary
+	glossarySectionRegex = regexp.MustCompile(`^##\s+Glossary\s*$`)
+
+	// Matches: ## Plan
+	planSectionRegex = regexp.MustCompile(`^##\s+Plan\s*$`)
+
+	// Matches: ## Notes
+	notesHeaderRegex = regexp.MustCompile(`^##\s+Notes\s*$`)
+
 	// Matches: ## Plan or ## Notes (to detect end of context section)
 	sectionHeaderRegex = regexp.MustCompile(`^##\s+\w+`)
 )
@@ -54,17 +103,63 @@ func Parse(content string) (*Plan, error) {
 		Steps:      make([]Step, 0),
 	}
 
+	var lines []string
 	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning plan: %w", err)
+	}
+	prose := proseLines(lines)
+	plan.FormatVersion = formatVersion(lines)
+	frontmatter, err := parseFrontmatter(lines)
+	if err != nil {
+		return nil, err
+	}
+	plan.Frontmatter = frontmatter
+
 	stepNumber := 0
 	notesMap := make(map[int]*stepNotes)
 
 	var currentNoteStep int
 	var inNotesSection bool
-	var inContextSection bool
-	var contextLines []string
+	// An **Acceptance** block runs to the next blank line, field or header
+	var inAcceptance bool
+	// A **History** block runs while its lines are attempts
+	var inHistory bool
+	// Sub-steps belong to the step line directly above them
+	var inSubSteps bool
+	// Steps belong to the phase header above them, until another ## header
+	var currentPhase int
+	// Free-text sections (Context, Glossary) collect lines until the next
+	// ## header
+	var freeText *string
+	var freeTextLines []string
+
+	for i, line := range lines {
+		// If in a free-text section, collect lines until next ## header
+		if freeText != nil {
+			if prose[i] {
+				freeTextLines = append(freeTextLines, line)
+				continue
+			}
+			// End of section, save collected content
+			*freeText = strings.TrimSpace(strings.Join(freeTextLines, "\n"))
+			freeText = nil
+			freeTextLines = nil
+			// Don't continue - let other matchers process this line
+		}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+		// Code blocks and comments are never structure, but belong to an
+		// acceptance block they appear in
+		if prose[i] {
+			if inAcceptance && currentNoteStep > 0 {
+				notes := notesMap[currentNoteStep]
+				notes.acceptance = append(notes.acceptance, strings.TrimRight(line, " \t"))
+			}
+			continue
+		}
 
 		// Check for project name
 		if matches := projectNameRegex.FindStringSubmatch(line); matches != nil {
@@ -72,39 +167,51 @@ func Parse(content string) (*Plan, error) {
 			continue
 		}
 
-		// Check for context section header
+		// Check for free-text section headers
 		if contextSectionRegex.MatchString(line) {
-			inContextSection = true
+			freeText = &plan.Context
 			continue
 		}
-
-		// If in context section, collect lines until next ## header
-		if inContextSection {
-			if sectionHeaderRegex.MatchString(line) {
-				// End of context section, save collected content
-				plan.Context = strings.TrimSpace(strings.Join(contextLines, "\n"))
-				inContextSection = false
-				contextLines = nil
-				// Don't continue - let other matchers process this line
-			} else {
-				contextLines = append(contextLines, line)
-				continue
-			}
+		if glossarySectionRegex.MatchString(line) {
+			freeText = &plan.Glossary
+			continue
 		}
 
 		// Check for step definition in Plan section
 		if matches := stepLineRegex.FindStringSubmatch(line); matches != nil {
 			stepNumber++
 			status := parseCheckbox(matches[1])
-			description := strings.TrimSpace(matches[3])
+			step := Step{Number: stepNumber, Status: status, Phase: currentPhase}
+			step.Description = parseStepMetadata(strings.TrimSpace(matches[3]), &step)
+			step.Tags = parseTags(step.Description)
+			plan.Steps = append(plan.Steps, step)
+			inSubSteps = true
+			continue
+		}
 
-			plan.Steps = append(plan.Steps, Step{
-				Number:      stepNumber,
-				Description: description,
-				Status:      status,
+		// Check for a sub-step of the step above
+		if matches := subStepRegex.FindStringSubmatch(line); matches != nil && inSubSteps {
+			parent := &plan.Steps[len(plan.Steps)-1]
+			parent.SubSteps = append(parent.SubSteps, SubStep{
+				Description: strings.TrimSpace(matches[2]),
+				Done:        matches[1] != " ",
 			})
 			continue
 		}
+		inSubSteps = false
+
+		// A ## header ends the notes, and the phase unless it starts one
+		if sectionHeaderRegex.MatchString(line) {
+			inNotesSection = false
+			inAcceptance = false
+			inHistory = false
+			currentPhase = 0
+			if matches := phaseHeaderRegex.FindStringSubmatch(line); matches != nil {
+				currentPhase = parseStepNumber(matches[1])
+				plan.Phases = append(plan.Phases, Phase{Number: currentPhase, Title: matches[2]})
+			}
+			continue
+		}
 
 		// Check for notes section header
 		if matches := notesSectionRegex.FindStringSubmatch(line); matches != nil {
@@ -114,6 +221,8 @@ func Parse(content string) (*Plan, error) {
 			if notesMap[num] == nil {
 				notesMap[num] = &stepNotes{}
 			}
+			inAcceptance = false
+			inHistory = false
 			continue
 		}
 
@@ -121,6 +230,38 @@ func Parse(content string) (*Plan, error) {
 		if inNotesSection && currentNoteStep > 0 {
 			notes := notesMap[currentNoteStep]
 
+			if inAcceptance {
+				trimmed := strings.TrimSpace(line)
+				if trimmed != "" && !strings.HasPrefix(trimmed, "**") && !strings.HasPrefix(trimmed, "#") {
+					notes.acceptance = append(notes.acceptance, strings.TrimRight(line, " \t"))
+					continue
+				}
+				inAcceptance = false
+			}
+
+			if inHistory {
+				if attempt, ok := parseHistoryLine(line); ok {
+					notes.attempts = append(notes.attempts, attempt)
+					continue
+				}
+				inHistory = false
+			}
+
+			if historyRegex.MatchString(line) {
+				notes.attempts = nil
+				inHistory = true
+				continue
+			}
+
+			if matches := acceptanceRegex.FindStringSubmatch(line); matches != nil {
+				notes.acceptance = nil
+				if text := strings.TrimSpace(matches[1]); text != "" {
+					notes.acceptance = append(notes.acceptance, text)
+				}
+				inAcceptance = true
+				continue
+			}
+
 			if matches := statusRegex.FindStringSubmatch(line); matches != nil {
 				notes.status = matches[1]
 				continue
@@ -141,6 +282,36 @@ func Parse(content string) (*Plan, error) {
 				continue
 			}
 
+			if matches := contextHashRegex.FindStringSubmatch(line); matches != nil {
+				notes.contextHash = matches[1]
+				continue
+			}
+
+			if matches := artifactsRegex.FindStringSubmatch(line); matches != nil {
+				notes.artifacts = strings.Split(matches[1], ", ")
+				continue
+			}
+
+			if matches := sessionRegex.FindStringSubmatch(line); matches != nil {
+				notes.sessionID = matches[1]
+				continue
+			}
+
+			if matches := transcriptRegex.FindStringSubmatch(line); matches != nil {
+				notes.transcript = strings.TrimSpace(matches[1])
+				continue
+			}
+
+			if matches := timeoutRegex.FindStringSubmatch(line); matches != nil {
+				notes.timeout = strings.TrimSpace(matches[1])
+				continue
+			}
+
+			if matches := maxRetriesRegex.FindStringSubmatch(line); matches != nil {
+				notes.maxRetries = strings.TrimSpace(matches[1])
+				continue
+			}
+
 			// Check if we've left the notes section (next header)
 			if strings.HasPrefix(line, "#") {
 				inNotesSection = false
@@ -149,8 +320,9 @@ func Parse(content string) (*Plan, error) {
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error scanning plan: %w", err)
+	if freeText != nil {
+		// A free-text section ran to the end of the file
+		*freeText = strings.TrimSpace(strings.Join(freeTextLines, "\n"))
 	}
 
 	// Apply notes to steps
@@ -166,17 +338,60 @@ func Parse(content string) (*Plan, error) {
 				plan.Steps[i].Notes = notes.notes
 			}
 			plan.Steps[i].RetryCount = notes.retryCount
+			plan.Steps[i].ContextHash = notes.contextHash
+			plan.Steps[i].Artifacts = notes.artifacts
+			plan.Steps[i].SessionID = notes.sessionID
+			plan.Steps[i].Transcript = notes.transcript
+			plan.Steps[i].Attempts = notes.attempts
+			plan.Steps[i].Acceptance = strings.Join(notes.acceptance, "\n")
+			parseOverrides(notes, &plan.Steps[i])
 		}
 	}
+	// A step isn't complete while any of its sub-steps is open
+	for i := range plan.Steps {
+		if step := &plan.Steps[i]; step.Status == StatusCompleted && !step.SubStepsDone() {
+			step.Status = StatusPending
+		}
+	}
+	plan.propagateBlocked()
 
 	return plan, nil
 }
 
 type stepNotes struct {
-	status     string
-	lastRun    string
-	notes      string
-	retryCount int
+	status      string
+	lastRun     string
+	notes       string
+	retryCount  int
+	contextHash string
+	artifacts   []string
+	sessionID   string
+	transcript  string
+	attempts    []Attempt
+	timeout     string
+	maxRetries  string
+	acceptance  []string // Lines of the **Acceptance** field
+}
+
+// parseOverrides records a step's **Timeout** and **Max Retries** notes
+// fields in step
+func parseOverrides(notes *stepNotes, step *Step) {
+	if notes.timeout != "" {
+		timeout, err := time.ParseDuration(notes.timeout)
+		if err != nil || timeout <= 0 {
+			step.MetadataError = fmt.Sprintf("invalid Timeout %q (want a duration, e.g. 90m)", notes.timeout)
+		} else {
+			step.Timeout = timeout
+		}
+	}
+	if notes.maxRetries != "" {
+		retries, err := strconv.Atoi(notes.maxRetries)
+		if err != nil || retries < 1 {
+			step.MetadataError = fmt.Sprintf("invalid Max Retries %q (want a positive number of attempts)", notes.maxRetries)
+		} else {
+			step.MaxRetries = retries
+		}
+	}
 }
 
 func parseCheckbox(marker string) StepStatus {
@@ -192,6 +407,62 @@ func parseCheckbox(marker string) StepStatus {
 	}
 }
 
+// parseStepMetadata splits a trailing "(agent: x, model: y, max_cost: $1.50,
+// max_duration: 20m, after: 2,3)" annotation off a step description,
+// recording it in step. It returns the description without the annotation.
+func parseStepMetadata(s string, step *Step) string {
+	loc := stepMetadataRegex.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return s
+	}
+	for _, pair := range metadataPairRegex.FindAllString(s[loc[2]:loc[3]], -1) {
+		key, value, _ := strings.Cut(pair, ":")
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "agent":
+			step.Agent = value
+		case "model":
+			step.Model = value
+		case "max_cost":
+			cost, err := strconv.ParseFloat(strings.TrimPrefix(value, "$"), 64)
+			if err != nil || cost <= 0 {
+				step.MetadataError = fmt.Sprintf("invalid max_cost %q (want a dollar amount, e.g. $1.50)", value)
+				continue
+			}
+			step.MaxCost = cost
+		case "max_duration":
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration <= 0 {
+				step.MetadataError = fmt.Sprintf("invalid max_duration %q (want a duration, e.g. 20m)", value)
+				continue
+			}
+			step.MaxDuration = duration
+		case "estimate":
+			estimate, err := time.ParseDuration(value)
+			if err != nil || estimate <= 0 {
+				step.MetadataError = fmt.Sprintf("invalid estimate %q (want a duration, e.g. 45m)", value)
+				continue
+			}
+			step.Estimate = estimate
+		case "priority":
+			priority, err := strconv.Atoi(value)
+			if err != nil || priority < 1 {
+				step.MetadataError = fmt.Sprintf("invalid priority %q (want a number from 1, the highest)", value)
+				continue
+			}
+			step.Priority = priority
+		case "after":
+			for _, dep := range strings.Split(value, ",") {
+				n, _ := strconv.Atoi(strings.TrimSpace(dep))
+				step.After = append(step.After, n)
+			}
+		case "ticket":
+			step.Ticket = value
+		}
+	}
+	return s[:loc[0]]
+}
+
 func parseStepNumber(s string) int {
 	var num int
 	fmt.Sscanf(s, "%d", &num)
@@ -0,0 +1,75 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matches: ## Phase 2: Title, or ## Phase 2 alone
+var phaseHeaderRegex = regexp.MustCompile(`^##\s+Phase\s+(\d+)(?:\s*:\s*(.*?))?\s*$`)
+
+// Phase is a group of consecutive steps under a "## Phase N: Title" header
+type Phase struct {
+	Number int
+	Title  string
+}
+
+// Name is how the phase is shown, e.g. "Phase 2: Backend"
+func (ph Phase) Name() string {
+	if ph.Title == "" {
+		return fmt.Sprintf("Phase %d", ph.Number)
+	}
+	return fmt.Sprintf("Phase %d: %s", ph.Number, ph.Title)
+}
+
+// PhaseByNumber returns the phase with the given number, or nil
+func (p *Plan) PhaseByNumber(number int) *Phase {
+	for i := range p.Phases {
+		if p.Phases[i].Number == number {
+			return &p.Phases[i]
+		}
+	}
+	return nil
+}
+
+// PhaseProgress counts the completed steps of a phase and all its steps.
+// Phase 0 holds the steps above the first phase header.
+func (p *Plan) PhaseProgress(number int) (completed int, total int) {
+	for _, step := range p.Steps {
+		if step.Phase != number {
+			continue
+		}
+		total++
+		if step.Status == StatusCompleted {
+			completed++
+		}
+	}
+	return completed, total
+}
+
+// PhaseNumbers lists the phase numbers as a string, e.g. "1, 2, 3"
+func (p *Plan) PhaseNumbers() string {
+	var numbers []string
+	for _, ph := range p.Phases {
+		numbers = append(numbers, fmt.Sprintf("%d", ph.Number))
+	}
+	return strings.Join(numbers, ", ")
+}
+
+// checkPhases reports phase numbers used by more than one header
+func checkPhases(p *Plan) []Issue {
+	var issues []Issue
+	seen := make(map[int]bool)
+	for _, ph := range p.Phases {
+		if seen[ph.Number] {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Rule:     "phase-numbering",
+				Message:  fmt.Sprintf("more than one '## Phase %d' header; give each phase its own number", ph.Number),
+			})
+		}
+		seen[ph.Number] = true
+	}
+	return issues
+}
@@ -0,0 +1,85 @@
+package plan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matches: the opening or closing line of a fenced code block, ``` or ~~~
+var fenceRegex = regexp.MustCompile("^\\s*(`{3,}|~{3,})")
+
+// proseLines marks the lines of a plan that are free text rather than plan
+// structure: fenced code blocks, HTML comments, the bodies of the Context
+// and Glossary sections, and, in a plan with a ## Plan header, the bodies
+// of any sections the user added other than phases. Step lines, headers and notes
+// fields are only recognized outside them, so examples, appendices and
+// commented-out text are never parsed as steps or rewritten.
+func proseLines(lines []string) []bool {
+	prose := blockLines(lines)
+
+	// Without a ## Plan header steps may sit under any header, so only
+	// the known free-text sections can be left out
+	hasPlanSection := false
+	for i, line := range lines {
+		if !prose[i] && planSectionRegex.MatchString(line) {
+			hasPlanSection = true
+			break
+		}
+	}
+
+	inFreeText := false
+	for i, line := range lines {
+		if prose[i] {
+			continue
+		}
+		switch {
+		case contextSectionRegex.MatchString(line), glossarySectionRegex.MatchString(line):
+			inFreeText = true
+		case planSectionRegex.MatchString(line), phaseHeaderRegex.MatchString(line), notesHeaderRegex.MatchString(line):
+			inFreeText = false
+		case sectionHeaderRegex.MatchString(line):
+			inFreeText = hasPlanSection
+		default:
+			prose[i] = inFreeText
+		}
+	}
+	return prose
+}
+
+// blockLines marks the lines inside fenced code blocks and HTML comments
+// that start a line, including the lines that open and close them
+func blockLines(lines []string) []bool {
+	block := make([]bool, len(lines))
+	fence := ""        // The open fence, e.g. "```"
+	inComment := false // Inside an HTML comment
+
+	// The frontmatter is settings, not markdown
+	start := frontmatterEnd(lines)
+	for i := 0; i < start; i++ {
+		block[i] = true
+	}
+
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			block[i] = true
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		case inComment:
+			block[i] = true
+			inComment = !strings.Contains(line, "-->")
+		default:
+			if matches := fenceRegex.FindStringSubmatch(line); matches != nil {
+				block[i] = true
+				fence = matches[1]
+			} else if strings.HasPrefix(trimmed, "<!--") {
+				block[i] = true
+				inComment = !strings.Contains(trimmed[len("<!--"):], "-->")
+			}
+		}
+	}
+	return block
+}
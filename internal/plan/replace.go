@@ -0,0 +1,164 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Replacement is a plan line changed by ReplaceText
+type Replacement struct {
+	Line    int    // 1-based line number
+	Section string // Where the line is: project, context, step N, notes N or plan
+	Before  string
+	After   string
+}
+
+// ReplaceText replaces old with new in the plan's prose: the project name,
+// the context, step and sub-step descriptions, notes and acceptance
+// criteria. Structure is left alone so the plan parses the same way
+// afterwards: headers, step markers and labels, (agent: ...) annotations,
+// note field names and their recorded values (status, last run, retries,
+// context hash, artifacts, timeout and max retries) and the freeze seal.
+func ReplaceText(content string, old string, new string) (string, []Replacement) {
+	if old == "" {
+		return content, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	prose := proseLines(lines)
+	var changes []Replacement
+	section := ""
+	stepCount := 0
+
+	for i, line := range lines {
+		before := line
+		where := section
+
+		switch {
+		case frozenSealRegex.MatchString(line):
+			continue
+
+		case prose[i]:
+			// Code blocks, comments and free text are replaced throughout
+			line = strings.ReplaceAll(line, old, new)
+
+		case projectNameRegex.MatchString(line):
+			prefix := line[:strings.Index(line, ":")+1]
+			line = prefix + strings.ReplaceAll(line[len(prefix):], old, new)
+			where = "project"
+
+		case contextSectionRegex.MatchString(line):
+			section = "context"
+			continue
+
+		case notesSectionRegex.MatchString(line):
+			section = "notes " + notesSectionRegex.FindStringSubmatch(line)[1]
+			continue
+
+		case sectionHeaderRegex.MatchString(line):
+			section = strings.ToLower(strings.TrimSpace(strings.TrimLeft(line, "#")))
+			continue
+
+		case strings.HasPrefix(line, "#"), frozenSealRegex.MatchString(line), line == frozenBanner:
+			continue
+
+		case stepLineRegex.MatchString(line):
+			matches := stepLineRegex.FindStringSubmatchIndex(line)
+			start, end := matches[6], matches[7] // The description
+			if loc := stepMetadataRegex.FindStringIndex(line[start:end]); loc != nil {
+				end = start + loc[0]
+			}
+			line = line[:start] + strings.ReplaceAll(line[start:end], old, new) + line[end:]
+			stepCount++
+			where = fmt.Sprintf("step %d", stepCount)
+
+		case subStepRegex.MatchString(line) && section == "plan":
+			matches := subStepRegex.FindStringSubmatchIndex(line)
+			start := matches[4] // The description
+			line = line[:start] + strings.ReplaceAll(line[start:], old, new)
+			where = fmt.Sprintf("step %d", stepCount)
+
+		case notesRegex.MatchString(line):
+			prefix := line[:len(line)-len(notesRegex.FindStringSubmatch(line)[1])]
+			line = prefix + strings.ReplaceAll(line[len(prefix):], old, new)
+
+		case acceptanceRegex.MatchString(line):
+			prefix := line[:len(line)-len(acceptanceRegex.FindStringSubmatch(line)[1])]
+			line = prefix + strings.ReplaceAll(line[len(prefix):], old, new)
+
+		case statusRegex.MatchString(line), lastRunRegex.MatchString(line), retriesRegex.MatchString(line),
+			contextHashRegex.MatchString(line), artifactsRegex.MatchString(line),
+			timeoutRegex.MatchString(line), maxRetriesRegex.MatchString(line):
+			continue
+
+		default:
+			line = strings.ReplaceAll(line, old, new)
+		}
+
+		if line != before {
+			lines[i] = line
+			changes = append(changes, Replacement{Line: i + 1, Section: where, Before: before, After: line})
+		}
+	}
+
+	return strings.Join(lines, "\n"), changes
+}
+
+// ReplaceInFile applies ReplaceText to a plan file. With dryRun the file
+// is left untouched. Frozen plans are refused.
+func ReplaceInFile(path string, old string, new string, dryRun bool) ([]Replacement, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	if IsFrozen(string(content)) {
+		return nil, ErrPlanFrozen
+	}
+
+	updated, changes := ReplaceText(string(content), old, new)
+	if err := sameStructure(string(content), updated); err != nil {
+		return nil, err
+	}
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	if err := SaveContent(path, updated); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// sameStructure checks that an edit kept the plan's steps and their
+// statuses, so replacement text can't turn prose into structure
+func sameStructure(before string, after string) error {
+	p, err := Parse(before)
+	if err != nil {
+		return err
+	}
+	q, err := Parse(after)
+	if err != nil {
+		return err
+	}
+	if len(p.Steps) != len(q.Steps) {
+		return fmt.Errorf("the replacement would change the number of steps (%d -> %d)", len(p.Steps), len(q.Steps))
+	}
+	for i := range p.Steps {
+		a, b := p.Steps[i], q.Steps[i]
+		if a.Status != b.Status || a.RetryCount != b.RetryCount || a.Metadata() != b.Metadata() ||
+			a.Timeout != b.Timeout || a.MaxRetries != b.MaxRetries {
+			return fmt.Errorf("the replacement would change the status or annotations of step %d", a.Number)
+		}
+		if len(a.SubSteps) != len(b.SubSteps) {
+			return fmt.Errorf("the replacement would change the sub-steps of step %d", a.Number)
+		}
+		for j := range a.SubSteps {
+			if a.SubSteps[j].Done != b.SubSteps[j].Done {
+				return fmt.Errorf("the replacement would change the sub-steps of step %d", a.Number)
+			}
+		}
+	}
+	return nil
+}
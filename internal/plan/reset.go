@@ -0,0 +1,79 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ResetSteps sets the given steps back to pending with no retries, so the
+// loop runs them again from scratch. A completed step's sub-steps are
+// unchecked too; a failed or skipped step keeps the ones it finished.
+func ResetSteps(path string, numbers []int) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+	if IsFrozen(string(content)) {
+		return ErrPlanFrozen
+	}
+
+	updated := string(content)
+	for _, num := range numbers {
+		updated = resetStepInContent(updated, num)
+	}
+
+	return SaveContent(path, updated)
+}
+
+func resetStepInContent(content string, stepNum int) string {
+	lines := strings.Split(content, "\n")
+	prose := proseLines(lines)
+
+	currentStep := 0
+	inSubSteps := false
+	wasCompleted := false
+	for i, line := range lines {
+		if prose[i] {
+			continue
+		}
+		if matches := stepLineRegex.FindStringSubmatch(line); matches != nil {
+			currentStep++
+			inSubSteps = currentStep == stepNum
+			if currentStep == stepNum {
+				wasCompleted = matches[1] == "x"
+				lines[i] = updateCheckbox(line, " ")
+			}
+		} else if inSubSteps && subStepRegex.MatchString(line) {
+			if wasCompleted {
+				lines[i] = updateCheckbox(line, " ")
+			}
+		} else {
+			inSubSteps = false
+		}
+	}
+
+	// The session can't be resumed by a fresh attempt, and the context hash
+	// only describes a completed step
+	section, ok := findNotesSections(lines)[stepNum]
+	if !ok {
+		return strings.Join(lines, "\n")
+	}
+	var kept []string
+	for i := section.start; i < section.end; i++ {
+		line := lines[i]
+		switch {
+		case prose[i]:
+		case statusRegex.MatchString(line):
+			line = fmt.Sprintf("**Status**: %s", StatusPending)
+		case retriesRegex.MatchString(line):
+			line = "**Retries**: 0"
+		case sessionRegex.MatchString(line), contextHashRegex.MatchString(line):
+			continue
+		}
+		kept = append(kept, line)
+	}
+	lines = replaceLines(lines, section.start, section.end, kept)
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,348 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Matches: the after: pair of a step annotation
+var afterPairRegex = regexp.MustCompile(`after\s*:\s*\d+(?:\s*,\s*\d+)*`)
+
+// InsertStep adds a pending step at position, moving the steps from there
+// on down by one. Position len(steps)+1 appends it.
+func InsertStep(path string, position int, description string) error {
+	return restructureFile(path, 0, func(p *Plan) ([]int, []string, error) {
+		if position < 1 || position > len(p.Steps)+1 {
+			return nil, nil, fmt.Errorf("position %d is out of range (1-%d)", position, len(p.Steps)+1)
+		}
+		order := identityOrder(len(p.Steps))
+		order = append(order[:position-1], append([]int{0}, order[position-1:]...)...)
+		return order, []string{description}, nil
+	})
+}
+
+// RemoveStep deletes a step and its notes section, moving the steps after
+// it up by one. A step other steps depend on can't be removed.
+func RemoveStep(path string, number int) error {
+	return restructureFile(path, 0, func(p *Plan) ([]int, []string, error) {
+		if number < 1 || number > len(p.Steps) {
+			return nil, nil, fmt.Errorf("step %d does not exist (the plan has %d)", number, len(p.Steps))
+		}
+		var dependents []string
+		for _, step := range p.Steps {
+			for _, dep := range step.After {
+				if dep == number {
+					dependents = append(dependents, strconv.Itoa(step.Number))
+				}
+			}
+		}
+		if len(dependents) > 0 {
+			return nil, nil, fmt.Errorf("step %d can't be removed: step(s) %s depend on it (after: %d)", number, strings.Join(dependents, ", "), number)
+		}
+		order := identityOrder(len(p.Steps))
+		return append(order[:number-1], order[number:]...), nil, nil
+	})
+}
+
+// MoveStep moves a step to position, shifting the steps in between
+func MoveStep(path string, from int, to int) error {
+	return restructureFile(path, from, func(p *Plan) ([]int, []string, error) {
+		for _, n := range []int{from, to} {
+			if n < 1 || n > len(p.Steps) {
+				return nil, nil, fmt.Errorf("step %d does not exist (the plan has %d)", n, len(p.Steps))
+			}
+		}
+		order := identityOrder(len(p.Steps))
+		order = append(order[:from-1], order[from:]...)
+		order = append(order[:to-1], append([]int{from}, order[to-1:]...)...)
+		return order, nil, nil
+	})
+}
+
+// identityOrder returns the step numbers 1 to n
+func identityOrder(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i + 1
+	}
+	return order
+}
+
+// restructureFile reads a plan, asks plan for the new step order and
+// rewrites the file in that order (see restructure)
+func restructureFile(path string, moved int, plan func(p *Plan) ([]int, []string, error)) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+	if IsFrozen(string(content)) {
+		return ErrPlanFrozen
+	}
+	p, err := Parse(string(content))
+	if err != nil {
+		return err
+	}
+	order, added, err := plan(p)
+	if err != nil {
+		return err
+	}
+	updated, err := restructure(string(content), order, added, moved)
+	if err != nil {
+		return err
+	}
+	return SaveContent(path, updated)
+}
+
+// restructure rewrites content so its steps come in the given order. order
+// lists, for each new position, the current number of the step that goes
+// there, or 0 for a new pending step, whose description is taken from
+// added in turn. Steps left out are removed with their notes sections.
+//
+// Step lines keep their sub-steps, and notes sections their fields, as
+// written. Step labels, notes headers and (after: ...) references are
+// renumbered to match.
+//
+// Phase headers stay where they are. A new step, or the moved step (0 for
+// none), joins the phase of the position it lands in.
+func restructure(content string, order []int, added []string, moved int) (string, error) {
+	lines := strings.Split(content, "\n")
+
+	// Each step runs from its step line to the next one or to a phase
+	// header, so sub-steps and anything else written under it move with it
+	var stepStarts, phaseStarts, starts []int
+	prose := proseLines(lines)
+	for i, line := range lines {
+		switch {
+		case prose[i]:
+		case stepLineRegex.MatchString(line):
+			stepStarts = append(stepStarts, i)
+			starts = append(starts, i)
+		case phaseHeaderRegex.MatchString(line) && len(stepStarts) > 0:
+			phaseStarts = append(phaseStarts, i)
+			starts = append(starts, i)
+		}
+	}
+	// Phase headers after the last step stay where they are
+	for len(phaseStarts) > 0 && phaseStarts[len(phaseStarts)-1] > stepStarts[len(stepStarts)-1] {
+		phaseStarts = phaseStarts[:len(phaseStarts)-1]
+		starts = starts[:len(starts)-1]
+	}
+
+	// Split the steps from the phase headers between them; a step's group
+	// is the number of those headers above it
+	var steps, headers [][]string
+	var groups []int
+	for i, block := range blocks(lines, starts, -1) {
+		if stepLineRegex.MatchString(lines[starts[i]]) {
+			steps = append(steps, block)
+			groups = append(groups, len(headers))
+		} else {
+			headers = append(headers, block)
+		}
+	}
+
+	sections := findNotesSections(lines)
+	var notesStarts []int
+	notesNumbers := make(map[int]int) // Start line to step number
+	for num, section := range sections {
+		if num < 1 || num > len(stepStarts) {
+			return "", fmt.Errorf("notes section 'Step %d' has no step; remove it or fix the plan first", num)
+		}
+		notesStarts = append(notesStarts, section.start)
+		notesNumbers[section.start] = num
+	}
+	sort.Ints(notesStarts)
+	lastNotesEnd := -1
+	if len(notesStarts) > 0 {
+		lastNotesEnd = sections[notesNumbers[notesStarts[len(notesStarts)-1]]].end
+	}
+	notes := make(map[int][]string)
+	for i, block := range blocks(lines, notesStarts, lastNotesEnd) {
+		notes[notesNumbers[notesStarts[i]]] = block
+	}
+
+	renumber := make(map[int]int)
+	for position, old := range order {
+		if old > 0 {
+			renumber[old] = position + 1
+		}
+	}
+
+	if len(stepStarts) == 0 {
+		updated, _ := appendStepsToContent(content, added)
+		return updated, nil
+	}
+
+	var newSteps, newNotes []string
+	emitted := 0
+	emitHeader := func() {
+		if len(newSteps) > 0 {
+			newSteps = append(newSteps, "")
+		}
+		newSteps = append(newSteps, headers[emitted]...)
+		newSteps = append(newSteps, "")
+		emitted++
+	}
+	for position, old := range order {
+		num := position + 1
+		for emitted < newGroup(order, groups, moved, position) {
+			emitHeader()
+		}
+		if old == 0 {
+			newSteps = append(newSteps, fmt.Sprintf("- [ ] Step %d: %s", num, added[0]))
+			added = added[1:]
+			newNotes = appendNotesBlock(newNotes, []string{
+				fmt.Sprintf("### Step %d", num),
+				"**Status**: pending",
+				"**Last Run**: N/A",
+				"**Notes**: (none)",
+				"**Retries**: 0",
+			})
+			continue
+		}
+
+		block := append([]string(nil), steps[old-1]...)
+		matches := stepLineRegex.FindStringSubmatch(block[0])
+		block[0] = fmt.Sprintf("- [%s] Step %d: %s", matches[1], num, renumberAfter(strings.TrimSpace(matches[3]), renumber))
+		newSteps = append(newSteps, block...)
+
+		if section, ok := notes[old]; ok {
+			section = append([]string{fmt.Sprintf("### Step %d", num)}, section[1:]...)
+			newNotes = appendNotesBlock(newNotes, section)
+		}
+	}
+
+	// Phases left without steps keep their headers
+	for emitted < len(headers) {
+		emitHeader()
+	}
+	if len(newSteps) > 0 && newSteps[len(newSteps)-1] == "" {
+		newSteps = newSteps[:len(newSteps)-1]
+	}
+
+	// Replace the later of the two regions first so the other's indices
+	// stay valid
+	stepsEnd := stepStarts[len(stepStarts)-1] + len(steps[len(steps)-1])
+	replaceSteps := func() { lines = replaceLines(lines, stepStarts[0], stepsEnd, newSteps) }
+	switch {
+	case len(notesStarts) == 0:
+		replaceSteps()
+		if len(newNotes) > 0 {
+			lines = insertLines(lines, notesSectionEnd(&lines), append([]string{""}, newNotes...)...)
+		}
+	case notesStarts[0] > stepStarts[0]:
+		lines = replaceLines(lines, notesStarts[0], lastNotesEnd, newNotes)
+		replaceSteps()
+	default:
+		replaceSteps()
+		lines = replaceLines(lines, notesStarts[0], lastNotesEnd, newNotes)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// newGroup returns the phase group of the step at position in the new
+// order. Steps keep their group, except new steps and the moved one, which
+// take the group of the steps around them: for a new step the one after it,
+// and for the moved step its own if it lies between those of its neighbours.
+func newGroup(order []int, groups []int, moved int, position int) int {
+	placed := func(old int) bool { return old == 0 || old == moved }
+	if !placed(order[position]) {
+		return groups[order[position]-1]
+	}
+
+	lower, upper := 0, -1
+	for i := position - 1; i >= 0; i-- {
+		if !placed(order[i]) {
+			lower = groups[order[i]-1]
+			break
+		}
+	}
+	for i := position + 1; i < len(order); i++ {
+		if !placed(order[i]) {
+			upper = groups[order[i]-1]
+			break
+		}
+	}
+
+	group := upper
+	if order[position] != 0 || upper < 0 {
+		group = lower
+		if order[position] != 0 {
+			group = groups[order[position]-1]
+		}
+	}
+	if group < lower {
+		group = lower
+	}
+	if upper >= 0 && group > upper {
+		group = upper
+	}
+	return group
+}
+
+// blocks splits lines into the blocks starting at each of starts, each
+// running to the next start, or to end for the last one (-1 for the line
+// after the last step's sub-steps). Trailing blank lines are dropped.
+func blocks(lines []string, starts []int, end int) [][]string {
+	result := make([][]string, len(starts))
+	for i, start := range starts {
+		stop := end
+		switch {
+		case i+1 < len(starts):
+			stop = starts[i+1]
+		case end < 0:
+			stop = start + 1
+			for stop < len(lines) && subStepRegex.MatchString(lines[stop]) {
+				stop++
+			}
+		}
+		for stop > start+1 && strings.TrimSpace(lines[stop-1]) == "" {
+			stop--
+		}
+		result[i] = lines[start:stop]
+	}
+	return result
+}
+
+// appendNotesBlock adds a notes section, separated from the one before by
+// a blank line
+func appendNotesBlock(notes []string, section []string) []string {
+	if len(notes) > 0 {
+		notes = append(notes, "")
+	}
+	return append(notes, section...)
+}
+
+// renumberAfter rewrites the step numbers in a description's (after: ...)
+// annotation
+func renumberAfter(description string, renumber map[int]int) string {
+	loc := stepMetadataRegex.FindStringIndex(description)
+	if loc == nil {
+		return description
+	}
+	metadata := afterPairRegex.ReplaceAllStringFunc(description[loc[0]:loc[1]], func(pair string) string {
+		_, list, _ := strings.Cut(pair, ":")
+		var deps []string
+		for _, dep := range strings.Split(list, ",") {
+			n, _ := strconv.Atoi(strings.TrimSpace(dep))
+			if renumbered, ok := renumber[n]; ok {
+				n = renumbered
+			}
+			deps = append(deps, strconv.Itoa(n))
+		}
+		return "after: " + strings.Join(deps, ",")
+	})
+	return description[:loc[0]] + metadata
+}
+
+// replaceLines replaces lines[start:end] with values
+func replaceLines(lines []string, start int, end int, values []string) []string {
+	result := make([]string, 0, len(lines)-(end-start)+len(values))
+	result = append(result, lines[:start]...)
+	result = append(result, values...)
+	return append(result, lines[end:]...)
+}
@@ -0,0 +1,146 @@
+package plan
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/layout"
+)
+
+// BackupsKept is how many earlier versions of each plan are kept
+const BackupsKept = 20
+
+// SaveContent replaces the content of the plan file at path. The current
+// version is first copied to the plan's backups, then the new one is
+// written to a temporary file and renamed over the plan, so a crash
+// leaves either the old plan or the new one, never a truncated file.
+func SaveContent(path string, content string) error {
+	mode := os.FileMode(0644)
+	old, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if bytes.Equal(old, []byte(content)) {
+			return nil
+		}
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := backupPlan(path, old); err != nil {
+			return err
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	// Replace the file a symlinked plan points to, not the link
+	target := path
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		target = resolved
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace plan file: %w", err)
+	}
+	return nil
+}
+
+// BackupDir returns where a plan's earlier versions are kept:
+// .ralph-loop/backups/<plan name>/ next to the plan file
+func BackupDir(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return layout.ForPlan(path).Path(layout.Backups, name)
+}
+
+// backupTimeFormat names backups by when they were taken. Timestamps sort
+// by name; the nanoseconds keep quick writes apart.
+const backupTimeFormat = "20060102-150405.000000000"
+
+// Backup is an earlier version of a plan
+type Backup struct {
+	Path  string
+	Taken time.Time // When the version was replaced
+}
+
+// Backups lists a plan's backups, oldest first
+func Backups(path string) ([]Backup, error) {
+	dir := BackupDir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backups: %w", err)
+	}
+	var backups []Backup
+	for _, entry := range entries {
+		stamp := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		taken, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if entry.IsDir() || err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: filepath.Join(dir, entry.Name()), Taken: taken})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Taken.Before(backups[j].Taken) })
+	return backups, nil
+}
+
+// backupPlan stores content as the newest backup of the plan and removes
+// the oldest ones beyond BackupsKept
+func backupPlan(path string, content []byte) error {
+	if err := layout.ForPlan(path).Ensure(); err != nil {
+		return err
+	}
+	dir := BackupDir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := time.Now().Format(backupTimeFormat) + filepath.Ext(path)
+	if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+		return fmt.Errorf("failed to back up plan file: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			backups = append(backups, entry.Name())
+		}
+	}
+	sort.Strings(backups)
+	for len(backups) > BackupsKept {
+		os.Remove(filepath.Join(dir, backups[0]))
+		backups = backups[1:]
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// manualSkipPrefix starts the notes of a step skipped by hand, before the
+// reason given
+const manualSkipPrefix = "Skipped by hand: "
+
+// SkipStep marks a step as skipped and records why in its notes, so the
+// loop moves past it. Steps that depend on it become blocked.
+func SkipStep(path string, stepNum int, reason string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+	if IsFrozen(string(content)) {
+		return ErrPlanFrozen
+	}
+
+	lines := strings.Split(string(content), "\n")
+	prose := proseLines(lines)
+	currentStep := 0
+	for i, line := range lines {
+		if !prose[i] && stepLineRegex.MatchString(line) {
+			currentStep++
+			if currentStep == stepNum {
+				lines[i] = updateCheckbox(line, "-")
+				break
+			}
+		}
+	}
+	if currentStep != stepNum {
+		return fmt.Errorf("step %d does not exist (the plan has %d)", stepNum, currentStep)
+	}
+
+	lines, _ = scaffoldNotesSections(lines, []Step{{Number: stepNum, Status: StatusSkipped}})
+	updated := strings.Join(lines, "\n")
+	updated = setNotesField(updated, stepNum, "Status", string(StatusSkipped))
+	updated = setNotesField(updated, stepNum, "Last Run", time.Now().Format("2006-01-02 15:04:05"))
+	updated = setNotesField(updated, stepNum, "Notes", manualSkipPrefix+reason)
+
+	return SaveContent(path, updated)
+}
@@ -0,0 +1,112 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Matches template placeholders: {{entity}}
+var templateParamRegex = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// Matches template step lines: - Description or - [ ] Description
+var templateStepRegex = regexp.MustCompile(`^-\s+(?:\[ \]\s+)?(.+)$`)
+
+// StepTemplate is a reusable list of steps with {{param}} placeholders.
+// Template files live in a templates directory as <name>.md:
+//
+//	# Add CRUD endpoints for {{entity}}
+//	- Create the {{entity}} model and migration
+//	- Add list and get endpoints for {{entity}}
+type StepTemplate struct {
+	Name        string
+	Description string   // Optional "# ..." header line
+	Steps       []string // Step descriptions with placeholders
+}
+
+// LoadStepTemplate reads the named template from dir
+func LoadStepTemplate(dir string, name string) (*StepTemplate, error) {
+	content, err := os.ReadFile(filepath.Join(dir, name+".md"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("template not found: %s (looked in %s)", name, dir)
+		}
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	t := &StepTemplate{Name: name}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			if t.Description == "" {
+				t.Description = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			}
+			continue
+		}
+		if matches := templateStepRegex.FindStringSubmatch(line); matches != nil {
+			t.Steps = append(t.Steps, strings.TrimSpace(matches[1]))
+		}
+	}
+
+	if len(t.Steps) == 0 {
+		return nil, fmt.Errorf("template %s has no steps (expected '- Description' lines)", name)
+	}
+
+	return t, nil
+}
+
+// ListStepTemplates returns the names of the templates in dir, sorted
+func ListStepTemplates(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".md") {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".md"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Params returns the placeholder names used by the template, in order of first use
+func (t *StepTemplate) Params() []string {
+	seen := make(map[string]bool)
+	var params []string
+	for _, text := range append([]string{t.Description}, t.Steps...) {
+		for _, matches := range templateParamRegex.FindAllStringSubmatch(text, -1) {
+			if !seen[matches[1]] {
+				seen[matches[1]] = true
+				params = append(params, matches[1])
+			}
+		}
+	}
+	return params
+}
+
+// Expand substitutes params into the template's steps.
+// Every placeholder must have a value.
+func (t *StepTemplate) Expand(params map[string]string) ([]string, error) {
+	for _, name := range t.Params() {
+		if _, ok := params[name]; !ok {
+			return nil, fmt.Errorf("missing value for template parameter: %s", name)
+		}
+	}
+
+	steps := make([]string, len(t.Steps))
+	for i, step := range t.Steps {
+		steps[i] = templateParamRegex.ReplaceAllStringFunc(step, func(placeholder string) string {
+			return params[templateParamRegex.FindStringSubmatch(placeholder)[1]]
+		})
+	}
+	return steps, nil
+}
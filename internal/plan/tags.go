@@ -0,0 +1,54 @@
+package plan
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Matches: a #tag in a step description, e.g. #backend or #db-migrations.
+// Tags start with a letter, so issue references such as #123 aren't tags.
+var tagRegex = regexp.MustCompile(`(?:^|\s)#([A-Za-z][\w-]*)`)
+
+// parseTags returns the tags in a step description, lowercased, in order
+// and without repeats
+func parseTags(description string) []string {
+	var tags []string
+	for _, matches := range tagRegex.FindAllStringSubmatch(description, -1) {
+		tag := strings.ToLower(matches[1])
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// NormalizeTag turns a tag as given on the command line, with or without
+// its #, into the form steps store
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// HasAnyTag reports whether the step has one of the tags
+func (s *Step) HasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		if slices.Contains(s.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags returns every tag used in the plan, sorted
+func (p *Plan) Tags() []string {
+	var tags []string
+	for _, step := range p.Steps {
+		for _, tag := range step.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
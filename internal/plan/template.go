@@ -7,6 +7,7 @@ import (
 )
 
 const defaultTemplate = `# Project: [Your Project Name]
+<!-- ralph-loop:format 2 -->
 
 ## Context
 
@@ -25,16 +26,19 @@ Include key technologies, constraints, or any context the AI should know.
 **Status**: pending
 **Last Run**: N/A
 **Notes**: (none)
+**Retries**: 0
 
 ### Step 2
 **Status**: pending
 **Last Run**: N/A
 **Notes**: (none)
+**Retries**: 0
 
 ### Step 3
 **Status**: pending
 **Last Run**: N/A
 **Notes**: (none)
+**Retries**: 0
 `
 
 // CreateTemplate creates a new plan template file
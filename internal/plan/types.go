@@ -1,6 +1,11 @@
 package plan
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // StepStatus represents the current state of a step
 type StepStatus string
@@ -10,6 +15,7 @@ const (
 	StatusCompleted StepStatus = "completed"
 	StatusFailed    StepStatus = "failed"
 	StatusSkipped   StepStatus = "skipped" // For steps that exceeded max retries
+	StatusBlocked   StepStatus = "blocked" // Pending or failed, but a dependency was skipped; derived when parsing, never stored
 )
 
 // Step represents a single step in the plan
@@ -19,29 +25,132 @@ type Step struct {
 	Status      StepStatus
 	LastRun     *time.Time
 	Notes       string
-	RetryCount  int // Track retry attempts
+	RetryCount  int       // Track retry attempts
+	ContextHash string    // Context fingerprint when the step last completed
+	Artifacts   []string  // URLs of artifacts uploaded when the step last completed
+	SessionID   string    // Agent session of the last attempt, for resuming on retry
+	Transcript  string    // Where the full output of the last attempt is stored
+	Attempts    []Attempt // Every recorded attempt, oldest first, from the step's **History**
+	Agent       string    // Agent override from the step's (agent: ...) annotation
+	Model       string    // Model override from the step's (model: ...) annotation
+	After       []int     // Steps that must complete first, from the step's (after: ...) annotation
+	Ticket      string    // Issue tracker key, e.g. PROJ-123, from the step's (ticket: ...) annotation
+	Tags        []string  // Lowercased #tags in the description, e.g. backend for #backend
+	SubSteps    []SubStep // Indented checkboxes under the step line
+	Acceptance  string    // Criteria for the step being done, from its **Acceptance** notes field; may span lines
+	BlockedBy   int       // For blocked steps, the skipped or blocked dependency
+	Phase       int       // Number of the phase the step is under; 0 for none
+
+	// Budgets from the step's (max_cost: ..., max_duration: ...) annotation;
+	// zero means no budget
+	MaxCost       float64 // US dollars per attempt
+	MaxDuration   time.Duration
+	Estimate      time.Duration // Expected agent time, from the (estimate: ...) annotation; zero for none
+	Priority      int           // From the (priority: ...) annotation, 1 the highest; zero for none
+	MetadataError string        // Why part of the annotation or overrides could not be parsed
+
+	// Overrides of the loop's settings from the step's **Timeout** and
+	// **Max Retries** notes fields; zero means the loop's setting
+	Timeout    time.Duration
+	MaxRetries int
+}
+
+// SubStep is an indented checkbox under a step line. Sub-steps are the
+// step's acceptance items: the step is only complete when all of them are.
+type SubStep struct {
+	Description string
+	Done        bool
+}
+
+// Metadata returns the step's "(agent: x, model: y, max_cost: $1.50,
+// max_duration: 20m, after: 2,3)" annotation, or "" if it has no
+// overrides, budgets or dependencies
+func (s *Step) Metadata() string {
+	var parts []string
+	if s.Agent != "" {
+		parts = append(parts, "agent: "+s.Agent)
+	}
+	if s.Model != "" {
+		parts = append(parts, "model: "+s.Model)
+	}
+	if s.MaxCost > 0 {
+		parts = append(parts, fmt.Sprintf("max_cost: $%.2f", s.MaxCost))
+	}
+	if s.MaxDuration > 0 {
+		parts = append(parts, "max_duration: "+FormatDuration(s.MaxDuration))
+	}
+	if s.Estimate > 0 {
+		parts = append(parts, "estimate: "+FormatDuration(s.Estimate))
+	}
+	if s.Priority > 0 {
+		parts = append(parts, "priority: "+strconv.Itoa(s.Priority))
+	}
+	if len(s.After) > 0 {
+		deps := make([]string, len(s.After))
+		for i, n := range s.After {
+			deps[i] = strconv.Itoa(n)
+		}
+		parts = append(parts, "after: "+strings.Join(deps, ","))
+	}
+	if s.Ticket != "" {
+		parts = append(parts, "ticket: "+s.Ticket)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// SubStepsDone reports whether all of the step's sub-steps are checked
+func (s *Step) SubStepsDone() bool {
+	for _, sub := range s.SubSteps {
+		if !sub.Done {
+			return false
+		}
+	}
+	return true
 }
 
 // Plan represents the entire plan document
 type Plan struct {
 	ProjectName string
 	Context     string // Project context/background info for the AI
+	Glossary    string // Project-specific terms and acronyms, included in every prompt
 	Steps       []Step
-	RawContent  string // Original markdown content for preservation
+	Phases      []Phase      // From "## Phase N: Title" headers, in plan order
+	Frontmatter *Frontmatter // Run defaults from the YAML frontmatter; nil without one
+	RawContent  string       // Original markdown content for preservation
+
+	// Version of the plan format, from its format marker; 1 for plans
+	// written before there was one
+	FormatVersion int
 }
 
-// NextStep returns the first pending or failed step, or nil if all complete/skipped
+// NextStep returns the first pending or failed step whose dependencies
+// have completed, or nil if none can run
 func (p *Plan) NextStep() *Step {
 	for i := range p.Steps {
 		s := p.Steps[i].Status
-		if s == StatusPending || s == StatusFailed {
+		if (s == StatusPending || s == StatusFailed) && p.Ready(&p.Steps[i]) {
 			return &p.Steps[i]
 		}
-		// Skip StatusCompleted and StatusSkipped
+		// Skip StatusCompleted, StatusSkipped, StatusBlocked and steps
+		// still waiting on a dependency
 	}
 	return nil
 }
 
+// HasStep reports whether a step has the description, ignoring case and
+// spacing
+func (p *Plan) HasStep(description string) bool {
+	for i := range p.Steps {
+		if sameDescription(&p.Steps[i], &Step{Description: description}) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsComplete returns true if all steps are completed or skipped
 func (p *Plan) IsComplete() bool {
 	for _, step := range p.Steps {
@@ -54,9 +163,37 @@ func (p *Plan) IsComplete() bool {
 
 // StepResult represents the outcome of running a step
 type StepResult struct {
-	Success    bool
-	Output     string
-	Reason     string     // Populated if failed
-	Status     StepStatus // Optional explicit status (use for skipped)
-	RetryCount int        // Current retry count for the step
+	Success     bool
+	Output      string
+	Reason      string     // Populated if failed
+	Status      StepStatus // Optional explicit status (use for skipped)
+	RetryCount  int        // Current retry count for the step
+	ContextHash string     // Context fingerprint to record on success
+	Artifacts   []string   // Artifact URLs to record on success
+	SessionID   string     // Agent session of the attempt, if the agent reported one
+	Transcript  string     // Where the attempt's full output is stored, if it was
+	Attempt     *Attempt   // The attempt to add to the step's history, nil if none ran; its status and reason are taken from the result
+}
+
+// Attempt is one run of a step's agent, as recorded in its **History**
+type Attempt struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	Status    StepStatus // completed or failed
+	Reason    string     // Why a failed attempt failed
+	Agent     string
+	Model     string // Empty when the agent's default was used
+}
+
+// FormatDuration formats d without zero trailing units, e.g. "20m" rather
+// than "20m0s"
+func FormatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
 }
@@ -16,24 +16,19 @@ func UpdateStep(path string, stepNum int, result StepResult) error {
 		return fmt.Errorf("failed to read plan file: %w", err)
 	}
 
-	updated := updateStepInContent(string(content), stepNum, result)
-
-	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
-		return fmt.Errorf("failed to write plan file: %w", err)
+	// Don't clobber manual edits made while the plan was frozen
+	if err := CheckFrozen(string(content)); err != nil {
+		return err
 	}
 
-	return nil
+	updated := resealFrozen(updateStepInContent(string(content), stepNum, result))
+
+	return SaveContent(path, updated)
 }
 
 func updateStepInContent(content string, stepNum int, result StepResult) string {
 	lines := strings.Split(content, "\n")
-	var output []string
-
-	currentStep := 0
-	inNotesSection := false
-	notesStepNum := 0
-	foundNotesSection := false
-	notesSectionExists := false
+	prose := proseLines(lines)
 
 	// Determine the new marker based on status or success
 	newMarker := " "
@@ -45,88 +40,152 @@ func updateStepInContent(content string, stepNum int, result StepResult) string
 		newMarker = "!"
 	}
 
-	// First pass: update the checkbox in the plan section
+	// Update the checkbox in the plan section, and check off the sub-steps
+	// of a completed step
+	currentStep := 0
+	inSubSteps := false
 	for i, line := range lines {
-		if matches := stepLineRegex.FindStringSubmatch(line); matches != nil {
+		if prose[i] {
+			continue
+		}
+		if stepLineRegex.MatchString(line) {
 			currentStep++
+			inSubSteps = currentStep == stepNum
 			if currentStep == stepNum {
-				// Update the checkbox
 				lines[i] = updateCheckbox(line, newMarker)
 			}
-		}
-
-		// Check if notes section exists
-		if notesSectionRegex.MatchString(line) {
-			notesSectionExists = true
+		} else if inSubSteps && subStepRegex.MatchString(line) {
+			if result.Success {
+				lines[i] = updateCheckbox(line, "x")
+			}
+		} else {
+			inSubSteps = false
 		}
 	}
 
-	// Second pass: update or create notes section
-	for i, line := range lines {
-		// Check if we're entering a notes section for our step
-		if matches := notesSectionRegex.FindStringSubmatch(line); matches != nil {
-			num := parseStepNumber(matches[1])
-			notesStepNum = num
-			inNotesSection = true
-			if num == stepNum {
-				foundNotesSection = true
+	// Update the fields of the step's notes section, or add one. Everything
+	// else in the section is kept as written.
+	if section, ok := findNotesSections(lines)[stepNum]; ok {
+		for i := section.start + 1; i < section.end; i++ {
+			if prose[i] {
+				continue
 			}
-		}
-
-		// Update notes content if in the right section
-		if inNotesSection && notesStepNum == stepNum {
-			if statusRegex.MatchString(line) {
-				status := "completed"
-				if result.Status == StatusSkipped {
-					status = "skipped"
-				} else if !result.Success {
-					status = "failed"
-				}
-				lines[i] = fmt.Sprintf("**Status**: %s", status)
-			} else if lastRunRegex.MatchString(line) {
+			switch line := lines[i]; {
+			case statusRegex.MatchString(line):
+				lines[i] = fmt.Sprintf("**Status**: %s", resultStatus(result))
+			case lastRunRegex.MatchString(line):
 				lines[i] = fmt.Sprintf("**Last Run**: %s", time.Now().Format("2006-01-02 15:04:05"))
-			} else if notesRegex.MatchString(line) {
-				notes := summarizeOutput(result.Output)
-				if !result.Success && result.Reason != "" {
-					notes = fmt.Sprintf("Failed: %s", result.Reason)
-				}
-				lines[i] = fmt.Sprintf("**Notes**: %s", notes)
-			} else if retriesRegex.MatchString(line) {
+			case notesRegex.MatchString(line):
+				lines[i] = fmt.Sprintf("**Notes**: %s", resultNotes(result))
+			case retriesRegex.MatchString(line):
 				lines[i] = fmt.Sprintf("**Retries**: %d", result.RetryCount)
 			}
 		}
+	} else {
+		lines = insertLines(lines, notesSectionEnd(&lines), "", createNotesSectionForStep(stepNum, result))
+	}
 
-		// Check if we're leaving the notes section
-		if inNotesSection && strings.HasPrefix(line, "### Step") && notesStepNum != parseStepNumber(notesSectionRegex.FindStringSubmatch(line)[1]) {
-			inNotesSection = false
+	updated := strings.Join(lines, "\n")
+	if result.Success && result.ContextHash != "" {
+		updated = setNotesField(updated, stepNum, "Context Hash", result.ContextHash)
+	}
+	if result.Success && len(result.Artifacts) > 0 {
+		updated = setNotesField(updated, stepNum, "Artifacts", strings.Join(result.Artifacts, ", "))
+	}
+	if result.SessionID != "" {
+		updated = setNotesField(updated, stepNum, "Session", result.SessionID)
+	}
+	if result.Transcript != "" {
+		updated = setNotesField(updated, stepNum, "Transcript", result.Transcript)
+	}
+	if result.Attempt != nil {
+		attempt := *result.Attempt
+		attempt.Status = resultStatus(result)
+		if !result.Success {
+			attempt.Reason = result.Reason
 		}
+		updated = appendHistory(updated, stepNum, attempt)
+	}
 
-		output = append(output, lines[i])
+	return updated
+}
+
+// resultStatus is the **Status** recorded for a result
+func resultStatus(result StepResult) StepStatus {
+	switch {
+	case result.Status == StatusSkipped:
+		return StatusSkipped
+	case result.Success:
+		return StatusCompleted
 	}
+	return StatusFailed
+}
 
-	// If notes section doesn't exist at all, we need to create the entire Notes section
-	if !notesSectionExists {
-		output = append(output, "")
-		output = append(output, "## Notes")
-		output = append(output, "")
-		output = append(output, createNotesSectionForStep(stepNum, result))
-	} else if !foundNotesSection {
-		// Find where to insert the new notes section (at end of ## Notes)
-		for i := len(output) - 1; i >= 0; i-- {
-			if strings.HasPrefix(output[i], "## Notes") || strings.HasPrefix(output[i], "### Step") {
-				// Insert after the last notes-related line
-				insertIdx := i + 1
-				for insertIdx < len(output) && (strings.TrimSpace(output[insertIdx]) != "" || strings.HasPrefix(strings.TrimSpace(output[insertIdx]), "**")) {
-					insertIdx++
-				}
-				newSection := createNotesSectionForStep(stepNum, result)
-				output = insertAfter(output, insertIdx, newSection)
-				break
-			}
+// resultNotes is the **Notes** recorded for a result: the failure reason,
+// or a summary of the output
+func resultNotes(result StepResult) string {
+	if !result.Success && result.Reason != "" {
+		return fmt.Sprintf("Failed: %s", result.Reason)
+	}
+	return summarizeOutput(result.Output)
+}
+
+// setNotesField sets a **Field**: value line in a step's notes section,
+// replacing an existing line or appending one after the section's last field
+func setNotesField(content string, stepNum int, field string, value string) string {
+	lines := strings.Split(content, "\n")
+	section, ok := findNotesSections(lines)[stepNum]
+	if !ok {
+		return content
+	}
+
+	prefix := fmt.Sprintf("**%s**:", field)
+	newLine := fmt.Sprintf("%s %s", prefix, value)
+	prose := proseLines(lines)
+	for i := section.start + 1; i < section.end; i++ {
+		if !prose[i] && strings.HasPrefix(lines[i], prefix) {
+			lines[i] = newLine
+			return strings.Join(lines, "\n")
 		}
 	}
 
-	return strings.Join(output, "\n")
+	return strings.Join(insertLines(lines, fieldsEnd(lines, prose, section), newLine), "\n")
+}
+
+// fieldsEnd returns the line after a notes section's last field, including
+// the lines of a field that spans several (**Acceptance**, **History**), so
+// new fields stay together ahead of any text written under them
+func fieldsEnd(lines []string, prose []bool, section *notesSection) int {
+	end := section.start + 1
+	inField := false
+	for i := section.start + 1; i < section.end; i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case prose[i]:
+			inField = false
+		case fieldLineRegex.MatchString(lines[i]):
+			inField = true
+			end = i + 1
+		case inField && trimmed != "" && !strings.HasPrefix(trimmed, "#"):
+			end = i + 1
+		default:
+			inField = false
+		}
+	}
+	return end
+}
+
+// storedStatus returns the status written to the plan file. Blocked is
+// derived when parsing, so a blocked step is stored as failed once it has
+// been attempted, and as pending otherwise.
+func (s *Step) storedStatus() StepStatus {
+	if s.Status != StatusBlocked {
+		return s.Status
+	}
+	if s.RetryCount > 0 {
+		return StatusFailed
+	}
+	return StatusPending
 }
 
 func updateCheckbox(line, marker string) string {
@@ -135,33 +194,11 @@ func updateCheckbox(line, marker string) string {
 }
 
 func createNotesSectionForStep(stepNum int, result StepResult) string {
-	status := "completed"
-	if result.Status == StatusSkipped {
-		status = "skipped"
-	} else if !result.Success {
-		status = "failed"
-	}
-	notes := summarizeOutput(result.Output)
-	if !result.Success && result.Reason != "" {
-		notes = fmt.Sprintf("Failed: %s", result.Reason)
-	}
-
 	return fmt.Sprintf(`### Step %d
 **Status**: %s
 **Last Run**: %s
 **Notes**: %s
-**Retries**: %d`, stepNum, status, time.Now().Format("2006-01-02 15:04:05"), notes, result.RetryCount)
-}
-
-func insertAfter(slice []string, index int, value string) []string {
-	if index >= len(slice) {
-		return append(slice, "", value)
-	}
-	result := make([]string, 0, len(slice)+2)
-	result = append(result, slice[:index]...)
-	result = append(result, "", value)
-	result = append(result, slice[index:]...)
-	return result
+**Retries**: %d`, stepNum, resultStatus(result), time.Now().Format("2006-01-02 15:04:05"), resultNotes(result), result.RetryCount)
 }
 
 func summarizeOutput(output string) string {
@@ -187,24 +224,146 @@ func summarizeOutput(output string) string {
 	return "Completed successfully"
 }
 
-// WriteFile writes a plan to a file
+// AppendSteps adds pending steps after the last step in the plan, scaffolds
+// their notes sections, and returns the new step numbers
+func AppendSteps(path string, descriptions []string) ([]int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	if IsFrozen(string(content)) {
+		return nil, ErrPlanFrozen
+	}
+
+	updated, numbers := appendStepsToContent(string(content), descriptions)
+
+	if err := SaveContent(path, updated); err != nil {
+		return nil, err
+	}
+
+	return numbers, nil
+}
+
+// AppendDiscoveredSteps appends the steps an agent asked for while running
+// another, like AppendSteps, and records addedBy as each new step's
+// **Added By** note, so it is clear where it came from. Descriptions the
+// plan already has are left out, so an agent that repeats itself on a retry
+// adds nothing. It returns the new steps.
+func AppendDiscoveredSteps(path string, descriptions []string, addedBy string) ([]Step, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	if IsFrozen(string(content)) {
+		return nil, ErrPlanFrozen
+	}
+
+	p, err := Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	var fresh []string
+	for _, desc := range descriptions {
+		if !p.HasStep(desc) {
+			fresh = append(fresh, desc)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+
+	updated, numbers := appendStepsToContent(string(content), fresh)
+	var added []Step
+	for i, num := range numbers {
+		updated = setNotesField(updated, num, "Added By", addedBy)
+		added = append(added, Step{Number: num, Description: fresh[i], Status: StatusPending})
+	}
+
+	if err := SaveContent(path, updated); err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+func appendStepsToContent(content string, descriptions []string) (string, []int) {
+	lines := strings.Split(content, "\n")
+
+	// Insert after the last step line, or at the top of the Plan section
+	count := 0
+	insertAt := -1
+	prose := proseLines(lines)
+	for i, line := range lines {
+		if prose[i] {
+			continue
+		}
+		if stepLineRegex.MatchString(line) {
+			count++
+			insertAt = i + 1
+		} else if insertAt == i && subStepRegex.MatchString(line) {
+			insertAt = i + 1 // Keep the last step's sub-steps with it
+		}
+	}
+
+	var newLines []string
+	if insertAt < 0 {
+		for i, line := range lines {
+			if !prose[i] && strings.HasPrefix(line, "## Plan") {
+				insertAt = i + 1
+				break
+			}
+		}
+		if insertAt < 0 {
+			lines = append(lines, "## Plan")
+			insertAt = len(lines)
+		}
+		newLines = append(newLines, "")
+	}
+
+	var steps []Step
+	var numbers []int
+	for i, desc := range descriptions {
+		num := count + i + 1
+		newLines = append(newLines, fmt.Sprintf("- [ ] Step %d: %s", num, desc))
+		steps = append(steps, Step{Number: num, Description: desc, Status: StatusPending})
+		numbers = append(numbers, num)
+	}
+
+	lines = insertLines(lines, insertAt, newLines...)
+	lines, _ = scaffoldNotesSections(lines, steps)
+
+	return strings.Join(lines, "\n"), numbers
+}
+
+// WriteFile renders a plan as a new file. Only what Plan holds is written,
+// so it is for creating plans; existing plans are changed with targeted
+// line edits that keep everything else byte for byte.
 func WriteFile(path string, plan *Plan) error {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("# Project: %s\n\n", plan.ProjectName))
+	sb.WriteString(fmt.Sprintf("# Project: %s\n", plan.ProjectName))
+	sb.WriteString(formatMarker(FormatVersion) + "\n\n")
 
-	// Write context section if present
+	sb.WriteString("## Context\n\n")
 	if plan.Context != "" {
-		sb.WriteString("## Context\n\n")
 		sb.WriteString(plan.Context)
 		sb.WriteString("\n\n")
 	}
 
+	// Write glossary section if present
+	if plan.Glossary != "" {
+		sb.WriteString("## Glossary\n\n")
+		sb.WriteString(plan.Glossary)
+		sb.WriteString("\n\n")
+	}
+
 	sb.WriteString("## Plan\n\n")
 
 	for _, step := range plan.Steps {
 		marker := " "
-		switch step.Status {
+		switch step.storedStatus() {
 		case StatusCompleted:
 			marker = "x"
 		case StatusFailed:
@@ -212,7 +371,18 @@ func WriteFile(path string, plan *Plan) error {
 		case StatusSkipped:
 			marker = "-"
 		}
-		sb.WriteString(fmt.Sprintf("- [%s] Step %d: %s\n", marker, step.Number, step.Description))
+		description := step.Description
+		if metadata := step.Metadata(); metadata != "" {
+			description += " " + metadata
+		}
+		sb.WriteString(fmt.Sprintf("- [%s] Step %d: %s\n", marker, step.Number, description))
+		for _, sub := range step.SubSteps {
+			subMarker := " "
+			if sub.Done {
+				subMarker = "x"
+			}
+			sb.WriteString(fmt.Sprintf("  - [%s] %s\n", subMarker, sub.Description))
+		}
 	}
 
 	sb.WriteString("\n## Notes\n")
@@ -220,8 +390,7 @@ func WriteFile(path string, plan *Plan) error {
 	for _, step := range plan.Steps {
 		sb.WriteString(fmt.Sprintf("\n### Step %d\n", step.Number))
 
-		status := string(step.Status)
-		sb.WriteString(fmt.Sprintf("**Status**: %s\n", status))
+		sb.WriteString(fmt.Sprintf("**Status**: %s\n", step.storedStatus()))
 
 		lastRun := "N/A"
 		if step.LastRun != nil {
@@ -236,14 +405,44 @@ func WriteFile(path string, plan *Plan) error {
 		sb.WriteString(fmt.Sprintf("**Notes**: %s\n", notes))
 
 		sb.WriteString(fmt.Sprintf("**Retries**: %d\n", step.RetryCount))
-	}
 
-	return os.WriteFile(path, []byte(sb.String()), 0644)
-}
+		if step.ContextHash != "" {
+			sb.WriteString(fmt.Sprintf("**Context Hash**: %s\n", step.ContextHash))
+		}
+
+		if len(step.Artifacts) > 0 {
+			sb.WriteString(fmt.Sprintf("**Artifacts**: %s\n", strings.Join(step.Artifacts, ", ")))
+		}
+
+		if step.SessionID != "" {
+			sb.WriteString(fmt.Sprintf("**Session**: %s\n", step.SessionID))
+		}
+
+		if step.Timeout > 0 {
+			sb.WriteString(fmt.Sprintf("**Timeout**: %s\n", FormatDuration(step.Timeout)))
+		}
+
+		if step.MaxRetries > 0 {
+			sb.WriteString(fmt.Sprintf("**Max Retries**: %d\n", step.MaxRetries))
+		}
+
+		if step.Acceptance != "" {
+			if strings.Contains(step.Acceptance, "\n") {
+				sb.WriteString(fmt.Sprintf("**Acceptance**:\n%s\n", step.Acceptance))
+			} else {
+				sb.WriteString(fmt.Sprintf("**Acceptance**: %s\n", step.Acceptance))
+			}
+		}
+
+		if len(step.Attempts) > 0 {
+			sb.WriteString("**History**:\n")
+			for _, attempt := range step.Attempts {
+				sb.WriteString(historyLine(attempt) + "\n")
+			}
+		}
+	}
 
-// SavePlan reads, parses, and rewrites the plan file (useful after modifications)
-func SavePlan(path string, plan *Plan) error {
-	return WriteFile(path, plan)
+	return SaveContent(path, sb.String())
 }
 
 // Scanner helper for parsing
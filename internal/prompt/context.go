@@ -0,0 +1,79 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eraldohasanaj/ralph-loop/internal/plan"
+)
+
+// charsPerToken approximates how many characters make a model token, for
+// budgets given in tokens
+const charsPerToken = 4
+
+// ContextProvider supplies one section of extra context for a step's
+// prompt, such as the working tree's diff or the project's instruction
+// files. An empty result leaves the section out.
+type ContextProvider interface {
+	Name() string
+	Collect(ctx context.Context, step *plan.Step) (string, error)
+}
+
+// Section is context collected from a provider, quoted into the prompt
+// under the provider's name
+type Section struct {
+	Name    string
+	Content string
+}
+
+// budgeted truncates a provider's output to a token budget
+type budgeted struct {
+	ContextProvider
+	maxTokens int
+}
+
+// WithBudget limits a provider's output to about maxTokens tokens; longer
+// output is cut at a line boundary and marked as truncated. A budget of 0
+// means no limit.
+func WithBudget(p ContextProvider, maxTokens int) ContextProvider {
+	if maxTokens <= 0 {
+		return p
+	}
+	return budgeted{ContextProvider: p, maxTokens: maxTokens}
+}
+
+func (b budgeted) Collect(ctx context.Context, step *plan.Step) (string, error) {
+	content, err := b.ContextProvider.Collect(ctx, step)
+	if err != nil {
+		return "", err
+	}
+	limit := b.maxTokens * charsPerToken
+	if len(content) <= limit {
+		return content, nil
+	}
+	cut := content[:limit]
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i]
+	}
+	return fmt.Sprintf("%s\n[... truncated to about %d tokens]", cut, b.maxTokens), nil
+}
+
+// CollectContext runs the providers in order and returns their non-empty
+// sections. A provider that fails is left out and its error returned, so
+// one broken source doesn't hold up the step.
+func CollectContext(ctx context.Context, providers []ContextProvider, step *plan.Step) ([]Section, []error) {
+	var sections []Section
+	var errs []error
+	for _, p := range providers {
+		content, err := p.Collect(ctx, step)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("context provider %s: %w", p.Name(), err))
+			continue
+		}
+		if content = strings.TrimSpace(content); content != "" {
+			sections = append(sections, Section{Name: p.Name(), Content: content})
+		}
+	}
+	return sections, errs
+}
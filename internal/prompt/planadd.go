@@ -0,0 +1,28 @@
+package prompt
+
+import "strings"
+
+// PlanAddMarker starts a line of agent output that asks for a new step to
+// be appended to the plan; the step's description follows
+const PlanAddMarker = "PLAN_ADD:"
+
+// ParsePlanAdds returns the descriptions of the steps the agent asked to
+// add with PLAN_ADD lines, in order, without blanks or repeats
+func ParsePlanAdds(output string) []string {
+	var descriptions []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, PlanAddMarker) {
+			continue
+		}
+		desc := strings.Join(strings.Fields(line[len(PlanAddMarker):]), " ")
+		key := strings.ToLower(desc)
+		if desc == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		descriptions = append(descriptions, desc)
+	}
+	return descriptions
+}
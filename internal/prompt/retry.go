@@ -0,0 +1,107 @@
+package prompt
+
+import (
+	"strings"
+)
+
+// PartialPrefix starts the reason of an attempt that ended with
+// STEP_PARTIAL: it finished part of the step and said what is left
+const PartialPrefix = "Partial: "
+
+// Failure classes of a step's previous attempt, as the retry prompt
+// addresses them
+const (
+	failureTimeout  = "timeout"   // Ran out of time, or over its max_duration
+	failurePartial  = "partial"   // Reported STEP_PARTIAL
+	failureVerify   = "verify"    // Reported success, but the verification command failed
+	failureNoMarker = "no-marker" // Ended without a usable completion marker
+	failureStalled  = "stalled"   // Went silent and was stopped
+	failurePolicy   = "policy"    // Ran a denied command and was stopped
+	failureReview   = "review"    // Reported success, but the reviewer agent or the operator rejected it
+	failureGeneric  = "generic"   // Anything else, e.g. STEP_FAILED
+)
+
+const (
+	failedNotePrefix = "Failed: "       // Starts the notes of a failed step
+	verifyPrefix     = "verification `" // Starts the reason of a failed verification
+	verifyLineSep    = " | "            // Joins its output lines
+)
+
+// Reasons recorded by the runner for each class. The class is read back
+// from the step's notes, so retries in a later run are addressed too.
+var failurePrefixes = []struct {
+	prefix string
+	class  string
+}{
+	{"Step timed out after ", failureTimeout},
+	{"Budget exceeded: ran longer than max_duration", failureTimeout},
+	{PartialPrefix, failurePartial},
+	{verifyPrefix, failureVerify},
+	{"No STEP_COMPLETE or STEP_FAILED marker", failureNoMarker},
+	{"Marker is tagged with run ID", failureNoMarker},
+	{"Agent stalled: ", failureStalled},
+	{"Agent stalled waiting for input: ", failureStalled},
+	{"Policy violation: ", failurePolicy},
+	{ReviewFailedPrefix, failureReview},
+	{"Rejected in review", failureReview},
+}
+
+// classifyFailure returns the failure class of a failed step's notes
+func classifyFailure(notes string) string {
+	reason := strings.TrimPrefix(notes, failedNotePrefix)
+	for _, f := range failurePrefixes {
+		if strings.HasPrefix(reason, f.prefix) {
+			return f.class
+		}
+	}
+	return failureGeneric
+}
+
+// retryGuidance tells the agent how to approach a retry after a failure of
+// the given class
+func retryGuidance(class string) string {
+	switch class {
+	case failureTimeout:
+		return "The previous attempt ran out of time before it finished. Break the work into smaller pieces. " +
+			"Finish one piece and make sure it builds, then stop and output exactly:\n" +
+			"   STEP_PARTIAL: <what is done and what is left>\n" +
+			"instead of STEP_COMPLETE. The next attempt continues from there.\n\n"
+	case failurePartial:
+		return "The previous attempt finished part of this step and reported what is left (see the notes above). " +
+			"Continue from where it stopped and don't redo finished work. " +
+			"If the rest is still too much for one attempt, finish the next piece, then stop and output exactly:\n" +
+			"   STEP_PARTIAL: <what is done and what is left>\n\n"
+	case failureVerify:
+		return "The previous attempt reported STEP_COMPLETE, but the verification command failed with the output above. " +
+			"Fix only the failing tests or checks. Don't rewrite code that already works, and don't weaken or delete tests to make them pass.\n\n"
+	case failureNoMarker:
+		return "The previous attempt ended without a valid completion marker, so its result could not be recorded. " +
+			"However this attempt goes, your response MUST end with STEP_COMPLETE or STEP_FAILED exactly as the Instructions below describe: " +
+			"on its own line, outside any code block, with nothing after it.\n\n"
+	case failureStalled:
+		return "The previous attempt stopped producing output and was stopped. " +
+			"Avoid commands that wait for input or never exit, such as watch modes, dev servers and pagers. " +
+			"Run them with a timeout or in the background.\n\n"
+	case failureReview:
+		return "The previous attempt reported STEP_COMPLETE, but a review rejected its changes with the feedback above. " +
+			"Fix each problem the feedback names and keep the work that is fine.\n\n"
+	case failurePolicy:
+		return "The previous attempt was stopped for running a destructive command that is not allowed. " +
+			"Don't run it again; find a safe way to do the step.\n\n"
+	}
+	return "Please try a different approach or fix the issues mentioned above.\n\n"
+}
+
+// verifyOutputLines returns the output lines recorded in a failed
+// verification's notes, or nil
+func verifyOutputLines(notes string) []string {
+	reason := strings.TrimPrefix(notes, failedNotePrefix)
+	if !strings.HasPrefix(reason, verifyPrefix) {
+		return nil
+	}
+	_, output, found := strings.Cut(reason, "): ")
+	if !found {
+		return nil
+	}
+	return strings.Split(output, verifyLineSep)
+}
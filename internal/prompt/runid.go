@@ -0,0 +1,60 @@
+package prompt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// runIDPrefix starts the line on which the agent echoes its run ID
+const runIDPrefix = "RUN_ID:"
+
+// markerWindow is how many lines before a marker are searched for the echo
+const markerWindow = 5
+
+// NewRunID returns a short random ID for a run. It carries no information
+// about the machine or user.
+func NewRunID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// CorrelationID identifies one attempt at a step within a run, e.g.
+// "3f9a2c1e-4.2" for the second attempt at step 4
+func CorrelationID(runID string, step int, attempt int) string {
+	if runID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s-%d.%d", runID, step, attempt)
+}
+
+// EchoedRunID returns the run ID the agent echoed next to its final marker,
+// or "" if there is none
+func EchoedRunID(output string) string {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if _, ok := parseMarker(strings.TrimSpace(lines[i])); ok {
+			return echoedRunID(lines[:i+1])
+		}
+	}
+	return ""
+}
+
+// echoedRunID looks for a RUN_ID line among the last few lines, which end
+// with the marker line
+func echoedRunID(lines []string) string {
+	for i := len(lines) - 1; i >= 0 && i >= len(lines)-markerWindow; i-- {
+		_, rest, ok := strings.Cut(lines[i], runIDPrefix)
+		if !ok {
+			continue
+		}
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	return ""
+}
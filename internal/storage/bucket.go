@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// bucketStore saves files in an object store through its CLI
+type bucketStore struct {
+	url       string   // s3://bucket/prefix or gs://bucket/prefix
+	cli       string   // aws or gcloud
+	copyArgs  []string // Arguments before source and destination
+	recursive string   // Flag to copy a directory
+}
+
+func (s *bucketStore) Name() string {
+	return s.url
+}
+
+func (s *bucketStore) Write(ctx context.Context, key string, content []byte) (string, error) {
+	tmp, err := os.CreateTemp("", "ralph-loop-upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return s.Copy(ctx, key, tmp.Name())
+}
+
+func (s *bucketStore) Copy(ctx context.Context, key string, src string) (string, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+	url := s.url + "/" + path.Clean(key)
+	args := append(append([]string{}, s.copyArgs...), src, url)
+	if info.IsDir() {
+		args = append(args, s.recursive)
+	}
+	return url, run(ctx, s.cli, args...)
+}
+
+// run runs an upload CLI, including its output in any error
+func run(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %v: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
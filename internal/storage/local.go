@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// localStore saves files in a local directory
+type localStore struct {
+	dir string
+}
+
+func (s *localStore) Name() string {
+	return s.dir
+}
+
+func (s *localStore) Write(ctx context.Context, key string, content []byte) (string, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+	return target, os.WriteFile(target, content, 0644)
+}
+
+func (s *localStore) Copy(ctx context.Context, key string, src string) (string, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+	target, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return target, copyDir(src, target)
+	}
+	return target, copyFile(src, target)
+}
+
+// path returns the absolute path of key
+func (s *localStore) path(key string) (string, error) {
+	return filepath.Abs(filepath.Join(s.dir, filepath.FromSlash(key)))
+}
+
+// copyDir copies a directory tree
+func copyDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		return copyFile(p, filepath.Join(dst, rel))
+	})
+}
+
+// copyFile copies a single file, creating parent directories
+func copyFile(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,41 @@
+// Package storage saves run logs, transcripts and artifacts to a local
+// directory or an object store, so they outlive ephemeral machines.
+package storage
+
+import (
+	"context"
+	"strings"
+)
+
+// Store saves files under slash-separated keys
+type Store interface {
+	// Name identifies the store in diagnostics
+	Name() string
+
+	// Write saves content under key and returns its location
+	Write(ctx context.Context, key string, content []byte) (string, error)
+
+	// Copy saves a local file or directory tree under key and returns its
+	// location
+	Copy(ctx context.Context, key string, src string) (string, error)
+}
+
+// Open returns the store for a destination: s3://bucket/prefix and
+// gs://bucket/prefix use the aws and gcloud CLIs, and anything else is a
+// local directory
+func Open(dest string) Store {
+	switch {
+	case strings.HasPrefix(dest, "s3://"):
+		return &bucketStore{url: strings.TrimRight(dest, "/"), cli: "aws", copyArgs: []string{"s3", "cp"}, recursive: "--recursive"}
+	case strings.HasPrefix(dest, "gs://"):
+		return &bucketStore{url: strings.TrimRight(dest, "/"), cli: "gcloud", copyArgs: []string{"storage", "cp"}, recursive: "--recursive"}
+	default:
+		return &localStore{dir: dest}
+	}
+}
+
+// IsLocal reports whether a destination is a local directory
+func IsLocal(dest string) bool {
+	_, ok := Open(dest).(*localStore)
+	return ok
+}